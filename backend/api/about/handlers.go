@@ -12,7 +12,7 @@ import (
 
 // AboutHandler - GET - get `about`	info
 func AboutHandler(w http.ResponseWriter, r *http.Request) {
-	services.SetCommonResponseHeaders(w)
+	services.SetCommonResponseHeaders(w, r)
 	services.SetAboutResponseHeaders(w)
 
 	// Read embedded license data
@@ -0,0 +1,167 @@
+package audit
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sondrus/tetrad/config"
+	"github.com/sondrus/tetrad/database"
+	"github.com/sondrus/tetrad/services"
+)
+
+const (
+	// defaultLimit - records per page by default
+	defaultLimit = 100
+
+	// maxLimit - max records per page
+	maxLimit = 1000
+)
+
+// GetAuditHandler - GET - get audit log records, newest first (admin only)
+// Filters: `noteId`, `from` and `to` (unix time, RFC 3339 or YYYY-MM-DD), `operation` (comma-separated), `userId`,
+// `notebook` (notebook of request for `noteId`)
+// Paging: `limit` and `offset`
+func GetAuditHandler(w http.ResponseWriter, r *http.Request) {
+	services.SetCommonResponseHeaders(w, r)
+
+	if !services.CheckAdmin(w, r) {
+		return
+	}
+
+	filter, err := parseFilter(r)
+	if err != nil {
+		services.RespondWithError(w, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	records, total, err := services.GetAuditRecords(filter)
+	if err != nil {
+		services.RespondWithError(w, http.StatusInternalServerError, "Failed to fetch audit log", err)
+		return
+	}
+
+	// Create response
+	response := map[string]any{
+		"success":   true,
+		"records":   records,
+		"total":     total,
+		"retention": config.AppConfig.AuditRetention,
+	}
+
+	// Send response
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// CleanupAuditHandler - POST - delete records older than retention period now (admin only)
+func CleanupAuditHandler(w http.ResponseWriter, r *http.Request) {
+	services.SetCommonResponseHeaders(w, r)
+
+	if !services.CheckAdmin(w, r) {
+		return
+	}
+
+	deleted, err := services.CleanupAudit(config.AppConfig.AuditRetention)
+	if err != nil {
+		services.RespondWithError(w, http.StatusInternalServerError, "Failed to clean up audit log", err)
+		return
+	}
+
+	// Create response
+	response := map[string]any{
+		"success": true,
+		"deleted": deleted,
+	}
+
+	// Send response
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// parseFilter - get audit log filter from query params
+func parseFilter(r *http.Request) (services.AuditFilter, error) {
+	query := r.URL.Query()
+	filter := services.AuditFilter{Limit: defaultLimit}
+
+	var err error
+	if filter.NoteID, err = parseInt(query.Get("noteId")); err != nil {
+		return filter, errors.New("Wrong note ID")
+	}
+	if filter.UserID, err = parseInt(query.Get("userId")); err != nil {
+		return filter, errors.New("Wrong user ID")
+	}
+	if filter.From, err = parseTime(query.Get("from"), false); err != nil {
+		return filter, errors.New("Wrong 'from' time")
+	}
+	if filter.To, err = parseTime(query.Get("to"), true); err != nil {
+		return filter, errors.New("Wrong 'to' time")
+	}
+
+	// Note IDs are unique just inside notebook
+	filter.Notebook = query.Get("notebook")
+	if filter.Notebook == "" && filter.NoteID > 0 {
+		filter.Notebook = database.GetContextNotebook(r.Context()).Name
+	}
+
+	for _, operation := range strings.Split(query.Get("operation"), ",") {
+		if operation = strings.TrimSpace(operation); operation != "" {
+			filter.Operations = append(filter.Operations, operation)
+		}
+	}
+
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit <= 0 {
+			return filter, errors.New("Wrong limit")
+		}
+		filter.Limit = min(limit, maxLimit)
+	}
+	if value := query.Get("offset"); value != "" {
+		offset, err := strconv.Atoi(value)
+		if err != nil || offset < 0 {
+			return filter, errors.New("Wrong offset")
+		}
+		filter.Offset = offset
+	}
+
+	return filter, nil
+}
+
+// parseInt - parse optional positive integer (empty = 0)
+func parseInt(value string) (int64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	number, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || number < 0 {
+		return 0, fmt.Errorf("invalid number %s", value)
+	}
+	return number, nil
+}
+
+// parseTime - parse optional time: unix seconds, RFC 3339 or date (end of day if `end` is set)
+func parseTime(value string, end bool) (int64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return seconds, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.Unix(), nil
+	}
+
+	date, err := time.ParseInLocation(time.DateOnly, value, time.Local)
+	if err != nil {
+		return 0, err
+	}
+	if end {
+		date = date.AddDate(0, 0, 1).Add(-time.Second)
+	}
+	return date.Unix(), nil
+}
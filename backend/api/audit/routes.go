@@ -0,0 +1,11 @@
+package audit
+
+import (
+	"github.com/gorilla/mux"
+)
+
+// RegisterRoutes - register all routes for audit log
+func RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/api/audit", GetAuditHandler).Methods("GET")
+	router.HandleFunc("/api/audit/cleanup", CleanupAuditHandler).Methods("POST")
+}
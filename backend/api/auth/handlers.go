@@ -0,0 +1,98 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+
+	auth_service "github.com/sondrus/tetrad/auth"
+	"github.com/sondrus/tetrad/services"
+)
+
+// StatusHandler - GET - check authentication is enabled and client is logged in
+func StatusHandler(w http.ResponseWriter, r *http.Request) {
+	services.SetCommonResponseHeaders(w, r)
+
+	enabled := auth_service.IsEnabled()
+	user := services.GetContextUser(r.Context())
+
+	// Create response
+	response := map[string]any{
+		"success":       true,
+		"enabled":       enabled,
+		"authenticated": !enabled || user != nil,
+		"user":          user,
+	}
+
+	// Send response
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// LoginHandler - POST - check password and create session
+func LoginHandler(w http.ResponseWriter, r *http.Request) {
+	services.SetCommonResponseHeaders(w, r)
+
+	// Check client is not locked out after failed logins
+	if allowed, wait := auth_service.LoginAllowed(r); !allowed {
+		seconds := int(math.Ceil(wait.Seconds()))
+		w.Header().Set("Retry-After", fmt.Sprintf("%d", seconds))
+		services.RespondWithError(w, http.StatusTooManyRequests,
+			fmt.Sprintf("Too many failed logins, try again in %d seconds", seconds), nil)
+		return
+	}
+
+	// Declare JSON POST structure
+	var req struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+	}
+
+	// Decode input JSON
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		services.RespondWithError(w, http.StatusBadRequest, "Invalid JSON", err)
+		return
+	}
+
+	// Check user name and password
+	user, err := auth_service.Authenticate(req.Username, req.Password)
+	if err != nil {
+		auth_service.LoginFailed(r)
+		services.RespondWithError(w, http.StatusUnauthorized, "Wrong user name or password", nil)
+		return
+	}
+	auth_service.LoginSucceeded(r)
+
+	// Create session
+	if err := auth_service.CreateSession(w, r, user); err != nil {
+		services.RespondWithError(w, http.StatusInternalServerError, "Failed to create session", err)
+		return
+	}
+
+	// Create response
+	response := map[string]any{
+		"success": true,
+	}
+
+	// Send response
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// LogoutHandler - POST - delete session
+func LogoutHandler(w http.ResponseWriter, r *http.Request) {
+	services.SetCommonResponseHeaders(w, r)
+
+	auth_service.DeleteSession(w, r)
+	auth_service.LockAll(w, r)
+
+	// Create response
+	response := map[string]any{
+		"success": true,
+	}
+
+	// Send response
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,12 @@
+package auth
+
+import (
+	"github.com/gorilla/mux"
+)
+
+// RegisterRoutes - registers all routes for login/logout
+func RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/api/auth/status", StatusHandler).Methods("GET")
+	router.HandleFunc("/api/auth/login", LoginHandler).Methods("POST")
+	router.HandleFunc("/api/auth/logout", LogoutHandler).Methods("POST")
+}
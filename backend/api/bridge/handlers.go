@@ -0,0 +1,274 @@
+package bridge
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/sondrus/tetrad/database"
+	"github.com/sondrus/tetrad/models"
+	"github.com/sondrus/tetrad/services"
+)
+
+// getApp - get IFRAME note from URL and check user role for it
+func getApp(w http.ResponseWriter, r *http.Request, role string) (models.NoteDB, bool) {
+	ID := r.Context().Value(database.IDKey).(int)
+	app, err := services.GetNote(r.Context(), ID)
+	if err != nil {
+		services.RespondWithError(w, http.StatusNotFound, "Note is not found", nil)
+		return app, false
+	}
+
+	if !services.CheckNoteRole(w, r, app, role) {
+		return app, false
+	}
+
+	if app.Type != "IFRAME" {
+		services.RespondWithError(w, http.StatusBadRequest, "Note type is not iframe", nil)
+		return app, false
+	}
+
+	return app, true
+}
+
+// GetDataHandler - GET - get JSON data of IFRAME note
+func GetDataHandler(w http.ResponseWriter, r *http.Request) {
+	services.SetCommonResponseHeaders(w, r)
+
+	app, ok := getApp(w, r, services.RoleViewer)
+	if !ok {
+		return
+	}
+
+	// Get data
+	data, err := services.GetBridgeData(r.Context(), app.ID)
+	if err != nil {
+		services.RespondWithError(w, http.StatusInternalServerError, "Failed to load data", err)
+		return
+	}
+
+	// Create response
+	response := map[string]any{
+		"success": true,
+		"data":    data,
+	}
+
+	// Send response
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// PutDataHandler - PUT - save JSON data of IFRAME note
+func PutDataHandler(w http.ResponseWriter, r *http.Request) {
+	services.SetCommonResponseHeaders(w, r)
+
+	app, ok := getApp(w, r, services.RoleEditor)
+	if !ok {
+		return
+	}
+
+	// Declare JSON structure
+	var req struct {
+		Data json.RawMessage `json:"data"`
+	}
+
+	// Decode input JSON
+	r.Body = http.MaxBytesReader(w, r.Body, services.BridgeDataLimit+1024)
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		services.RespondWithError(w, http.StatusBadRequest, "Invalid JSON", err)
+		return
+	}
+	if len(req.Data) == 0 {
+		req.Data = json.RawMessage("null")
+	}
+
+	// Save data
+	if err := services.SaveBridgeData(r.Context(), app.ID, req.Data); err != nil {
+		services.RespondWithError(w, http.StatusBadRequest, "Failed to save data", err)
+		return
+	}
+	services.Audit(r, services.AuditEntry{
+		Operation: services.AuditAppData,
+		NoteIDs:   []int64{app.ID},
+		Details:   fmt.Sprintf("%d bytes", len(req.Data)),
+	})
+
+	// Create response
+	response := map[string]any{
+		"success": true,
+	}
+
+	// Send response
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetNotesHandler - GET - get notes (without contents) which IFRAME note is allowed to read
+func GetNotesHandler(w http.ResponseWriter, r *http.Request) {
+	services.SetCommonResponseHeaders(w, r)
+
+	app, ok := getApp(w, r, services.RoleViewer)
+	if !ok {
+		return
+	}
+
+	// Get grants
+	grants, err := services.GetBridgeGrants(r.Context(), app.ID)
+	if err != nil {
+		services.RespondWithError(w, http.StatusInternalServerError, "Failed to load grants", err)
+		return
+	}
+
+	// Granted notes with children, visible for user
+	notes := []models.NoteDB{}
+	for _, grant := range grants {
+		root, err := services.GetNote(r.Context(), int(grant.NoteID))
+		if err != nil {
+			continue
+		}
+		subtree, err := services.GetNotes(r.Context(), services.NoteQueryOptions{
+			Where:        `"LEFT" >= ? AND "RIGHT" <= ?`,
+			Args:         []any{root.Left, root.Right},
+			Order:        "LEFT ASC",
+			OmitContents: true,
+			User:         services.GetContextUser(r.Context()),
+		})
+		if err != nil {
+			services.RespondWithError(w, http.StatusInternalServerError, "Failed to load notes", err)
+			return
+		}
+		notes = append(notes, subtree...)
+	}
+
+	// Create response
+	response := map[string]any{
+		"success": true,
+		"notes":   notes,
+	}
+
+	// Send response
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetNoteHandler - GET - get note which IFRAME note is allowed to read
+func GetNoteHandler(w http.ResponseWriter, r *http.Request) {
+	services.SetCommonResponseHeaders(w, r)
+
+	app, ok := getApp(w, r, services.RoleViewer)
+	if !ok {
+		return
+	}
+
+	// Get note from database
+	noteID, _ := strconv.Atoi(mux.Vars(r)["noteId"])
+	note, err := services.GetUnlockedNote(r.Context(), services.GetContextKeyring(r.Context()), noteID)
+	if err != nil {
+		services.RespondWithError(w, http.StatusNotFound, "Note is not found", nil)
+		return
+	}
+
+	// Both user and IFRAME note must have access
+	if !services.HasBridgeGrant(r.Context(), app.ID, note) {
+		services.RespondWithError(w, http.StatusForbidden, "Note is not granted to this app", nil)
+		return
+	}
+	if !services.CheckNoteRole(w, r, note, services.RoleViewer) {
+		return
+	}
+	if note.Locked {
+		services.RespondWithError(w, http.StatusLocked, "Note is encrypted and locked", nil)
+		return
+	}
+
+	// Create response
+	response := map[string]any{
+		"success": true,
+		"note":    note,
+	}
+
+	// Send response
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetGrantsHandler - GET - get notes granted to IFRAME note
+func GetGrantsHandler(w http.ResponseWriter, r *http.Request) {
+	services.SetCommonResponseHeaders(w, r)
+
+	app, ok := getApp(w, r, services.RoleOwner)
+	if !ok {
+		return
+	}
+
+	// Get grants
+	grants, err := services.GetBridgeGrants(r.Context(), app.ID)
+	if err != nil {
+		services.RespondWithError(w, http.StatusInternalServerError, "Failed to load grants", err)
+		return
+	}
+
+	// Create response
+	response := map[string]any{
+		"success": true,
+		"grants":  grants,
+	}
+
+	// Send response
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// PutGrantHandler - PUT - allow (or disallow) IFRAME note to read note with its children
+func PutGrantHandler(w http.ResponseWriter, r *http.Request) {
+	services.SetCommonResponseHeaders(w, r)
+
+	app, ok := getApp(w, r, services.RoleOwner)
+	if !ok {
+		return
+	}
+
+	// Declare JSON structure
+	var req struct {
+		NoteID int64 `json:"noteId"`
+		Allow  bool  `json:"allow"`
+	}
+
+	// Decode input JSON
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		services.RespondWithError(w, http.StatusBadRequest, "Invalid JSON", err)
+		return
+	}
+
+	// Owner could grant just notes which are visible for them
+	note, err := services.GetNote(r.Context(), int(req.NoteID))
+	if err != nil {
+		services.RespondWithError(w, http.StatusNotFound, "Note is not found", nil)
+		return
+	}
+	if req.Allow && !services.CheckNoteRole(w, r, note, services.RoleViewer) {
+		return
+	}
+
+	// Save
+	if err := services.SetBridgeGrant(r.Context(), app.ID, note.ID, req.Allow); err != nil {
+		services.RespondWithError(w, http.StatusInternalServerError, "Failed to save grant", err)
+		return
+	}
+	services.Audit(r, services.AuditEntry{
+		Operation: services.AuditAppGrant,
+		NoteIDs:   []int64{app.ID, note.ID},
+		Details:   fmt.Sprintf("note %d allow=%t", note.ID, req.Allow),
+	})
+
+	// Create response
+	response := map[string]any{
+		"success": true,
+	}
+
+	// Send response
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,15 @@
+package bridge
+
+import (
+	"github.com/gorilla/mux"
+)
+
+// RegisterRoutes - register all routes for IFRAME notes bridge (mini-apps)
+func RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/api/bridge/{id:[0-9]+}/data", GetDataHandler).Methods("GET")
+	router.HandleFunc("/api/bridge/{id:[0-9]+}/data", PutDataHandler).Methods("PUT")
+	router.HandleFunc("/api/bridge/{id:[0-9]+}/notes", GetNotesHandler).Methods("GET")
+	router.HandleFunc("/api/bridge/{id:[0-9]+}/notes/{noteId:[0-9]+}", GetNoteHandler).Methods("GET")
+	router.HandleFunc("/api/bridge/{id:[0-9]+}/grants", GetGrantsHandler).Methods("GET")
+	router.HandleFunc("/api/bridge/{id:[0-9]+}/grants", PutGrantHandler).Methods("PUT")
+}
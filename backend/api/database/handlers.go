@@ -2,29 +2,47 @@ package database
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 
 	"github.com/sondrus/tetrad/database"
+	"github.com/sondrus/tetrad/metrics"
 	"github.com/sondrus/tetrad/services"
 )
 
 // OptimizeDatabaseHandler - GET
 func OptimizeDatabaseHandler(w http.ResponseWriter, r *http.Request) {
-	services.SetCommonResponseHeaders(w)
+	services.SetCommonResponseHeaders(w, r)
 
-	size := database.GetFilesize()
+	// Maintenance is allowed for admin only
+	if !services.CheckAdmin(w, r) {
+		return
+	}
+
+	notebook := database.GetContextNotebook(r.Context())
+	size := notebook.Filesize()
 
-	success, err := database.Vacuum()
+	err := notebook.Vacuum()
+	success := err == nil
+	metrics.ObserveMaintenance("vacuum", err)
 	errorMessage := ""
 	if err != nil {
 		errorMessage = err.Error()
 	}
+	details := fmt.Sprintf("size %d => %d", size, notebook.Filesize())
+	if err != nil {
+		details += ", failed: " + errorMessage
+	}
+	services.Audit(r, services.AuditEntry{
+		Operation: services.AuditVacuum,
+		Details:   details,
+	})
 
 	response := map[string]any{
 		"success":  success,
 		"error":    errorMessage,
 		"size_old": size,
-		"size_new": database.GetFilesize(),
+		"size_new": notebook.Filesize(),
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -0,0 +1,146 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/sondrus/tetrad/database"
+	"github.com/sondrus/tetrad/events"
+	"github.com/sondrus/tetrad/models"
+	"github.com/sondrus/tetrad/services"
+)
+
+// heartbeatInterval - interval of comments which keep connection alive (proxies close idle ones)
+const heartbeatInterval = 25 * time.Second
+
+// EventsHandler - GET - stream of note changes (Server-Sent Events)
+// Client resumes by `Last-Event-ID` header (sent by EventSource automatically) or `lastEventId` param
+func EventsHandler(w http.ResponseWriter, r *http.Request) {
+	services.SetCommonResponseHeaders(w, r)
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		services.RespondWithError(w, http.StatusInternalServerError, "Streaming is not supported", nil)
+		return
+	}
+
+	// Stream is long, so write timeout of server must not break it
+	http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	// Last received event
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = r.URL.Query().Get("lastEventId")
+	}
+	lastID, _ := strconv.ParseInt(lastEventID, 10, 64)
+
+	subscriber, backlog := events.Subscribe(lastID)
+	defer events.Unsubscribe(subscriber)
+
+	user := services.GetContextUser(r.Context())
+	notebook := database.GetContextNotebook(r.Context()).Name
+
+	// Send headers
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, "retry: 3000\n\n")
+
+	// Missed events
+	for _, event := range backlog {
+		writeEvent(r.Context(), w, user, notebook, event)
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": ping\n\n")
+			flusher.Flush()
+		case event, ok := <-subscriber.C:
+			if !ok {
+				return
+			}
+			writeEvent(r.Context(), w, user, notebook, event)
+			flusher.Flush()
+		}
+	}
+}
+
+// writeEvent - send event if user can see it (ID is sent anyway, so client could resume)
+func writeEvent(ctx context.Context, w http.ResponseWriter, user *models.User, notebook string, event events.Event) {
+	if (event.Type != events.Reset && event.Notebook != notebook) || !isVisible(ctx, user, &event) {
+		fmt.Fprintf(w, "id: %d\n\n", event.ID)
+		return
+	}
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(w, "id: %d\ndata: %s\n\n", event.ID, data)
+}
+
+// isVisible - check user can see event (expand/collapse lists are filtered)
+func isVisible(ctx context.Context, user *models.User, event *events.Event) bool {
+	var userID int64
+	if user != nil {
+		userID = user.ID
+	}
+
+	switch event.Type {
+	case events.Settings, events.Reminder, events.Reminders:
+		return event.UserID == userID
+	case events.Reset:
+		return true
+	}
+
+	if services.IsAdmin(user) {
+		return true
+	}
+
+	// Deleted notes are checked by grants before deletion, parent is shown just with the deleted note itself
+	if event.Type == events.Deleted {
+		visible := event.Viewers[userID]
+		if len(visible) > 0 && !slices.Contains(visible, event.NoteIDs[0]) {
+			event.ParentID = 0
+		}
+		event.NoteIDs = visible
+		return len(visible) > 0
+	}
+
+	if event.Type == events.Expanded {
+		expand, _ := filterIDs(ctx, user, event.Expand)
+		collapse, _ := filterIDs(ctx, user, event.Collapse)
+		event.Expand, event.Collapse = expand, collapse
+		return len(expand)+len(collapse) > 0
+	}
+
+	for _, id := range event.NoteIDs {
+		note, err := services.GetNote(ctx, int(id))
+		if err != nil || !services.HasNoteRole(ctx, user, note, services.RoleViewer) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// filterIDs - keep visible note IDs ([0] means all notes)
+func filterIDs(ctx context.Context, user *models.User, ids []int) ([]int, error) {
+	if len(ids) == 1 && ids[0] == 0 {
+		return ids, nil
+	}
+	return services.FilterVisibleNoteIDs(ctx, user, ids)
+}
@@ -0,0 +1,10 @@
+package events
+
+import (
+	"github.com/gorilla/mux"
+)
+
+// RegisterRoutes - register all routes for live events
+func RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/api/events", EventsHandler).Methods("GET")
+}
@@ -0,0 +1,147 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/sondrus/tetrad/database"
+	"github.com/sondrus/tetrad/meta"
+	"github.com/sondrus/tetrad/services"
+)
+
+// readyTimeout - max duration of readiness checks
+const readyTimeout = 3 * time.Second
+
+// writableTTL - how long result of write check is reused
+const writableTTL = 10 * time.Second
+
+// writableCheck - cached result of write check
+type writableCheck struct {
+	Date time.Time
+	Err  error
+}
+
+var (
+	// startedAt - time of app start (for uptime)
+	startedAt = time.Now()
+
+	// Database path => last write check
+	writableCache = make(map[string]writableCheck)
+	writableMutex sync.Mutex
+)
+
+// LiveHandler - GET - liveness: the process is running and serves requests (no authentication)
+func LiveHandler(w http.ResponseWriter, r *http.Request) {
+	services.SetCommonResponseHeaders(w, r)
+
+	// Create response
+	response := map[string]any{
+		"success": true,
+		"status":  "ok",
+	}
+
+	// Send response
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// ReadyHandler - GET - readiness: main database and opened notebooks answer and are writable, 503 otherwise (no authentication)
+// Response has no details (notebook names, paths), they are logged
+func ReadyHandler(w http.ResponseWriter, r *http.Request) {
+	services.SetCommonResponseHeaders(w, r)
+
+	ctx, cancel := context.WithTimeout(r.Context(), readyTimeout)
+	defer cancel()
+
+	// Main database and all opened notebooks
+	for _, notebook := range database.OpenedNotebooks() {
+		if err := notebook.Ping(ctx); err != nil {
+			slog.Error("Database is not available", "notebook", notebook.Name, "error", err)
+			services.RespondWithError(w, http.StatusServiceUnavailable, "Not ready", nil)
+			return
+		}
+		if err := checkWritable(ctx, notebook); err != nil {
+			slog.Error("Database is not writable", "notebook", notebook.Name, "error", err)
+			services.RespondWithError(w, http.StatusServiceUnavailable, "Not ready", nil)
+			return
+		}
+	}
+
+	// Create response
+	response := map[string]any{
+		"success": true,
+		"status":  "ready",
+	}
+
+	// Send response
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// checkWritable - write check of notebook, its result is cached for a while (probes come often and check takes write lock)
+func checkWritable(ctx context.Context, notebook *database.Notebook) error {
+	writableMutex.Lock()
+	cached, ok := writableCache[notebook.Path]
+	writableMutex.Unlock()
+	if ok && time.Since(cached.Date) < writableTTL {
+		return cached.Err
+	}
+
+	err := notebook.CheckWritable(ctx)
+
+	writableMutex.Lock()
+	writableCache[notebook.Path] = writableCheck{Date: time.Now(), Err: err}
+	writableMutex.Unlock()
+
+	return err
+}
+
+// DiagnosticsHandler - GET - SQLite, database file (of selected notebook) and Go runtime details (admin only)
+func DiagnosticsHandler(w http.ResponseWriter, r *http.Request) {
+	services.SetCommonResponseHeaders(w, r)
+
+	if !services.CheckAdmin(w, r) {
+		return
+	}
+
+	diagnostics, err := database.GetContextNotebook(r.Context()).GetDiagnostics()
+	if err != nil {
+		services.RespondWithError(w, http.StatusInternalServerError, "Failed to get database diagnostics", err)
+		return
+	}
+
+	var memory runtime.MemStats
+	runtime.ReadMemStats(&memory)
+
+	// Create response
+	response := map[string]any{
+		"success":  true,
+		"version":  meta.Version,
+		"started":  startedAt.Unix(),
+		"uptime":   int64(time.Since(startedAt).Seconds()),
+		"database": diagnostics,
+		"runtime": map[string]any{
+			"goVersion":  runtime.Version(),
+			"os":         runtime.GOOS,
+			"arch":       runtime.GOARCH,
+			"cpus":       runtime.NumCPU(),
+			"maxProcs":   runtime.GOMAXPROCS(0),
+			"goroutines": runtime.NumGoroutine(),
+			"heapAlloc":  memory.HeapAlloc,
+			"heapInuse":  memory.HeapInuse,
+			"sys":        memory.Sys,
+			"totalAlloc": memory.TotalAlloc,
+			"numGC":      memory.NumGC,
+			"lastGC":     int64(memory.LastGC / uint64(time.Second)),
+		},
+	}
+
+	// Send response
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
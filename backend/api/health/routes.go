@@ -0,0 +1,12 @@
+package health
+
+import (
+	"github.com/gorilla/mux"
+)
+
+// RegisterRoutes - register all routes for health checks and diagnostics
+func RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/healthz", LiveHandler).Methods("GET", "HEAD")
+	router.HandleFunc("/readyz", ReadyHandler).Methods("GET", "HEAD")
+	router.HandleFunc("/api/diagnostics", DiagnosticsHandler).Methods("GET")
+}
@@ -0,0 +1,168 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/sondrus/tetrad/database"
+	"github.com/sondrus/tetrad/importers"
+	"github.com/sondrus/tetrad/services"
+)
+
+// maxUploadSize - limit for uploaded export files
+const maxUploadSize = 1 << 30
+
+// ImportJoplinHandler - POST - import Joplin export (JEX), multipart field `file`
+func ImportJoplinHandler(w http.ResponseWriter, r *http.Request) {
+	services.SetCommonResponseHeaders(w, r)
+
+	sources, parentID, closeAll, ok := parseUpload(w, r)
+	if !ok {
+		return
+	}
+	defer closeAll()
+
+	if len(sources) != 1 {
+		services.RespondWithError(w, http.StatusBadRequest, "Exactly one JEX file is expected", nil)
+		return
+	}
+
+	// Import
+	report, err := importers.ImportJoplin(database.GetContextORM(r.Context()), sources[0].Reader, parentID)
+	if err != nil {
+		services.RespondWithError(w, http.StatusBadRequest, "Failed to import Joplin export", err)
+		return
+	}
+
+	respondWithReport(w, r, "Joplin", parentID, report)
+}
+
+// ImportEvernoteHandler - POST - import Evernote exports (ENEX), multipart field `file` (one or more)
+// Optional `path` fields (one per file) set notebook path with stacks, eg `Stack/Notebook.enex`
+func ImportEvernoteHandler(w http.ResponseWriter, r *http.Request) {
+	services.SetCommonResponseHeaders(w, r)
+
+	sources, parentID, closeAll, ok := parseUpload(w, r)
+	if !ok {
+		return
+	}
+	defer closeAll()
+
+	// Import
+	report, err := importers.ImportEvernote(database.GetContextORM(r.Context()), sources, parentID)
+	if err != nil {
+		services.RespondWithError(w, http.StatusBadRequest, "Failed to import Evernote export", err)
+		return
+	}
+
+	respondWithReport(w, r, "Evernote", parentID, report)
+}
+
+// parseUpload - get uploaded files and `parent` note ID from multipart form
+func parseUpload(w http.ResponseWriter, r *http.Request) ([]importers.Source, int64, func(), bool) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		services.RespondWithError(w, http.StatusBadRequest, "Invalid multipart form", err)
+		return nil, 0, nil, false
+	}
+
+	// Parent note (0 = root)
+	var parentID int64
+	if value := r.FormValue("parent"); value != "" {
+		id, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || id < 0 {
+			services.RespondWithError(w, http.StatusBadRequest, "Wrong parent note ID", nil)
+			return nil, 0, nil, false
+		}
+		if id > 0 {
+			parent, err := services.GetNote(r.Context(), int(id))
+			if err != nil {
+				services.RespondWithError(w, http.StatusNotFound, "Parent note is not found", nil)
+				return nil, 0, nil, false
+			}
+			if !services.CheckNoteRole(w, r, parent, services.RoleEditor) {
+				return nil, 0, nil, false
+			}
+			if vault, _ := services.GetVaultByNote(r.Context(), parent); vault != nil {
+				services.RespondWithError(w, http.StatusBadRequest, "Import into encrypted subtree is not supported", nil)
+				return nil, 0, nil, false
+			}
+		}
+		parentID = id
+	}
+
+	// Import to root is allowed for admin only
+	if parentID == 0 && !services.CheckAdmin(w, r) {
+		return nil, 0, nil, false
+	}
+
+	// Open all files
+	var sources []importers.Source
+	var closers []func() error
+	closeAll := func() {
+		for _, c := range closers {
+			c()
+		}
+		r.MultipartForm.RemoveAll()
+	}
+
+	headers := r.MultipartForm.File["file"]
+	if len(headers) == 0 {
+		closeAll()
+		services.RespondWithError(w, http.StatusBadRequest, "Missing 'file' field", nil)
+		return nil, 0, nil, false
+	}
+
+	// Browsers send only base file name, full path could be set by `path` fields
+	paths := r.MultipartForm.Value["path"]
+	if len(paths) != len(headers) {
+		paths = nil
+	}
+
+	for i, header := range headers {
+		name := header.Filename
+		if paths != nil && paths[i] != "" {
+			name = paths[i]
+		}
+
+		file, err := header.Open()
+		if err != nil {
+			closeAll()
+			services.RespondWithError(w, http.StatusBadRequest, fmt.Sprintf("Failed to open %s", header.Filename), err)
+			return nil, 0, nil, false
+		}
+		closers = append(closers, file.Close)
+		sources = append(sources, importers.Source{Name: name, Reader: file})
+	}
+
+	return sources, parentID, closeAll, true
+}
+
+// respondWithReport - rebuild tree, record import in audit log and send import report
+func respondWithReport(w http.ResponseWriter, r *http.Request, format string, parentID int64, report *importers.Report) {
+	// Nested set rebuild and tasks of imported notes
+	services.RebuildNotesTree(r.Context())
+	services.ReindexTasks(r.Context())
+
+	var noteIDs []int64
+	if parentID > 0 {
+		noteIDs = []int64{parentID}
+	}
+	services.Audit(r, services.AuditEntry{
+		Operation: services.AuditNotesImport,
+		NoteIDs:   noteIDs,
+		Details:   fmt.Sprintf("%s: %s", format, report),
+	})
+
+	// Create response
+	response := map[string]any{
+		"success": true,
+		"report":  report,
+	}
+
+	// Send response
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,11 @@
+package importer
+
+import (
+	"github.com/gorilla/mux"
+)
+
+// RegisterRoutes - registers all routes for import from other applications
+func RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/api/import/joplin", ImportJoplinHandler).Methods("POST")
+	router.HandleFunc("/api/import/evernote", ImportEvernoteHandler).Methods("POST")
+}
@@ -0,0 +1,198 @@
+package journal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/sondrus/tetrad/services"
+)
+
+// GetDatesHandler - GET - get dates with journal notes, optional `from` and `to` (YYYY-MM-DD) limit the range
+func GetDatesHandler(w http.ResponseWriter, r *http.Request) {
+	services.SetCommonResponseHeaders(w, r)
+
+	user := services.GetContextUser(r.Context())
+	journal, ok := getSettings(w, r)
+	if !ok {
+		return
+	}
+
+	entries, err := services.GetJournalEntries(r.Context(), user, journal)
+	if err != nil {
+		services.RespondWithError(w, http.StatusInternalServerError, "Failed to fetch journal dates", err)
+		return
+	}
+
+	// Filter by range (dates are compared as text)
+	from, to := r.URL.Query().Get("from"), r.URL.Query().Get("to")
+	dates := make([]services.JournalEntry, 0, len(entries))
+	for _, entry := range entries {
+		if (from == "" || entry.Date >= from) && (to == "" || entry.Date <= to) {
+			dates = append(dates, entry)
+		}
+	}
+
+	// Create response
+	response := map[string]any{
+		"success": true,
+		"dates":   dates,
+	}
+
+	// Send response
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetEntryHandler - GET - get journal note for date (`today` or YYYY-MM-DD)
+func GetEntryHandler(w http.ResponseWriter, r *http.Request) {
+	services.SetCommonResponseHeaders(w, r)
+
+	journal, ok := getSettings(w, r)
+	if !ok {
+		return
+	}
+	date, ok := parseDate(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := services.GetJournalNote(r.Context(), journal, date)
+	if errors.Is(err, services.ErrJournalNotFound) {
+		services.RespondWithError(w, http.StatusNotFound, "Journal note is not found", nil)
+		return
+	}
+	if err != nil {
+		services.RespondWithError(w, http.StatusInternalServerError, "Failed to fetch journal note", err)
+		return
+	}
+
+	respondWithNote(w, r, date, id, false)
+}
+
+// PostEntryHandler - POST - get journal note for date, create it if it is missing
+func PostEntryHandler(w http.ResponseWriter, r *http.Request) {
+	services.SetCommonResponseHeaders(w, r)
+
+	journal, ok := getSettings(w, r)
+	if !ok {
+		return
+	}
+	date, ok := parseDate(w, r)
+	if !ok {
+		return
+	}
+
+	id, created, err := services.EnsureJournalNote(r.Context(), services.GetContextUser(r.Context()),
+		services.GetContextKeyring(r.Context()), journal, date)
+	switch {
+	case errors.Is(err, services.ErrAccessDenied):
+		services.RespondWithError(w, http.StatusForbidden, "Access denied", nil)
+		return
+	case errors.Is(err, services.ErrLocked):
+		services.RespondWithError(w, http.StatusLocked, "Note is encrypted and locked", nil)
+		return
+	case err != nil:
+		services.RespondWithError(w, http.StatusInternalServerError, "Failed to create journal note", err)
+		return
+	}
+
+	if created {
+		services.Audit(r, services.AuditEntry{
+			Operation: services.AuditNoteCreate,
+			NoteIDs:   []int64{id},
+			Details:   fmt.Sprintf("journal %s", date.Format(services.JournalDateLayout)),
+		})
+	}
+
+	respondWithNote(w, r, date, id, created)
+}
+
+// GetPrevEntryHandler - GET - get nearest journal note before date
+func GetPrevEntryHandler(w http.ResponseWriter, r *http.Request) {
+	adjacentEntry(w, r, -1)
+}
+
+// GetNextEntryHandler - GET - get nearest journal note after date
+func GetNextEntryHandler(w http.ResponseWriter, r *http.Request) {
+	adjacentEntry(w, r, 1)
+}
+
+// adjacentEntry - respond with nearest journal note before (step < 0) or after (step > 0) date
+func adjacentEntry(w http.ResponseWriter, r *http.Request, step int) {
+	services.SetCommonResponseHeaders(w, r)
+
+	journal, ok := getSettings(w, r)
+	if !ok {
+		return
+	}
+	date, ok := parseDate(w, r)
+	if !ok {
+		return
+	}
+
+	entry, err := services.GetAdjacentJournalEntry(r.Context(), services.GetContextUser(r.Context()), journal, date, step)
+	if errors.Is(err, services.ErrJournalNotFound) {
+		services.RespondWithError(w, http.StatusNotFound, "Journal note is not found", nil)
+		return
+	}
+	if err != nil {
+		services.RespondWithError(w, http.StatusInternalServerError, "Failed to fetch journal note", err)
+		return
+	}
+
+	entryDate, _ := time.ParseInLocation(services.JournalDateLayout, entry.Date, time.Local)
+	respondWithNote(w, r, entryDate, entry.ID, false)
+}
+
+// getSettings - load journal settings of user, respond with error if they are wrong
+func getSettings(w http.ResponseWriter, r *http.Request) (services.JournalSettings, bool) {
+	journal, err := services.GetJournalSettings(r.Context(), services.GetContextUser(r.Context()))
+	if err != nil {
+		services.RespondWithError(w, http.StatusInternalServerError, "Wrong journal settings", err)
+		return journal, false
+	}
+	return journal, true
+}
+
+// parseDate - get date from URL (`today` is current time of server, it is used for {{time}} in template)
+func parseDate(w http.ResponseWriter, r *http.Request) (time.Time, bool) {
+	value := mux.Vars(r)["date"]
+	if value == "today" {
+		return time.Now(), true
+	}
+
+	date, err := time.ParseInLocation(services.JournalDateLayout, value, time.Local)
+	if err != nil {
+		services.RespondWithError(w, http.StatusBadRequest, "Wrong date", err)
+		return time.Time{}, false
+	}
+	return date, true
+}
+
+// respondWithNote - send journal note (user must be able to view it)
+func respondWithNote(w http.ResponseWriter, r *http.Request, date time.Time, id int64, created bool) {
+	note, err := services.GetUnlockedNote(r.Context(), services.GetContextKeyring(r.Context()), int(id))
+	if err != nil {
+		services.RespondWithError(w, http.StatusNotFound, "Journal note is not found", nil)
+		return
+	}
+	if !services.CheckNoteRole(w, r, note, services.RoleViewer) {
+		return
+	}
+
+	// Create response
+	response := map[string]any{
+		"success": true,
+		"date":    date.Format(services.JournalDateLayout),
+		"created": created,
+		"note":    note,
+	}
+
+	// Send response
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
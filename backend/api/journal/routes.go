@@ -0,0 +1,14 @@
+package journal
+
+import (
+	"github.com/gorilla/mux"
+)
+
+// RegisterRoutes - register all routes for daily journal
+func RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/api/journal/dates", GetDatesHandler).Methods("GET")
+	router.HandleFunc("/api/journal/{date:today|[0-9]{4}-[0-9]{2}-[0-9]{2}}", GetEntryHandler).Methods("GET")
+	router.HandleFunc("/api/journal/{date:today|[0-9]{4}-[0-9]{2}-[0-9]{2}}", PostEntryHandler).Methods("POST")
+	router.HandleFunc("/api/journal/{date:today|[0-9]{4}-[0-9]{2}-[0-9]{2}}/prev", GetPrevEntryHandler).Methods("GET")
+	router.HandleFunc("/api/journal/{date:today|[0-9]{4}-[0-9]{2}-[0-9]{2}}/next", GetNextEntryHandler).Methods("GET")
+}
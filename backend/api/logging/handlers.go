@@ -0,0 +1,83 @@
+package logging
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/sondrus/tetrad/logging"
+	"github.com/sondrus/tetrad/services"
+)
+
+// GetLoggingHandler - GET - get current log level and SQL tracing flag (admin only)
+func GetLoggingHandler(w http.ResponseWriter, r *http.Request) {
+	services.SetCommonResponseHeaders(w, r)
+
+	if !services.CheckAdmin(w, r) {
+		return
+	}
+
+	// Create response
+	response := map[string]any{
+		"success":  true,
+		"level":    logging.GetLevel(),
+		"sqlTrace": logging.IsSQLTrace(),
+	}
+
+	// Send response
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// PatchLoggingHandler - PATCH - change log level and/or SQL tracing until restart (admin only)
+func PatchLoggingHandler(w http.ResponseWriter, r *http.Request) {
+	services.SetCommonResponseHeaders(w, r)
+
+	if !services.CheckAdmin(w, r) {
+		return
+	}
+
+	// Declare JSON structure
+	var req struct {
+		Level    *string `json:"level"`
+		SQLTrace *bool   `json:"sqlTrace"`
+	}
+
+	// Decode input JSON
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		services.RespondWithError(w, http.StatusBadRequest, "Invalid JSON", err)
+		return
+	}
+
+	var fields []string
+	if req.Level != nil {
+		if err := logging.SetLevel(*req.Level); err != nil {
+			services.RespondWithError(w, http.StatusBadRequest, "Invalid log level", err)
+			return
+		}
+		fields = append(fields, "level")
+	}
+	if req.SQLTrace != nil {
+		logging.SetSQLTrace(*req.SQLTrace)
+		fields = append(fields, "sqlTrace")
+	}
+
+	slog.Info("Logging is changed", "level", logging.GetLevel(), "sqlTrace", logging.IsSQLTrace())
+	services.Audit(r, services.AuditEntry{
+		Operation: services.AuditLogging,
+		Fields:    fields,
+		Details:   fmt.Sprintf("level %s sqlTrace=%t", logging.GetLevel(), logging.IsSQLTrace()),
+	})
+
+	// Create response
+	response := map[string]any{
+		"success":  true,
+		"level":    logging.GetLevel(),
+		"sqlTrace": logging.IsSQLTrace(),
+	}
+
+	// Send response
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,11 @@
+package logging
+
+import (
+	"github.com/gorilla/mux"
+)
+
+// RegisterRoutes - register all routes for logging settings
+func RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/api/logging", GetLoggingHandler).Methods("GET")
+	router.HandleFunc("/api/logging", PatchLoggingHandler).Methods("PATCH")
+}
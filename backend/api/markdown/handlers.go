@@ -0,0 +1,41 @@
+package markdown
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/sondrus/tetrad/services"
+)
+
+// RenderMarkdownHandler - POST - render arbitrary markdown to sanitized HTML
+func RenderMarkdownHandler(w http.ResponseWriter, r *http.Request) {
+	services.SetCommonResponseHeaders(w, r)
+
+	// Declare JSON POST structure
+	var req struct {
+		Markdown string `json:"markdown"`
+	}
+
+	// Decode input JSON
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		services.RespondWithError(w, http.StatusBadRequest, "Invalid JSON", err)
+		return
+	}
+
+	// Render
+	html, err := services.RenderMarkdown(req.Markdown)
+	if err != nil {
+		services.RespondWithError(w, http.StatusInternalServerError, "Failed to render markdown", err)
+		return
+	}
+
+	// Create response
+	response := map[string]any{
+		"success": true,
+		"html":    html,
+	}
+
+	// Send response
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
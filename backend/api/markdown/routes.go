@@ -0,0 +1,10 @@
+package markdown
+
+import (
+	"github.com/gorilla/mux"
+)
+
+// RegisterRoutes - registers all routes for markdown rendering
+func RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/api/markdown/render", RenderMarkdownHandler).Methods("POST")
+}
@@ -2,11 +2,20 @@ package note
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/gorilla/mux"
+	"github.com/sondrus/tetrad/auth"
+	"github.com/sondrus/tetrad/clipper"
+	"github.com/sondrus/tetrad/config"
 	"github.com/sondrus/tetrad/database"
+	"github.com/sondrus/tetrad/events"
 	"github.com/sondrus/tetrad/models"
 	"github.com/sondrus/tetrad/services"
 	"gorm.io/gorm"
@@ -14,16 +23,21 @@ import (
 
 // GetNoteHandler - GET - get single note by ID
 func GetNoteHandler(w http.ResponseWriter, r *http.Request) {
-	services.SetCommonResponseHeaders(w)
+	services.SetCommonResponseHeaders(w, r)
 
 	// Get note from database
 	ID := r.Context().Value(database.IDKey).(int)
-	note, err := services.GetNote(ID)
+	note, err := services.GetUnlockedNote(r.Context(), services.GetContextKeyring(r.Context()), ID)
 	if err != nil {
 		services.RespondWithError(w, http.StatusNotFound, "Note is not found", nil)
 		return
 	}
 
+	// Check user can view note
+	if !services.CheckNoteRole(w, r, note, services.RoleViewer) {
+		return
+	}
+
 	// Create response
 	response := map[string]any{
 		"success": true,
@@ -35,9 +49,42 @@ func GetNoteHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
+// GetNoteHTMLHandler - GET - get single note rendered to sanitized HTML
+func GetNoteHTMLHandler(w http.ResponseWriter, r *http.Request) {
+	services.SetCommonResponseHeaders(w, r)
+
+	// Get note from database
+	ID := r.Context().Value(database.IDKey).(int)
+	note, err := services.GetUnlockedNote(r.Context(), services.GetContextKeyring(r.Context()), ID)
+	if err != nil {
+		services.RespondWithError(w, http.StatusNotFound, "Note is not found", nil)
+		return
+	}
+
+	// Check user can view note
+	if !services.CheckNoteRole(w, r, note, services.RoleViewer) {
+		return
+	}
+
+	// Render note contents
+	html, err := services.RenderNote(r.Context(), note)
+	if errors.Is(err, services.ErrLocked) {
+		services.RespondWithError(w, http.StatusLocked, "Note is encrypted and locked", nil)
+		return
+	}
+	if err != nil {
+		services.RespondWithError(w, http.StatusBadRequest, "Failed to render note", err)
+		return
+	}
+
+	// Send response
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(html))
+}
+
 // PostNoteHandler - POST - create new note
 func PostNoteHandler(w http.ResponseWriter, r *http.Request) {
-	services.SetCommonResponseHeaders(w)
+	services.SetCommonResponseHeaders(w, r)
 
 	// Decode request json into map
 	var fields map[string]any
@@ -49,36 +96,40 @@ func PostNoteHandler(w http.ResponseWriter, r *http.Request) {
 	// Changes keys from JSON to GORM
 	fields = services.NormalizeNoteKeys(fields)
 
-	// If exists 'ID', delete it
-	delete(fields, "ID")
-
-	// Set dates
-	now := time.Now().Unix()
-	fields["DATE_CREATED"] = now
-	fields["DATE_MODIFIED"] = now
-
-	// Set empty contents if not set
-	if _, exists := fields["CONTENTS"]; !exists {
-		fields["CONTENTS"] = ""
-	}
-
 	// Insert new note to DB
-	db := database.GetORM()
-	result := db.Model(&models.NoteDB{}).Create(fields)
-	if result.Error != nil {
-		errorMessage := fmt.Sprintf("Error creating note: %v", result.Error)
-		services.RespondWithError(w, http.StatusInternalServerError, errorMessage, nil)
+	id, err := services.CreateNote(r.Context(), services.GetContextUser(r.Context()), services.GetContextKeyring(r.Context()), fields)
+	switch {
+	case errors.Is(err, services.ErrAccessDenied):
+		services.RespondWithError(w, http.StatusForbidden, "Access denied", nil)
+		return
+	case errors.Is(err, services.ErrParentNotFound):
+		services.RespondWithError(w, http.StatusNotFound, "Parent note is not found", nil)
+		return
+	case errors.Is(err, services.ErrLocked):
+		services.RespondWithError(w, http.StatusLocked, "Note is encrypted and locked", nil)
+		return
+	case err != nil:
+		services.RespondWithError(w, http.StatusInternalServerError, "Failed to create note", err)
 		return
 	}
 
-	// Nested set rebuild
-	services.RebuildNotesTree()
+	services.Audit(r, services.AuditEntry{
+		Operation: services.AuditNoteCreate,
+		NoteIDs:   []int64{id},
+		Fields:    fieldNames(fields),
+		Details:   fmt.Sprintf("parent %d", toInt64(fields["PARENT_ID"])),
+	})
+
+	// Page of new URL note is archived while user works on
+	if url, _ := fields["URL"].(string); config.AppConfig.ClipperAuto && fields["TYPE"] == "URL" && strings.TrimSpace(url) != "" {
+		services.ClipNoteInBackground(r, id, clipper.DefaultOptions())
+	}
 
 	// Create response
 	response := map[string]any{
 		"success": true,
-		"date":    now,
-		"id":      fields["ID"],
+		"date":    fields["DATE_MODIFIED"],
+		"id":      id,
 	}
 
 	// Send response
@@ -88,7 +139,7 @@ func PostNoteHandler(w http.ResponseWriter, r *http.Request) {
 
 // PatchNoteHandler - PATCH - update some fields for note
 func PatchNoteHandler(w http.ResponseWriter, r *http.Request) {
-	services.SetCommonResponseHeaders(w)
+	services.SetCommonResponseHeaders(w, r)
 
 	// Decode request json into map
 	var fields map[string]any
@@ -102,34 +153,117 @@ func PatchNoteHandler(w http.ResponseWriter, r *http.Request) {
 
 	// Get note from database
 	ID := r.Context().Value(database.IDKey).(int)
-	_, err := services.GetNote(ID)
+	note, err := services.GetNote(r.Context(), ID)
 	if err != nil {
 		services.RespondWithError(w, http.StatusNotFound, "Note is not found", nil)
 		return
 	}
 
+	// Check user can edit note
+	if !services.CheckNoteRole(w, r, note, services.RoleEditor) {
+		return
+	}
+
+	// Nested set values define permission scopes, so they are changed by moving only
+	if keys := services.ProtectedNoteKeys(fields); len(keys) > 0 {
+		services.RespondWithError(w, http.StatusBadRequest, "Fields could not be changed: "+strings.Join(keys, ", "), nil)
+		return
+	}
+
 	// If exists 'ID', delete it
 	delete(fields, "ID")
 
+	// Move to other parent: owner of note and editor of new parent
+	user := services.GetContextUser(r.Context())
+	newParentID, moved := fields["PARENT_ID"]
+	moved = moved && toInt64(newParentID) != note.ParentID
+	if moved {
+		if !services.HasNoteRole(r.Context(), user, note, services.RoleOwner) ||
+			!services.HasParentRole(r.Context(), user, toInt64(newParentID), services.RoleEditor) {
+			services.RespondWithError(w, http.StatusForbidden, "Access denied", nil)
+			return
+		}
+	}
+
+	// Encrypted subtree: notes could not be moved in or out, contents are encrypted
+	vault, err := services.GetVaultByNote(r.Context(), note)
+	if err != nil {
+		services.RespondWithError(w, http.StatusInternalServerError, "Failed to check encryption", err)
+		return
+	}
+	if moved {
+		parentVault, err := services.GetVaultByParent(r.Context(), toInt64(newParentID))
+		if err != nil {
+			services.RespondWithError(w, http.StatusNotFound, "Parent note is not found", nil)
+			return
+		}
+		movesRoot := vault != nil && vault.NoteID == note.ID && parentVault == nil
+		if !movesRoot && vaultID(vault) != vaultID(parentVault) {
+			services.RespondWithError(w, http.StatusBadRequest, "Notes could not be moved into or out of encrypted subtree", nil)
+			return
+		}
+	}
+	if !sealContents(w, r, vault, fields) {
+		return
+	}
+
 	// Set dates
 	now := time.Now().Unix()
 	fields["DATE_MODIFIED"] = now
 
-	// Update fields
-	db := database.GetORM()
-	err = db.Model(&models.NoteDB{}).Where("ID = ?", ID).Updates(fields).Error
+	// Update fields, note moved to root keeps accessible for its owner
+	db := database.GetContextORM(r.Context())
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.NoteDB{}).Where("ID = ?", ID).Updates(fields).Error; err != nil {
+			return err
+		}
+		if moved && toInt64(newParentID) == 0 && !services.IsAdmin(user) {
+			return services.SaveNotePermission(tx, user.ID, note.ID, services.RoleOwner)
+		}
+		return nil
+	})
 	if err != nil {
 		errorMessage := fmt.Sprintf("Error updating note: %v", err)
 		services.RespondWithError(w, http.StatusInternalServerError, errorMessage, nil)
 		return
 	}
 
+	// Checkbox items of markdown notes
+	_, hasContents := fields["CONTENTS"]
+	_, hasType := fields["TYPE"]
+	if hasContents || hasType {
+		services.IndexNoteTasks(r.Context(), note.ID)
+	}
+
 	// Nested set rebuild (just if has PARENT_ID or TITLE)
 	_, hasParent := fields["PARENT_ID"]
 	_, hasTitle := fields["TITLE"]
 	if hasParent || hasTitle {
-		services.RebuildNotesTree()
+		services.RebuildNotesTree(r.Context())
+	}
+
+	event := events.Event{
+		Type:         events.Updated,
+		NoteIDs:      []int64{note.ID},
+		ParentID:     note.ParentID,
+		DateModified: now,
+	}
+	if moved {
+		event.Type = events.Moved
+		event.ParentID = toInt64(newParentID)
+	}
+	services.PublishEvent(r.Context(), event)
+
+	entry := services.AuditEntry{
+		Operation: services.AuditNoteUpdate,
+		NoteIDs:   []int64{note.ID},
+		Fields:    fieldNames(fields),
+	}
+	if moved {
+		entry.Operation = services.AuditNoteMove
+		entry.Details = fmt.Sprintf("parent %d => %d", note.ParentID, toInt64(newParentID))
 	}
+	services.Audit(r, entry)
 
 	// Create response
 	response := map[string]any{
@@ -143,26 +277,154 @@ func PatchNoteHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
+// PostClipHandler - POST - fetch page of URL note and save its readable copy as note contents
+func PostClipHandler(w http.ResponseWriter, r *http.Request) {
+	services.SetCommonResponseHeaders(w, r)
+
+	// ID is not put to context for POST requests
+	ID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil || ID <= 0 {
+		services.RespondWithError(w, http.StatusBadRequest, "Wrong note ID in query", nil)
+		return
+	}
+	note, err := services.GetNote(r.Context(), ID)
+	if err != nil {
+		services.RespondWithError(w, http.StatusNotFound, "Note is not found", nil)
+		return
+	}
+
+	// Check user can edit note
+	if !services.CheckNoteRole(w, r, note, services.RoleEditor) {
+		return
+	}
+
+	// Fetch page and save it
+	keyring := services.GetContextKeyring(r.Context())
+	fields, err := services.ClipNote(r.Context(), keyring, note.ID, clipper.DefaultOptions())
+	switch {
+	case errors.Is(err, services.ErrNotURLNote):
+		services.RespondWithError(w, http.StatusBadRequest, "Note has no URL", nil)
+		return
+	case errors.Is(err, services.ErrLocked):
+		services.RespondWithError(w, http.StatusLocked, "Note is encrypted and locked", nil)
+		return
+	case errors.Is(err, services.ErrNoteChanged):
+		services.RespondWithError(w, http.StatusConflict, "Note is changed while page was fetched", nil)
+		return
+	case errors.Is(err, clipper.ErrForbidden):
+		services.RespondWithError(w, http.StatusForbidden, "Page host is not allowed", err)
+		return
+	case errors.Is(err, clipper.ErrTooLarge):
+		services.RespondWithError(w, http.StatusRequestEntityTooLarge, "Page is too large", err)
+		return
+	case errors.Is(err, clipper.ErrNotHTML):
+		services.RespondWithError(w, http.StatusUnsupportedMediaType, "Page is not HTML", err)
+		return
+	case err != nil:
+		services.RespondWithError(w, http.StatusBadGateway, "Failed to fetch page", err)
+		return
+	}
+
+	services.Audit(r, services.AuditEntry{
+		Operation: services.AuditNoteUpdate,
+		NoteIDs:   []int64{note.ID},
+		Fields:    fields,
+		Details:   "web clipper",
+	})
+
+	// Note with archived copy
+	note, err = services.GetUnlockedNote(r.Context(), keyring, ID)
+	if err != nil {
+		services.RespondWithError(w, http.StatusNotFound, "Note is not found", nil)
+		return
+	}
+
+	// Create response
+	response := map[string]any{
+		"success": true,
+		"note":    note,
+	}
+
+	// Send response
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
 // DeleteNoteHandler - DELETE - delete single note with its children
 func DeleteNoteHandler(w http.ResponseWriter, r *http.Request) {
-	services.SetCommonResponseHeaders(w)
+	services.SetCommonResponseHeaders(w, r)
 
 	// Get note from database
 	ID := r.Context().Value(database.IDKey).(int)
-	note, err := services.GetNote(ID)
+	note, err := services.GetNote(r.Context(), ID)
 	if err != nil {
 		services.RespondWithError(w, http.StatusNotFound, "Note is not found", nil)
 		return
 	}
 
+	// Check user is owner of note
+	if !services.CheckNoteRole(w, r, note, services.RoleOwner) {
+		return
+	}
+
+	// IDs of the note and its children (for notification), the note is first
+	var deletedIDs []int64
+	db := database.GetContextORM(r.Context())
+	db.Model(&models.NoteDB{}).Where("LEFT >= ? AND RIGHT <= ?", note.Left, note.Right).Order(`"LEFT" ASC`).Pluck("ID", &deletedIDs)
+
+	// Users who could see deleted notes (for notification)
+	viewers, err := services.GetSubtreeViewers(r.Context(), note)
+	if err != nil {
+		services.RespondWithError(w, http.StatusInternalServerError, "Failed to check permissions", err)
+		return
+	}
+
 	// Delete child notes in transaction
-	db := database.GetORM()
 	err = db.Transaction(func(tx *gorm.DB) error {
 		// Fetch LEFT, RIGHT, and DEPTH values from the note object
 		left := note.Left
 		right := note.Right
 		depth := note.Depth
 
+		// Delete resources (attached files) of the note and its children
+		if err := tx.Where("NOTE_ID IN (?)",
+			tx.Model(&models.NoteDB{}).Select("ID").Where("LEFT >= ? AND RIGHT <= ?", left, right),
+		).Delete(&models.Resource{}).Error; err != nil {
+			return fmt.Errorf("failed to delete resources for note ID %d: %w", ID, err)
+		}
+
+		// Delete permissions for the note and its children
+		if err := tx.Where("NOTE_ID IN (?)",
+			tx.Model(&models.NoteDB{}).Select("ID").Where("LEFT >= ? AND RIGHT <= ?", left, right),
+		).Delete(&models.Permission{}).Error; err != nil {
+			return fmt.Errorf("failed to delete permissions for note ID %d: %w", ID, err)
+		}
+
+		// Delete data and grants of IFRAME notes
+		if err := services.DeleteBridgeRecords(tx, left, right); err != nil {
+			return fmt.Errorf("failed to delete app data for note ID %d: %w", ID, err)
+		}
+
+		// Delete indexed tasks
+		if err := services.DeleteTasks(tx, left, right); err != nil {
+			return err
+		}
+
+		// Delete reminders
+		if err := services.DeleteReminders(tx, left, right); err != nil {
+			return err
+		}
+
+		// Delete share links
+		if err := services.DeleteShares(tx, left, right); err != nil {
+			return err
+		}
+
+		// Delete encrypted subtrees
+		if err := services.DeleteVaults(tx, left, right); err != nil {
+			return fmt.Errorf("failed to delete encryption for note ID %d: %w", ID, err)
+		}
+
 		// Delete all child notes based on LEFT, RIGHT, and DEPTH
 		if err := tx.Where("LEFT > ? AND RIGHT < ? AND DEPTH > ?", left, right, depth).
 			Delete(&models.NoteDB{}).Error; err != nil {
@@ -183,7 +445,19 @@ func DeleteNoteHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Nested set rebuild
-	services.RebuildNotesTree()
+	services.RebuildNotesTree(r.Context())
+
+	services.PublishEvent(r.Context(), events.Event{
+		Type:     events.Deleted,
+		NoteIDs:  deletedIDs,
+		ParentID: note.ParentID,
+		Viewers:  viewers,
+	})
+	services.Audit(r, services.AuditEntry{
+		Operation: services.AuditNoteDelete,
+		NoteIDs:   deletedIDs,
+		Details:   fmt.Sprintf("%q with %d children", note.Title, len(deletedIDs)-1),
+	})
 
 	// Create response
 	response := map[string]any{
@@ -194,3 +468,275 @@ func DeleteNoteHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
+
+// GetPermissionsHandler - GET - get permissions for note (including inherited from parents)
+func GetPermissionsHandler(w http.ResponseWriter, r *http.Request) {
+	services.SetCommonResponseHeaders(w, r)
+
+	// Get note from database
+	ID := r.Context().Value(database.IDKey).(int)
+	note, err := services.GetNote(r.Context(), ID)
+	if err != nil {
+		services.RespondWithError(w, http.StatusNotFound, "Note is not found", nil)
+		return
+	}
+
+	// Check user is owner of note
+	if !services.CheckNoteRole(w, r, note, services.RoleOwner) {
+		return
+	}
+
+	// Get permissions
+	permissions, err := services.GetNotePermissions(r.Context(), note)
+	if err != nil {
+		services.RespondWithError(w, http.StatusInternalServerError, "Failed to fetch permissions", err)
+		return
+	}
+
+	// Create response
+	response := map[string]any{
+		"success":     true,
+		"permissions": permissions,
+	}
+
+	// Send response
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// PutPermissionHandler - PUT - set user role for note subtree (empty role = remove)
+func PutPermissionHandler(w http.ResponseWriter, r *http.Request) {
+	services.SetCommonResponseHeaders(w, r)
+
+	// Declare JSON structure
+	var req struct {
+		UserID int64  `json:"userId"`
+		Role   string `json:"role"`
+	}
+
+	// Decode input JSON
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		services.RespondWithError(w, http.StatusBadRequest, "Invalid JSON", err)
+		return
+	}
+
+	// Check role
+	if req.Role != "" && !services.IsValidRole(req.Role) {
+		services.RespondWithError(w, http.StatusBadRequest, "Unknown role", nil)
+		return
+	}
+
+	// Get note from database
+	ID := r.Context().Value(database.IDKey).(int)
+	note, err := services.GetNote(r.Context(), ID)
+	if err != nil {
+		services.RespondWithError(w, http.StatusNotFound, "Note is not found", nil)
+		return
+	}
+
+	// Check user is owner of note
+	if !services.CheckNoteRole(w, r, note, services.RoleOwner) {
+		return
+	}
+
+	// Check user exists
+	if _, err := services.GetUser(req.UserID); err != nil {
+		services.RespondWithError(w, http.StatusNotFound, "User is not found", nil)
+		return
+	}
+
+	// Save
+	if err := services.SetNotePermission(r.Context(), req.UserID, note.ID, req.Role); err != nil {
+		services.RespondWithError(w, http.StatusInternalServerError, "Failed to save permission", err)
+		return
+	}
+	services.Audit(r, services.AuditEntry{
+		Operation: services.AuditNotePermission,
+		NoteIDs:   []int64{note.ID},
+		Details:   fmt.Sprintf("user %d role %q", req.UserID, req.Role),
+	})
+
+	// Create response
+	response := map[string]any{
+		"success": true,
+	}
+
+	// Send response
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// PutEncryptionHandler - PUT - encrypt note with its children by passphrase
+func PutEncryptionHandler(w http.ResponseWriter, r *http.Request) {
+	services.SetCommonResponseHeaders(w, r)
+
+	// Declare JSON structure
+	var req struct {
+		Passphrase string `json:"passphrase"`
+	}
+
+	// Decode input JSON
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		services.RespondWithError(w, http.StatusBadRequest, "Invalid JSON", err)
+		return
+	}
+
+	// Get note from database
+	ID := r.Context().Value(database.IDKey).(int)
+	note, err := services.GetNote(r.Context(), ID)
+	if err != nil {
+		services.RespondWithError(w, http.StatusNotFound, "Note is not found", nil)
+		return
+	}
+
+	// Check user is owner of note
+	if !services.CheckNoteRole(w, r, note, services.RoleOwner) {
+		return
+	}
+
+	// Encrypt
+	vault, key, err := services.EncryptSubtree(r.Context(), note, req.Passphrase)
+	if err != nil {
+		services.RespondWithError(w, http.StatusBadRequest, "Failed to encrypt note", err)
+		return
+	}
+
+	services.Audit(r, services.AuditEntry{
+		Operation: services.AuditNoteEncrypt,
+		NoteIDs:   []int64{note.ID},
+	})
+
+	// Keep unlocked for current session
+	user := services.GetContextUser(r.Context())
+	if err := auth.UnlockKey(w, r, user, vault.ID, key); err != nil {
+		services.RespondWithError(w, http.StatusInternalServerError, "Failed to unlock note", err)
+		return
+	}
+
+	// Create response
+	response := map[string]any{
+		"success": true,
+		"vault":   vault,
+	}
+
+	// Send response
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// DeleteEncryptionHandler - DELETE - decrypt note with its children (passphrase is required)
+func DeleteEncryptionHandler(w http.ResponseWriter, r *http.Request) {
+	services.SetCommonResponseHeaders(w, r)
+
+	// Declare JSON structure
+	var req struct {
+		Passphrase string `json:"passphrase"`
+	}
+
+	// Decode input JSON
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		services.RespondWithError(w, http.StatusBadRequest, "Invalid JSON", err)
+		return
+	}
+
+	// Get note from database
+	ID := r.Context().Value(database.IDKey).(int)
+	note, err := services.GetNote(r.Context(), ID)
+	if err != nil {
+		services.RespondWithError(w, http.StatusNotFound, "Note is not found", nil)
+		return
+	}
+
+	// Check user is owner of note
+	if !services.CheckNoteRole(w, r, note, services.RoleOwner) {
+		return
+	}
+
+	// Encryption could be removed just for whole subtree
+	vault, err := services.GetVaultByNote(r.Context(), note)
+	if err != nil || vault == nil || vault.NoteID != note.ID {
+		services.RespondWithError(w, http.StatusBadRequest, "Note is not a root of encrypted subtree", nil)
+		return
+	}
+
+	// Check passphrase
+	key, err := services.UnlockVault(vault, req.Passphrase)
+	if err != nil {
+		services.RespondWithError(w, http.StatusForbidden, "Wrong passphrase", nil)
+		return
+	}
+
+	// Decrypt
+	if err := services.DecryptSubtree(r.Context(), vault, key); err != nil {
+		services.RespondWithError(w, http.StatusInternalServerError, "Failed to decrypt note", err)
+		return
+	}
+	auth.ForgetVault(database.GetContextNotebook(r.Context()).Name, vault.ID)
+	services.Audit(r, services.AuditEntry{
+		Operation: services.AuditNoteDecrypt,
+		NoteIDs:   []int64{note.ID},
+	})
+
+	// Create response
+	response := map[string]any{
+		"success": true,
+	}
+
+	// Send response
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// sealContents - encrypt CONTENTS in fields if note is in vault, respond 423 if vault is locked
+func sealContents(w http.ResponseWriter, r *http.Request, vault *models.Vault, fields map[string]any) bool {
+	contents, ok := fields["CONTENTS"].(string)
+	if !ok || vault == nil {
+		return true
+	}
+
+	sealed, err := services.SealContents(services.GetContextKeyring(r.Context()), vault, contents)
+	if errors.Is(err, services.ErrLocked) {
+		services.RespondWithError(w, http.StatusLocked, "Note is encrypted and locked", nil)
+		return false
+	}
+	if err != nil {
+		services.RespondWithError(w, http.StatusInternalServerError, "Failed to encrypt note", err)
+		return false
+	}
+
+	fields["CONTENTS"] = sealed
+	return true
+}
+
+// vaultID - ID of vault (0 = not encrypted)
+func vaultID(vault *models.Vault) int64 {
+	if vault == nil {
+		return 0
+	}
+	return vault.ID
+}
+
+// fieldNames - sorted names of changed fields (ID and dates are skipped)
+func fieldNames(fields map[string]any) []string {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		if name != "ID" && name != "DATE_CREATED" && name != "DATE_MODIFIED" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// toInt64 - convert JSON number (float64) to int64
+func toInt64(value any) int64 {
+	switch v := value.(type) {
+	case float64:
+		return int64(v)
+	case int64:
+		return v
+	case int:
+		return int64(v)
+	}
+	return 0
+}
@@ -8,6 +8,12 @@ import (
 func RegisterRoutes(router *mux.Router) {
 	router.HandleFunc("/api/note/add", PostNoteHandler).Methods("POST")
 	router.HandleFunc("/api/note/{id:[0-9]+}", GetNoteHandler).Methods("GET")
+	router.HandleFunc("/api/note/{id:[0-9]+}/html", GetNoteHTMLHandler).Methods("GET")
+	router.HandleFunc("/api/note/{id:[0-9]+}/clip", PostClipHandler).Methods("POST")
 	router.HandleFunc("/api/note/{id:[0-9]+}", PatchNoteHandler).Methods("PATCH")
 	router.HandleFunc("/api/note/{id:[0-9]+}", DeleteNoteHandler).Methods("DELETE")
+	router.HandleFunc("/api/note/{id:[0-9]+}/permissions", GetPermissionsHandler).Methods("GET")
+	router.HandleFunc("/api/note/{id:[0-9]+}/permissions", PutPermissionHandler).Methods("PUT")
+	router.HandleFunc("/api/note/{id:[0-9]+}/encryption", PutEncryptionHandler).Methods("PUT")
+	router.HandleFunc("/api/note/{id:[0-9]+}/encryption", DeleteEncryptionHandler).Methods("DELETE")
 }
@@ -0,0 +1,166 @@
+package notebooks
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+
+	"github.com/gorilla/mux"
+	"github.com/sondrus/tetrad/config"
+	"github.com/sondrus/tetrad/database"
+	"github.com/sondrus/tetrad/services"
+)
+
+// GetNotebooksHandler - GET - get registered notebooks, main database is the first
+// File paths and sizes are shown to admin only
+func GetNotebooksHandler(w http.ResponseWriter, r *http.Request) {
+	services.SetCommonResponseHeaders(w, r)
+
+	notebooks, err := database.GetNotebooks()
+	if err != nil {
+		services.RespondWithError(w, http.StatusInternalServerError, "Failed to fetch notebooks", err)
+		return
+	}
+
+	if !services.IsAdmin(services.GetContextUser(r.Context())) {
+		for i := range notebooks {
+			notebooks[i].Path = ""
+			notebooks[i].Size = 0
+		}
+	}
+
+	// Create response
+	response := map[string]any{
+		"success":   true,
+		"notebooks": notebooks,
+		"current":   database.GetContextNotebook(r.Context()).Name,
+	}
+
+	// Send response
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// PostNotebookHandler - POST - register database file as notebook and open it (admin only)
+// Without `path` new database is created in ~/.tetrad/notebooks, with `path` the file must exist (or `create` is set)
+func PostNotebookHandler(w http.ResponseWriter, r *http.Request) {
+	services.SetCommonResponseHeaders(w, r)
+
+	if !services.CheckAdmin(w, r) {
+		return
+	}
+
+	// Declare JSON POST structure
+	var req struct {
+		Name   string `json:"name"`
+		Path   string `json:"path"`
+		Create bool   `json:"create"`
+	}
+
+	// Decode input JSON
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		services.RespondWithError(w, http.StatusBadRequest, "Invalid JSON", err)
+		return
+	}
+
+	if !database.IsValidNotebookName(req.Name) {
+		services.RespondWithError(w, http.StatusBadRequest, "Invalid notebook name (lowercase letters, digits, '-' and '_')", nil)
+		return
+	}
+	if req.Path == "" {
+		req.Path = filepath.Join(config.AppConfig.DataDir, "notebooks", req.Name+".db")
+		req.Create = true
+	}
+
+	notebook, err := database.CreateNotebook(req.Name, req.Path, req.Create)
+	if err != nil {
+		services.RespondWithError(w, http.StatusBadRequest, "Failed to create notebook", err)
+		return
+	}
+	if opened, err := database.GetNotebook(notebook.Name); err == nil {
+		services.ReindexNotebookTasks(opened)
+	}
+	services.Audit(r, services.AuditEntry{
+		Operation: services.AuditNotebookCreate,
+		Details:   fmt.Sprintf("notebook %s, file %s", notebook.Name, notebook.Path),
+	})
+
+	// Create response
+	response := map[string]any{
+		"success":  true,
+		"notebook": notebook,
+	}
+
+	// Send response
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// OpenNotebookHandler - POST - open registered notebook (admin only)
+func OpenNotebookHandler(w http.ResponseWriter, r *http.Request) {
+	changeNotebook(w, r, openNotebook, services.AuditNotebookOpen)
+}
+
+// CloseNotebookHandler - POST - close notebook, it stays registered (admin only)
+func CloseNotebookHandler(w http.ResponseWriter, r *http.Request) {
+	changeNotebook(w, r, database.CloseNotebook, services.AuditNotebookClose)
+}
+
+// DeleteNotebookHandler - DELETE - close notebook and remove it from registry, database file is kept (admin only)
+func DeleteNotebookHandler(w http.ResponseWriter, r *http.Request) {
+	changeNotebook(w, r, database.RemoveNotebook, services.AuditNotebookRemove)
+}
+
+// changeNotebook - run operation for notebook from URL and record it in audit log
+func changeNotebook(w http.ResponseWriter, r *http.Request, operation func(name string) error, auditOperation string) {
+	services.SetCommonResponseHeaders(w, r)
+
+	if !services.CheckAdmin(w, r) {
+		return
+	}
+
+	name := mux.Vars(r)["name"]
+	if name == database.DefaultNotebook {
+		services.RespondWithError(w, http.StatusBadRequest, "Main database could not be changed", nil)
+		return
+	}
+
+	if err := operation(name); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, database.ErrNotebookNotFound) {
+			status = http.StatusNotFound
+		}
+		services.RespondWithError(w, status, "Failed to change notebook", err)
+		return
+	}
+	services.Audit(r, services.AuditEntry{
+		Operation: auditOperation,
+		Details:   "notebook " + name,
+	})
+
+	// Create response
+	response := map[string]any{
+		"success": true,
+	}
+
+	// Send response
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// openNotebook - open notebook and index its tasks (file could be changed while it was closed)
+func openNotebook(name string) error {
+	if err := database.OpenNotebook(name); err != nil {
+		return err
+	}
+
+	notebook, err := database.GetNotebook(name)
+	if err != nil {
+		return err
+	}
+	services.ReindexNotebookTasks(notebook)
+
+	return nil
+}
@@ -0,0 +1,14 @@
+package notebooks
+
+import (
+	"github.com/gorilla/mux"
+)
+
+// RegisterRoutes - register all routes for notebooks (separate database files)
+func RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/api/notebooks", GetNotebooksHandler).Methods("GET")
+	router.HandleFunc("/api/notebooks", PostNotebookHandler).Methods("POST")
+	router.HandleFunc("/api/notebooks/{name}/open", OpenNotebookHandler).Methods("POST")
+	router.HandleFunc("/api/notebooks/{name}/close", CloseNotebookHandler).Methods("POST")
+	router.HandleFunc("/api/notebooks/{name}", DeleteNotebookHandler).Methods("DELETE")
+}
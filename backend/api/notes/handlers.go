@@ -2,18 +2,20 @@ package notes
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
-	"strings"
+	"time"
 
+	"github.com/sondrus/tetrad/metrics"
 	"github.com/sondrus/tetrad/services"
 )
 
 // GetNotesListHandler - GET - get notes list, sorted by `LEFT`
 func GetNotesListHandler(w http.ResponseWriter, r *http.Request) {
-	services.SetCommonResponseHeaders(w)
+	services.SetCommonResponseHeaders(w, r)
 
 	// Get notes list
-	notes, err := services.GetNotesList()
+	notes, err := services.GetNotesList(r.Context(), services.GetContextUser(r.Context()))
 	if err != nil {
 		services.RespondWithError(w, http.StatusInternalServerError, "Failed to fetch notes list", err)
 	}
@@ -31,10 +33,10 @@ func GetNotesListHandler(w http.ResponseWriter, r *http.Request) {
 
 // GetNotesTreeHandler - GET - get notes tree
 func GetNotesTreeHandler(w http.ResponseWriter, r *http.Request) {
-	services.SetCommonResponseHeaders(w)
+	services.SetCommonResponseHeaders(w, r)
 
 	// Get notes tree
-	notes, err := services.GetNotesTree()
+	notes, err := services.GetNotesTree(r.Context(), services.GetContextUser(r.Context()))
 	if err != nil {
 		services.RespondWithError(w, http.StatusInternalServerError, "Failed to fetch notes tree", err)
 		return
@@ -53,7 +55,7 @@ func GetNotesTreeHandler(w http.ResponseWriter, r *http.Request) {
 
 // SearchNotesHandler - GET - search notes by query
 func SearchNotesHandler(w http.ResponseWriter, r *http.Request) {
-	services.SetCommonResponseHeaders(w)
+	services.SetCommonResponseHeaders(w, r)
 
 	// Declare JSON POST structure
 	var req struct {
@@ -74,40 +76,18 @@ func SearchNotesHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// If `whole`, use full phrase 'as is', else use words from phrase
-	var words []string
-	if req.Whole {
-		words = []string{req.Query}
-	} else {
-		words = strings.Fields(req.Query)
-	}
-
-	// Prepare SQL WHERE
-	var whereConditions []string
-	var args []any
-	for _, word := range words {
-		if req.Title {
-			whereConditions = append(whereConditions, "custom_like(TITLE, ?)")
-			args = append(args, word)
-		} else {
-			whereConditions = append(whereConditions,
-				"("+
-					"custom_like(TITLE, ?) OR "+
-					"custom_like(CONTENTS, ?) OR "+
-					"(URL IS NOT NULL AND custom_like(URL, ?))"+
-					")",
-			)
-			args = append(args, word, word, word)
-		}
-	}
-	whereClause := strings.Join(whereConditions, " AND ")
-
 	// Get notes with filter
-	notes, err := services.GetNotes(services.NoteQueryOptions{
-		Where:        whereClause,
-		Args:         args,
-		OmitContents: true,
+	started := time.Now()
+	notes, err := services.SearchNotes(r.Context(), services.GetContextUser(r.Context()), services.SearchOptions{
+		Query: req.Query,
+		Title: req.Title,
+		Whole: req.Whole,
 	})
+	metrics.ObserveSearch(started)
+	if errors.Is(err, services.ErrEmptyQuery) {
+		services.RespondWithError(w, http.StatusBadRequest, "Missing 'query' parameter", nil)
+		return
+	}
 	if err != nil {
 		services.RespondWithError(w, http.StatusInternalServerError, "Failed to search notes", err)
 		return
@@ -126,7 +106,7 @@ func SearchNotesHandler(w http.ResponseWriter, r *http.Request) {
 
 // ExpandNotesHandler - expand/collapse notes
 func ExpandNotesHandler(w http.ResponseWriter, r *http.Request) {
-	services.SetCommonResponseHeaders(w)
+	services.SetCommonResponseHeaders(w, r)
 
 	// Decode into map
 	var fields map[string]any
@@ -154,8 +134,22 @@ func ExpandNotesHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// Keep just visible notes ([0] = all notes, just for full access)
+	user := services.GetContextUser(r.Context())
+	if !services.IsAdmin(user) {
+		var err error
+		if collapse, err = services.FilterVisibleNoteIDs(r.Context(), user, collapse); err != nil {
+			services.RespondWithError(w, http.StatusInternalServerError, "Failed to check permissions", err)
+			return
+		}
+		if expand, err = services.FilterVisibleNoteIDs(r.Context(), user, expand); err != nil {
+			services.RespondWithError(w, http.StatusInternalServerError, "Failed to check permissions", err)
+			return
+		}
+	}
+
 	// Execute
-	services.SetExpandCollapse(collapse, expand)
+	services.SetExpandCollapse(r.Context(), collapse, expand)
 
 	// Create response
 	response := map[string]any{
@@ -0,0 +1,261 @@
+package reminders
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/sondrus/tetrad/models"
+	"github.com/sondrus/tetrad/services"
+)
+
+// GetRemindersHandler - GET - get reminders of user, filters:
+// `status` (active, pending, fired, dismissed or all), `note` (reminders of single note)
+func GetRemindersHandler(w http.ResponseWriter, r *http.Request) {
+	services.SetCommonResponseHeaders(w, r)
+
+	filter := services.ReminderFilter{
+		Status: r.URL.Query().Get("status"),
+		User:   services.GetContextUser(r.Context()),
+	}
+	if value := r.URL.Query().Get("note"); value != "" {
+		id, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || id <= 0 {
+			services.RespondWithError(w, http.StatusBadRequest, "Wrong note ID", nil)
+			return
+		}
+		filter.NoteID = id
+	}
+
+	reminders, err := services.GetReminders(r.Context(), filter)
+	if err != nil {
+		services.RespondWithError(w, http.StatusBadRequest, "Failed to fetch reminders", err)
+		return
+	}
+
+	// Create response
+	response := map[string]any{
+		"success":   true,
+		"reminders": reminders,
+	}
+
+	// Send response
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// PostReminderHandler - POST - add reminder to note (`time` is unix timestamp)
+func PostReminderHandler(w http.ResponseWriter, r *http.Request) {
+	services.SetCommonResponseHeaders(w, r)
+
+	// Declare JSON structure
+	var req struct {
+		NoteID     int64  `json:"noteId"`
+		Time       int64  `json:"time"`
+		Recurrence string `json:"recurrence"`
+		Message    string `json:"message"`
+	}
+
+	// Decode input JSON
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		services.RespondWithError(w, http.StatusBadRequest, "Invalid JSON", err)
+		return
+	}
+	if req.Time <= 0 {
+		services.RespondWithError(w, http.StatusBadRequest, "Reminder time is required", nil)
+		return
+	}
+	if !services.IsValidRecurrence(req.Recurrence) {
+		services.RespondWithError(w, http.StatusBadRequest, "Unknown recurrence", nil)
+		return
+	}
+
+	// Reminders are personal, so viewer of note could add them
+	note, err := services.GetNote(r.Context(), int(req.NoteID))
+	if err != nil {
+		services.RespondWithError(w, http.StatusNotFound, "Note is not found", nil)
+		return
+	}
+	if !services.CheckNoteRole(w, r, note, services.RoleViewer) {
+		return
+	}
+
+	reminder, err := services.CreateReminder(r.Context(), services.GetContextUser(r.Context()), models.Reminder{
+		NoteID:     note.ID,
+		Message:    strings.TrimSpace(req.Message),
+		Recurrence: req.Recurrence,
+		DateStart:  req.Time,
+	})
+	if err != nil {
+		services.RespondWithError(w, http.StatusInternalServerError, "Failed to create reminder", err)
+		return
+	}
+
+	respondWithReminder(w, reminder)
+}
+
+// PatchReminderHandler - PATCH - change time, recurrence or message of reminder
+func PatchReminderHandler(w http.ResponseWriter, r *http.Request) {
+	services.SetCommonResponseHeaders(w, r)
+
+	// Declare JSON structure (missing fields are not changed)
+	var req struct {
+		Time       *int64  `json:"time"`
+		Recurrence *string `json:"recurrence"`
+		Message    *string `json:"message"`
+	}
+
+	// Decode input JSON
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		services.RespondWithError(w, http.StatusBadRequest, "Invalid JSON", err)
+		return
+	}
+
+	reminder, ok := getReminder(w, r)
+	if !ok {
+		return
+	}
+
+	if req.Time != nil {
+		if *req.Time <= 0 {
+			services.RespondWithError(w, http.StatusBadRequest, "Wrong reminder time", nil)
+			return
+		}
+		reminder.DateStart = *req.Time
+	}
+	if req.Recurrence != nil {
+		if !services.IsValidRecurrence(*req.Recurrence) {
+			services.RespondWithError(w, http.StatusBadRequest, "Unknown recurrence", nil)
+			return
+		}
+		reminder.Recurrence = *req.Recurrence
+	}
+	if req.Message != nil {
+		reminder.Message = strings.TrimSpace(*req.Message)
+	}
+
+	reminder, err := services.UpdateReminder(r.Context(), reminder)
+	if err != nil {
+		services.RespondWithError(w, http.StatusInternalServerError, "Failed to update reminder", err)
+		return
+	}
+
+	respondWithReminder(w, reminder)
+}
+
+// DeleteReminderHandler - DELETE - delete reminder
+func DeleteReminderHandler(w http.ResponseWriter, r *http.Request) {
+	services.SetCommonResponseHeaders(w, r)
+
+	reminder, ok := getReminder(w, r)
+	if !ok {
+		return
+	}
+
+	if err := services.DeleteReminder(r.Context(), reminder); err != nil {
+		services.RespondWithError(w, http.StatusInternalServerError, "Failed to delete reminder", err)
+		return
+	}
+
+	// Create response
+	response := map[string]any{
+		"success": true,
+	}
+
+	// Send response
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// SnoozeReminderHandler - POST - fire reminder again after `minutes` or at `until` (unix timestamp)
+func SnoozeReminderHandler(w http.ResponseWriter, r *http.Request) {
+	services.SetCommonResponseHeaders(w, r)
+
+	// Declare JSON structure
+	var req struct {
+		Minutes int64 `json:"minutes"`
+		Until   int64 `json:"until"`
+	}
+
+	// Decode input JSON
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		services.RespondWithError(w, http.StatusBadRequest, "Invalid JSON", err)
+		return
+	}
+
+	var until time.Time
+	switch {
+	case req.Until > 0:
+		until = time.Unix(req.Until, 0)
+	case req.Minutes > 0:
+		until = time.Now().Add(time.Duration(req.Minutes) * time.Minute)
+	default:
+		services.RespondWithError(w, http.StatusBadRequest, "Snooze time is required", nil)
+		return
+	}
+
+	reminder, ok := getReminder(w, r)
+	if !ok {
+		return
+	}
+
+	reminder, err := services.SnoozeReminder(r.Context(), reminder, until)
+	if err != nil {
+		services.RespondWithError(w, http.StatusInternalServerError, "Failed to snooze reminder", err)
+		return
+	}
+
+	respondWithReminder(w, reminder)
+}
+
+// DismissReminderHandler - POST - finish reminder (recurring reminder waits for the next occurrence)
+func DismissReminderHandler(w http.ResponseWriter, r *http.Request) {
+	services.SetCommonResponseHeaders(w, r)
+
+	reminder, ok := getReminder(w, r)
+	if !ok {
+		return
+	}
+
+	reminder, err := services.DismissReminder(r.Context(), reminder)
+	if err != nil {
+		services.RespondWithError(w, http.StatusInternalServerError, "Failed to dismiss reminder", err)
+		return
+	}
+
+	respondWithReminder(w, reminder)
+}
+
+// getReminder - get reminder of user from URL, respond with error if it is not found
+func getReminder(w http.ResponseWriter, r *http.Request) (models.Reminder, bool) {
+	id, _ := strconv.ParseInt(mux.Vars(r)["reminder"], 10, 64)
+
+	reminder, err := services.GetReminder(r.Context(), services.GetContextUser(r.Context()), id)
+	if errors.Is(err, services.ErrReminderNotFound) {
+		services.RespondWithError(w, http.StatusNotFound, "Reminder is not found", nil)
+		return reminder, false
+	}
+	if err != nil {
+		services.RespondWithError(w, http.StatusInternalServerError, "Failed to fetch reminder", err)
+		return reminder, false
+	}
+
+	return reminder, true
+}
+
+// respondWithReminder - send reminder
+func respondWithReminder(w http.ResponseWriter, reminder models.Reminder) {
+	// Create response
+	response := map[string]any{
+		"success":  true,
+		"reminder": reminder,
+	}
+
+	// Send response
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,15 @@
+package reminders
+
+import (
+	"github.com/gorilla/mux"
+)
+
+// RegisterRoutes - register all routes for reminders of notes
+func RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/api/reminders", GetRemindersHandler).Methods("GET")
+	router.HandleFunc("/api/reminders", PostReminderHandler).Methods("POST")
+	router.HandleFunc("/api/reminders/{reminder:[0-9]+}", PatchReminderHandler).Methods("PATCH")
+	router.HandleFunc("/api/reminders/{reminder:[0-9]+}", DeleteReminderHandler).Methods("DELETE")
+	router.HandleFunc("/api/reminders/{reminder:[0-9]+}/snooze", SnoozeReminderHandler).Methods("POST")
+	router.HandleFunc("/api/reminders/{reminder:[0-9]+}/dismiss", DismissReminderHandler).Methods("POST")
+}
@@ -0,0 +1,47 @@
+package resource
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/sondrus/tetrad/database"
+	"github.com/sondrus/tetrad/services"
+)
+
+// GetResourceHandler - GET - download resource (attached file) by ID
+func GetResourceHandler(w http.ResponseWriter, r *http.Request) {
+	services.SetCommonResponseHeaders(w, r)
+
+	// Get resource from database
+	ID := r.Context().Value(database.IDKey).(int)
+	resource, err := services.GetResource(r.Context(), ID)
+	if err != nil {
+		services.RespondWithError(w, http.StatusNotFound, "Resource is not found", nil)
+		return
+	}
+
+	// Check user can view note with resource
+	note, err := services.GetNote(r.Context(), int(resource.NoteID))
+	if err != nil {
+		services.RespondWithError(w, http.StatusNotFound, "Resource is not found", nil)
+		return
+	}
+	if !services.CheckNoteRole(w, r, note, services.RoleViewer) {
+		return
+	}
+
+	// Images are shown inline, other files are downloaded
+	disposition := "attachment"
+	if strings.HasPrefix(resource.Mime, "image/") && resource.Mime != "image/svg+xml" {
+		disposition = "inline"
+	}
+
+	// Send response
+	w.Header().Set("Content-Type", resource.Mime)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": resource.Name}))
+	w.Header().Set("Content-Length", fmt.Sprintf("%d", len(resource.Data)))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Write(resource.Data)
+}
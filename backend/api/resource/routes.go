@@ -0,0 +1,10 @@
+package resource
+
+import (
+	"github.com/gorilla/mux"
+)
+
+// RegisterRoutes - registers all routes for working with resources (attached files)
+func RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/api/resource/{id:[0-9]+}", GetResourceHandler).Methods("GET")
+}
@@ -10,10 +10,10 @@ import (
 
 // LoadSettingsHandler - GET - load frontend settings
 func LoadSettingsHandler(w http.ResponseWriter, r *http.Request) {
-	services.SetCommonResponseHeaders(w)
+	services.SetCommonResponseHeaders(w, r)
 
 	// Load settings from DB and transform to settings tree
-	settings, err := services.LoadSettings()
+	settings, err := services.LoadSettings(r.Context(), services.GetContextUser(r.Context()))
 	if err != nil {
 		services.RespondWithError(w, http.StatusInternalServerError, fmt.Sprintf("Error loading settings: %v", err), nil)
 		return
@@ -33,7 +33,7 @@ func LoadSettingsHandler(w http.ResponseWriter, r *http.Request) {
 
 // SaveSettingsHandler - PUT - save frontend settings
 func SaveSettingsHandler(w http.ResponseWriter, r *http.Request) {
-	services.SetCommonResponseHeaders(w)
+	services.SetCommonResponseHeaders(w, r)
 
 	// Decode request json into map
 	var settings map[string]any
@@ -44,7 +44,17 @@ func SaveSettingsHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Save settings to DB
-	services.SaveSettings(settings)
+	changed, err := services.SaveSettings(r.Context(), services.GetContextUser(r.Context()), settings)
+	if err != nil {
+		services.RespondWithError(w, http.StatusInternalServerError, "Failed to save settings", err)
+		return
+	}
+	if len(changed) > 0 {
+		services.Audit(r, services.AuditEntry{
+			Operation: services.AuditSettings,
+			Fields:    changed,
+		})
+	}
 
 	// Create response
 	response := map[string]any{
@@ -0,0 +1,196 @@
+package shares
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/sondrus/tetrad/auth"
+	"github.com/sondrus/tetrad/database"
+	"github.com/sondrus/tetrad/models"
+	"github.com/sondrus/tetrad/services"
+)
+
+// GetSharesHandler - GET - get share links of current user (admins get all links), optional `note` filter
+func GetSharesHandler(w http.ResponseWriter, r *http.Request) {
+	services.SetCommonResponseHeaders(w, r)
+
+	var noteID int64
+	if value := r.URL.Query().Get("note"); value != "" {
+		id, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || id <= 0 {
+			services.RespondWithError(w, http.StatusBadRequest, "Wrong note ID", nil)
+			return
+		}
+		noteID = id
+	}
+
+	shares, err := services.GetShares(r.Context(), services.GetContextUser(r.Context()), noteID)
+	if err != nil {
+		services.RespondWithError(w, http.StatusInternalServerError, "Failed to fetch share links", err)
+		return
+	}
+
+	// Create response
+	response := map[string]any{
+		"success": true,
+		"shares":  shares,
+	}
+
+	// Send response
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// PostShareHandler - POST - create share link of note or subtree, the link itself is returned just once
+func PostShareHandler(w http.ResponseWriter, r *http.Request) {
+	services.SetCommonResponseHeaders(w, r)
+
+	// Without authentication every API route is open, so link could not limit its visitors
+	if !auth.IsEnabled() {
+		services.RespondWithError(w, http.StatusConflict, "Share links require authentication", nil)
+		return
+	}
+
+	// Declare JSON POST structure
+	var req struct {
+		NoteID        int64  `json:"noteId"`
+		Subtree       bool   `json:"subtree"`
+		Password      string `json:"password"`
+		ExpiresInDays int    `json:"expiresInDays"`
+	}
+
+	// Decode input JSON
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		services.RespondWithError(w, http.StatusBadRequest, "Invalid JSON", err)
+		return
+	}
+
+	// Expiry date, 0 = never
+	var expires int64
+	if req.ExpiresInDays < 0 {
+		services.RespondWithError(w, http.StatusBadRequest, "Invalid expiry", nil)
+		return
+	}
+	if req.ExpiresInDays > 0 {
+		expires = time.Now().AddDate(0, 0, req.ExpiresInDays).Unix()
+	}
+
+	// Publishing note is like granting access to it
+	note, err := services.GetNote(r.Context(), int(req.NoteID))
+	if err != nil {
+		services.RespondWithError(w, http.StatusNotFound, "Note is not found", nil)
+		return
+	}
+	if !services.CheckNoteRole(w, r, note, services.RoleOwner) {
+		return
+	}
+	if note.Encrypted {
+		services.RespondWithError(w, http.StatusBadRequest, "Encrypted notes could not be shared", nil)
+		return
+	}
+
+	// Generate token and hash password
+	value, hash, err := auth.GenerateShareToken()
+	if err != nil {
+		services.RespondWithError(w, http.StatusInternalServerError, "Failed to generate share link", err)
+		return
+	}
+	var password string
+	if req.Password != "" {
+		password, err = auth.HashPassword(req.Password)
+		if err != nil {
+			services.RespondWithError(w, http.StatusInternalServerError, "Failed to hash password", err)
+			return
+		}
+	}
+
+	var userID int64
+	if user := services.GetContextUser(r.Context()); user != nil {
+		userID = user.ID
+	}
+
+	// Save share link
+	share, err := services.CreateShare(r.Context(), models.Share{
+		NoteID:      note.ID,
+		UserID:      userID,
+		Hash:        hash,
+		Prefix:      value[:6],
+		Subtree:     req.Subtree,
+		Password:    password,
+		DateExpires: expires,
+	})
+	if err != nil {
+		services.RespondWithError(w, http.StatusInternalServerError, "Failed to create share link", err)
+		return
+	}
+	services.Audit(r, services.AuditEntry{
+		Operation: services.AuditShareCreate,
+		NoteIDs:   []int64{note.ID},
+		Details:   fmt.Sprintf("share %d, subtree %t", share.ID, share.Subtree),
+	})
+
+	// Full URL as the request came (frontend could build own one from path)
+	path := services.SharePath(r.Context(), value)
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+
+	// Create response
+	response := map[string]any{
+		"success": true,
+		"share":   share,
+		"path":    path,
+		"url":     scheme + "://" + r.Host + path,
+	}
+
+	// Send response
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// DeleteShareHandler - DELETE - revoke share link (by its creator or admin)
+func DeleteShareHandler(w http.ResponseWriter, r *http.Request) {
+	services.SetCommonResponseHeaders(w, r)
+
+	ID := int64(r.Context().Value(database.IDKey).(int))
+	share, err := services.GetShare(r.Context(), ID)
+	if err != nil {
+		services.RespondWithError(w, http.StatusNotFound, "Share link is not found", nil)
+		return
+	}
+
+	user := services.GetContextUser(r.Context())
+	if !services.IsAdmin(user) && share.UserID != user.ID {
+		services.RespondWithError(w, http.StatusNotFound, "Share link is not found", nil)
+		return
+	}
+
+	err = services.RevokeShare(r.Context(), share.ID)
+	if errors.Is(err, services.ErrShareNotFound) {
+		services.RespondWithError(w, http.StatusNotFound, "Share link is not found", nil)
+		return
+	}
+	if err != nil {
+		services.RespondWithError(w, http.StatusInternalServerError, "Failed to revoke share link", err)
+		return
+	}
+	services.Audit(r, services.AuditEntry{
+		Operation: services.AuditShareRevoke,
+		NoteIDs:   []int64{share.NoteID},
+		Details:   fmt.Sprintf("share %d", share.ID),
+	})
+
+	// Create response
+	response := map[string]any{
+		"success": true,
+	}
+
+	// Send response
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
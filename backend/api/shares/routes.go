@@ -0,0 +1,12 @@
+package shares
+
+import (
+	"github.com/gorilla/mux"
+)
+
+// RegisterRoutes - register all routes for public share links
+func RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/api/shares", GetSharesHandler).Methods("GET")
+	router.HandleFunc("/api/shares", PostShareHandler).Methods("POST")
+	router.HandleFunc("/api/shares/{id:[0-9]+}", DeleteShareHandler).Methods("DELETE")
+}
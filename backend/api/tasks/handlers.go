@@ -0,0 +1,127 @@
+package tasks
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/sondrus/tetrad/services"
+)
+
+// GetTasksHandler - GET - get tasks of notes, filters:
+// `status` (open, done or all), `dueFrom` and `dueTo` (YYYY-MM-DD), `note` (tasks of note and its children)
+func GetTasksHandler(w http.ResponseWriter, r *http.Request) {
+	services.SetCommonResponseHeaders(w, r)
+
+	query := r.URL.Query()
+	filter := services.TaskFilter{
+		Status:  query.Get("status"),
+		DueFrom: query.Get("dueFrom"),
+		DueTo:   query.Get("dueTo"),
+		User:    services.GetContextUser(r.Context()),
+	}
+
+	// Check dates
+	for _, date := range []string{filter.DueFrom, filter.DueTo} {
+		if _, err := time.Parse(services.JournalDateLayout, date); date != "" && err != nil {
+			services.RespondWithError(w, http.StatusBadRequest, "Wrong due date", err)
+			return
+		}
+	}
+
+	// Subtree
+	if value := query.Get("note"); value != "" {
+		id, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || id <= 0 {
+			services.RespondWithError(w, http.StatusBadRequest, "Wrong note ID", nil)
+			return
+		}
+		filter.NoteID = id
+	}
+
+	tasks, err := services.GetTasks(r.Context(), filter)
+	if err != nil {
+		services.RespondWithError(w, http.StatusBadRequest, "Failed to fetch tasks", err)
+		return
+	}
+
+	// Create response
+	response := map[string]any{
+		"success": true,
+		"tasks":   tasks,
+	}
+
+	// Send response
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// ToggleTaskHandler - POST - check or uncheck task, optional `done` sets state (default: switch)
+func ToggleTaskHandler(w http.ResponseWriter, r *http.Request) {
+	services.SetCommonResponseHeaders(w, r)
+
+	// Declare JSON structure (empty body is allowed)
+	var req struct {
+		Done *bool `json:"done"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			services.RespondWithError(w, http.StatusBadRequest, "Invalid JSON", err)
+			return
+		}
+	}
+
+	// Get task and its note
+	id, _ := strconv.ParseInt(mux.Vars(r)["task"], 10, 64)
+	task, err := services.GetTask(r.Context(), id)
+	if err != nil {
+		services.RespondWithError(w, http.StatusNotFound, "Task is not found", nil)
+		return
+	}
+	note, err := services.GetNote(r.Context(), int(task.NoteID))
+	if err != nil {
+		services.RespondWithError(w, http.StatusNotFound, "Note is not found", nil)
+		return
+	}
+
+	// Check user can edit note
+	if !services.CheckNoteRole(w, r, note, services.RoleEditor) {
+		return
+	}
+
+	done := !task.Done
+	if req.Done != nil {
+		done = *req.Done
+	}
+
+	updated, err := services.ToggleTask(r.Context(), services.GetContextKeyring(r.Context()), task, done)
+	if errors.Is(err, services.ErrTaskChanged) {
+		services.RespondWithError(w, http.StatusConflict, "Task is changed, reload the list", nil)
+		return
+	}
+	if err != nil {
+		services.RespondWithError(w, http.StatusInternalServerError, "Failed to update task", err)
+		return
+	}
+
+	services.Audit(r, services.AuditEntry{
+		Operation: services.AuditNoteUpdate,
+		NoteIDs:   []int64{note.ID},
+		Fields:    []string{"CONTENTS"},
+		Details:   fmt.Sprintf("task line %d: done %t", task.Line, done),
+	})
+
+	// Create response
+	response := map[string]any{
+		"success": true,
+		"task":    updated,
+	}
+
+	// Send response
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
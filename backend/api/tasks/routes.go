@@ -0,0 +1,11 @@
+package tasks
+
+import (
+	"github.com/gorilla/mux"
+)
+
+// RegisterRoutes - register all routes for tasks (checkbox items of markdown notes)
+func RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/api/tasks", GetTasksHandler).Methods("GET")
+	router.HandleFunc("/api/tasks/{task:[0-9]+}/toggle", ToggleTaskHandler).Methods("POST")
+}
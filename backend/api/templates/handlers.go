@@ -0,0 +1,158 @@
+package templates
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/sondrus/tetrad/database"
+	"github.com/sondrus/tetrad/models"
+	"github.com/sondrus/tetrad/services"
+)
+
+// GetTemplatesHandler - GET - get notes marked as templates
+func GetTemplatesHandler(w http.ResponseWriter, r *http.Request) {
+	services.SetCommonResponseHeaders(w, r)
+
+	templates, err := services.GetTemplates(r.Context(), services.GetContextUser(r.Context()))
+	if err != nil {
+		services.RespondWithError(w, http.StatusInternalServerError, "Failed to fetch templates", err)
+		return
+	}
+
+	// Create response
+	response := map[string]any{
+		"success":   true,
+		"templates": templates,
+	}
+
+	// Send response
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetTemplateHandler - GET - get template with its child notes and prompts to ask before creation
+func GetTemplateHandler(w http.ResponseWriter, r *http.Request) {
+	services.SetCommonResponseHeaders(w, r)
+
+	ID := r.Context().Value(database.IDKey).(int)
+	template, ok := getTemplate(w, r, ID)
+	if !ok {
+		return
+	}
+
+	subtree, err := services.GetTemplateSubtree(r.Context(), services.GetContextKeyring(r.Context()), template)
+	if errors.Is(err, services.ErrLocked) {
+		services.RespondWithError(w, http.StatusLocked, "Template is encrypted and locked", nil)
+		return
+	}
+	if err != nil {
+		services.RespondWithError(w, http.StatusInternalServerError, "Failed to fetch template", err)
+		return
+	}
+
+	// Create response
+	response := map[string]any{
+		"success": true,
+		"notes":   subtree,
+		"prompts": services.GetTemplatePrompts(subtree),
+	}
+
+	// Send response
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// CreateFromTemplateHandler - POST - create note (with child notes) from template
+func CreateFromTemplateHandler(w http.ResponseWriter, r *http.Request) {
+	services.SetCommonResponseHeaders(w, r)
+
+	// Declare JSON structure
+	var req struct {
+		ParentID int64             `json:"parentId"`
+		Title    string            `json:"title"`
+		Time     int64             `json:"time"`
+		Prompts  map[string]string `json:"prompts"`
+	}
+
+	// Decode input JSON
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		services.RespondWithError(w, http.StatusBadRequest, "Invalid JSON", err)
+		return
+	}
+
+	// ID is not put to context for POST requests
+	ID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil || ID <= 0 {
+		services.RespondWithError(w, http.StatusBadRequest, "Wrong note ID in query", nil)
+		return
+	}
+	template, ok := getTemplate(w, r, ID)
+	if !ok {
+		return
+	}
+
+	// Placeholders values, {{date}} and {{time}} are current by default
+	values := services.TemplateValues{
+		Title:   req.Title,
+		Prompts: req.Prompts,
+	}
+	if req.Time > 0 {
+		values.Time = time.Unix(req.Time, 0)
+	}
+
+	// Create notes
+	ids, err := services.CreateFromTemplate(r.Context(), services.GetContextUser(r.Context()),
+		services.GetContextKeyring(r.Context()), template, req.ParentID, values)
+	if len(ids) > 0 {
+		services.Audit(r, services.AuditEntry{
+			Operation: services.AuditNoteCreate,
+			NoteIDs:   ids,
+			Details:   fmt.Sprintf("parent %d, template %d", req.ParentID, template.ID),
+		})
+	}
+	switch {
+	case errors.Is(err, services.ErrAccessDenied):
+		services.RespondWithError(w, http.StatusForbidden, "Access denied", nil)
+		return
+	case errors.Is(err, services.ErrParentNotFound):
+		services.RespondWithError(w, http.StatusNotFound, "Parent note is not found", nil)
+		return
+	case errors.Is(err, services.ErrLocked):
+		services.RespondWithError(w, http.StatusLocked, "Note is encrypted and locked", nil)
+		return
+	case err != nil:
+		services.RespondWithError(w, http.StatusInternalServerError, "Failed to create note from template", err)
+		return
+	}
+
+	// Create response
+	response := map[string]any{
+		"success": true,
+		"id":      ids[0],
+		"ids":     ids,
+	}
+
+	// Send response
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// getTemplate - get template note visible for user, respond with error if it is not found
+func getTemplate(w http.ResponseWriter, r *http.Request, id int) (models.NoteDB, bool) {
+	template, err := services.GetNote(r.Context(), id)
+	if err != nil || !template.Template {
+		services.RespondWithError(w, http.StatusNotFound, "Template is not found", nil)
+		return models.NoteDB{}, false
+	}
+
+	if !services.CheckNoteRole(w, r, template, services.RoleViewer) {
+		return models.NoteDB{}, false
+	}
+
+	return template, true
+}
@@ -0,0 +1,12 @@
+package templates
+
+import (
+	"github.com/gorilla/mux"
+)
+
+// RegisterRoutes - register all routes for note templates
+func RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/api/templates", GetTemplatesHandler).Methods("GET")
+	router.HandleFunc("/api/templates/{id:[0-9]+}", GetTemplateHandler).Methods("GET")
+	router.HandleFunc("/api/templates/{id:[0-9]+}/create", CreateFromTemplateHandler).Methods("POST")
+}
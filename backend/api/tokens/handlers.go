@@ -0,0 +1,155 @@
+package tokens
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/sondrus/tetrad/auth"
+	"github.com/sondrus/tetrad/database"
+	"github.com/sondrus/tetrad/models"
+	"github.com/sondrus/tetrad/services"
+)
+
+// sessionUser - get user logged in by session, respond with error otherwise
+// Tokens could not be managed by tokens (a leaked token must not create new ones)
+func sessionUser(w http.ResponseWriter, r *http.Request) *models.User {
+	if services.GetContextToken(r.Context()) != nil {
+		services.RespondWithError(w, http.StatusForbidden, "API tokens could be managed just after login", nil)
+		return nil
+	}
+
+	user := services.GetContextUser(r.Context())
+	if user == nil {
+		services.RespondWithError(w, http.StatusBadRequest, "Authentication is disabled, set password first", nil)
+		return nil
+	}
+
+	return user
+}
+
+// GetTokensHandler - GET - get all API tokens of current user
+func GetTokensHandler(w http.ResponseWriter, r *http.Request) {
+	services.SetCommonResponseHeaders(w, r)
+
+	user := sessionUser(w, r)
+	if user == nil {
+		return
+	}
+
+	// Get tokens
+	tokens, err := services.GetTokens(user.ID)
+	if err != nil {
+		services.RespondWithError(w, http.StatusInternalServerError, "Failed to fetch tokens", err)
+		return
+	}
+
+	// Create response
+	response := map[string]any{
+		"success": true,
+		"tokens":  tokens,
+	}
+
+	// Send response
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// PostTokenHandler - POST - create API token, the token itself is returned just once
+func PostTokenHandler(w http.ResponseWriter, r *http.Request) {
+	services.SetCommonResponseHeaders(w, r)
+
+	user := sessionUser(w, r)
+	if user == nil {
+		return
+	}
+
+	// Declare JSON POST structure
+	var req struct {
+		Name          string `json:"name"`
+		Scope         string `json:"scope"`
+		ExpiresInDays int    `json:"expiresInDays"`
+	}
+
+	// Decode input JSON
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		services.RespondWithError(w, http.StatusBadRequest, "Invalid JSON", err)
+		return
+	}
+
+	// Default scope is read-only
+	if req.Scope == "" {
+		req.Scope = services.ScopeRead
+	}
+
+	// Expiry date, 0 = never
+	var expires int64
+	if req.ExpiresInDays < 0 {
+		services.RespondWithError(w, http.StatusBadRequest, "Invalid expiry", nil)
+		return
+	}
+	if req.ExpiresInDays > 0 {
+		expires = time.Now().AddDate(0, 0, req.ExpiresInDays).Unix()
+	}
+
+	// Generate token
+	value, hash, err := auth.GenerateToken()
+	if err != nil {
+		services.RespondWithError(w, http.StatusInternalServerError, "Failed to generate token", err)
+		return
+	}
+	prefix := value[:len(auth.TokenPrefix)+6]
+
+	// Save token
+	token, err := services.CreateToken(user.ID, req.Name, hash, prefix, req.Scope, expires)
+	if err != nil {
+		services.RespondWithError(w, http.StatusBadRequest, "Failed to create token", err)
+		return
+	}
+	services.Audit(r, services.AuditEntry{
+		Operation: services.AuditTokenCreate,
+		Details:   fmt.Sprintf("token %d %q scope %s", token.ID, token.Name, token.Scope),
+	})
+
+	// Create response
+	response := map[string]any{
+		"success": true,
+		"token":   token,
+		"value":   value,
+	}
+
+	// Send response
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// DeleteTokenHandler - DELETE - revoke API token of current user
+func DeleteTokenHandler(w http.ResponseWriter, r *http.Request) {
+	services.SetCommonResponseHeaders(w, r)
+
+	user := sessionUser(w, r)
+	if user == nil {
+		return
+	}
+
+	// Delete token
+	ID := int64(r.Context().Value(database.IDKey).(int))
+	if err := services.DeleteToken(user.ID, ID); err != nil {
+		services.RespondWithError(w, http.StatusNotFound, "Token is not found", nil)
+		return
+	}
+	services.Audit(r, services.AuditEntry{
+		Operation: services.AuditTokenDelete,
+		Details:   fmt.Sprintf("token %d", ID),
+	})
+
+	// Create response
+	response := map[string]any{
+		"success": true,
+	}
+
+	// Send response
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,12 @@
+package tokens
+
+import (
+	"github.com/gorilla/mux"
+)
+
+// RegisterRoutes - register all routes for personal API tokens
+func RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/api/tokens", GetTokensHandler).Methods("GET")
+	router.HandleFunc("/api/tokens", PostTokenHandler).Methods("POST")
+	router.HandleFunc("/api/tokens/{id:[0-9]+}", DeleteTokenHandler).Methods("DELETE")
+}
@@ -0,0 +1,226 @@
+package users
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/sondrus/tetrad/auth"
+	"github.com/sondrus/tetrad/database"
+	"github.com/sondrus/tetrad/services"
+)
+
+// GetUsersHandler - GET - get all user accounts (admin only)
+func GetUsersHandler(w http.ResponseWriter, r *http.Request) {
+	services.SetCommonResponseHeaders(w, r)
+
+	if !services.CheckAdmin(w, r) {
+		return
+	}
+
+	// Get users
+	users, err := services.GetUsers()
+	if err != nil {
+		services.RespondWithError(w, http.StatusInternalServerError, "Failed to fetch users", err)
+		return
+	}
+
+	// Create response
+	response := map[string]any{
+		"success": true,
+		"users":   users,
+	}
+
+	// Send response
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// PostUserHandler - POST - create user account (admin only)
+func PostUserHandler(w http.ResponseWriter, r *http.Request) {
+	services.SetCommonResponseHeaders(w, r)
+
+	if !services.CheckAdmin(w, r) {
+		return
+	}
+
+	// Declare JSON POST structure
+	var req struct {
+		Name     string `json:"name"`
+		Password string `json:"password"`
+		Admin    bool   `json:"admin"`
+	}
+
+	// Decode input JSON
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		services.RespondWithError(w, http.StatusBadRequest, "Invalid JSON", err)
+		return
+	}
+
+	// Hash password
+	hash, err := auth.HashPassword(req.Password)
+	if err != nil {
+		services.RespondWithError(w, http.StatusBadRequest, "Invalid password", err)
+		return
+	}
+
+	// The first user is always admin (otherwise nobody could manage users)
+	admin := req.Admin || services.CountUsers() == 0
+
+	// Create user
+	user, err := services.CreateUser(req.Name, hash, admin)
+	if err != nil {
+		services.RespondWithError(w, http.StatusBadRequest, "Failed to create user", err)
+		return
+	}
+	services.Audit(r, services.AuditEntry{
+		Operation: services.AuditUserCreate,
+		Details:   fmt.Sprintf("user %d %q admin=%t", user.ID, user.Name, user.Admin),
+	})
+
+	// Create response
+	response := map[string]any{
+		"success": true,
+		"user":    user,
+	}
+
+	// Send response
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// PatchUserHandler - PATCH - update user (admin, or user itself for password)
+func PatchUserHandler(w http.ResponseWriter, r *http.Request) {
+	services.SetCommonResponseHeaders(w, r)
+
+	// Declare JSON structure
+	var req struct {
+		Name     *string `json:"name"`
+		Password *string `json:"password"`
+		Admin    *bool   `json:"admin"`
+	}
+
+	// Decode input JSON
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		services.RespondWithError(w, http.StatusBadRequest, "Invalid JSON", err)
+		return
+	}
+
+	// Get user from database
+	ID := int64(r.Context().Value(database.IDKey).(int))
+	user, err := services.GetUser(ID)
+	if err != nil {
+		services.RespondWithError(w, http.StatusNotFound, "User is not found", nil)
+		return
+	}
+
+	// Users could change just own password
+	current := services.GetContextUser(r.Context())
+	self := current != nil && current.ID == user.ID
+	if !services.IsAdmin(current) && !(self && req.Name == nil && req.Admin == nil) {
+		services.RespondWithError(w, http.StatusForbidden, "Access denied", nil)
+		return
+	}
+
+	// Prepare fields
+	fields := map[string]any{}
+	if req.Name != nil {
+		fields["NAME"] = *req.Name
+	}
+	if req.Admin != nil {
+		// Admin could not remove own admin rights
+		if self && !*req.Admin {
+			services.RespondWithError(w, http.StatusBadRequest, "Could not remove own admin rights", nil)
+			return
+		}
+		fields["ADMIN"] = *req.Admin
+	}
+	if req.Password != nil {
+		hash, err := auth.HashPassword(*req.Password)
+		if err != nil {
+			services.RespondWithError(w, http.StatusBadRequest, "Invalid password", err)
+			return
+		}
+		fields["PASSWORD"] = hash
+	}
+
+	// Update user
+	if len(fields) > 0 {
+		names := make([]string, 0, len(fields))
+		for name := range fields {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		if err := services.UpdateUser(user.ID, fields); err != nil {
+			services.RespondWithError(w, http.StatusBadRequest, "Failed to update user", err)
+			return
+		}
+		services.Audit(r, services.AuditEntry{
+			Operation: services.AuditUserUpdate,
+			Fields:    names,
+			Details:   fmt.Sprintf("user %d %q", user.ID, user.Name),
+		})
+	}
+
+	// Password changed => logout other sessions
+	if req.Password != nil {
+		auth.DeleteUserSessions(user.ID)
+		if self {
+			auth.CreateSession(w, r, user)
+		}
+	}
+
+	// Create response
+	response := map[string]any{
+		"success": true,
+	}
+
+	// Send response
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// DeleteUserHandler - DELETE - delete user with permissions and settings (admin only)
+func DeleteUserHandler(w http.ResponseWriter, r *http.Request) {
+	services.SetCommonResponseHeaders(w, r)
+
+	if !services.CheckAdmin(w, r) {
+		return
+	}
+
+	// Get user from database
+	ID := int64(r.Context().Value(database.IDKey).(int))
+	user, err := services.GetUser(ID)
+	if err != nil {
+		services.RespondWithError(w, http.StatusNotFound, "User is not found", nil)
+		return
+	}
+
+	// Admin could not delete itself
+	if current := services.GetContextUser(r.Context()); current != nil && current.ID == user.ID {
+		services.RespondWithError(w, http.StatusBadRequest, "Could not delete own account", nil)
+		return
+	}
+
+	// Delete
+	auth.DeleteUserSessions(user.ID)
+	if err := services.DeleteUser(user.ID); err != nil {
+		services.RespondWithError(w, http.StatusInternalServerError, "Failed to delete user", err)
+		return
+	}
+	services.Audit(r, services.AuditEntry{
+		Operation: services.AuditUserDelete,
+		Details:   fmt.Sprintf("user %d %q", user.ID, user.Name),
+	})
+
+	// Create response
+	response := map[string]any{
+		"success": true,
+	}
+
+	// Send response
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,13 @@
+package users
+
+import (
+	"github.com/gorilla/mux"
+)
+
+// RegisterRoutes - registers all routes for user accounts management
+func RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/api/users", GetUsersHandler).Methods("GET")
+	router.HandleFunc("/api/users", PostUserHandler).Methods("POST")
+	router.HandleFunc("/api/users/{id:[0-9]+}", PatchUserHandler).Methods("PATCH")
+	router.HandleFunc("/api/users/{id:[0-9]+}", DeleteUserHandler).Methods("DELETE")
+}
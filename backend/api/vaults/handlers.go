@@ -0,0 +1,146 @@
+package vaults
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+
+	"github.com/sondrus/tetrad/auth"
+	"github.com/sondrus/tetrad/services"
+)
+
+// vaultInfo - encrypted subtree with state for current session
+type vaultInfo struct {
+	ID       int64  `json:"id"`
+	NoteID   int64  `json:"noteId"`
+	Title    string `json:"title"`
+	Unlocked bool   `json:"unlocked"`
+}
+
+// GetVaultsHandler - GET - get encrypted subtrees visible for user
+func GetVaultsHandler(w http.ResponseWriter, r *http.Request) {
+	services.SetCommonResponseHeaders(w, r)
+
+	// Get vaults
+	vaults, err := services.GetVaults(r.Context())
+	if err != nil {
+		services.RespondWithError(w, http.StatusInternalServerError, "Failed to fetch encrypted notes", err)
+		return
+	}
+
+	user := services.GetContextUser(r.Context())
+	unlocked := services.GetContextKeyring(r.Context()).Unlocked()
+
+	// Keep vaults with visible root notes
+	result := []vaultInfo{}
+	for _, vault := range vaults {
+		note, err := services.GetNote(r.Context(), int(vault.NoteID))
+		if err != nil || !services.HasNoteRole(r.Context(), user, note, services.RoleViewer) {
+			continue
+		}
+		result = append(result, vaultInfo{
+			ID:       vault.ID,
+			NoteID:   vault.NoteID,
+			Title:    note.Title,
+			Unlocked: slices.Contains(unlocked, vault.ID),
+		})
+	}
+
+	// Create response
+	response := map[string]any{
+		"success": true,
+		"vaults":  result,
+	}
+
+	// Send response
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// UnlockHandler - POST - unlock encrypted subtree for current session
+func UnlockHandler(w http.ResponseWriter, r *http.Request) {
+	services.SetCommonResponseHeaders(w, r)
+
+	// Declare JSON POST structure
+	var req struct {
+		NoteID     int64  `json:"noteId"`
+		Passphrase string `json:"passphrase"`
+	}
+
+	// Decode input JSON
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		services.RespondWithError(w, http.StatusBadRequest, "Invalid JSON", err)
+		return
+	}
+
+	// Get note from database
+	note, err := services.GetNote(r.Context(), int(req.NoteID))
+	if err != nil {
+		services.RespondWithError(w, http.StatusNotFound, "Note is not found", nil)
+		return
+	}
+
+	// Check user can view note
+	if !services.CheckNoteRole(w, r, note, services.RoleViewer) {
+		return
+	}
+
+	// Get vault of note (note may be any note in encrypted subtree)
+	vault, err := services.GetVaultByNote(r.Context(), note)
+	if err != nil || vault == nil {
+		services.RespondWithError(w, http.StatusBadRequest, "Note is not encrypted", nil)
+		return
+	}
+
+	// Wrong passphrases are limited like logins
+	if allowed, wait := auth.LoginAllowed(r); !allowed {
+		seconds := int(math.Ceil(wait.Seconds()))
+		w.Header().Set("Retry-After", fmt.Sprintf("%d", seconds))
+		services.RespondWithError(w, http.StatusTooManyRequests,
+			fmt.Sprintf("Too many failed attempts, try again in %d seconds", seconds), nil)
+		return
+	}
+
+	// Check passphrase
+	key, err := services.UnlockVault(vault, req.Passphrase)
+	if err != nil {
+		auth.LoginFailed(r)
+		services.RespondWithError(w, http.StatusForbidden, "Wrong passphrase", nil)
+		return
+	}
+	auth.LoginSucceeded(r)
+
+	// Save key for session
+	if err := auth.UnlockKey(w, r, services.GetContextUser(r.Context()), vault.ID, key); err != nil {
+		services.RespondWithError(w, http.StatusInternalServerError, "Failed to unlock note", err)
+		return
+	}
+
+	// Create response
+	response := map[string]any{
+		"success": true,
+		"vaultId": vault.ID,
+	}
+
+	// Send response
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// LockHandler - POST - lock all encrypted subtrees for current session
+func LockHandler(w http.ResponseWriter, r *http.Request) {
+	services.SetCommonResponseHeaders(w, r)
+
+	auth.LockAll(w, r)
+
+	// Create response
+	response := map[string]any{
+		"success": true,
+	}
+
+	// Send response
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,12 @@
+package vaults
+
+import (
+	"github.com/gorilla/mux"
+)
+
+// RegisterRoutes - register all routes for unlock encrypted notes
+func RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/api/vaults", GetVaultsHandler).Methods("GET")
+	router.HandleFunc("/api/vaults/unlock", UnlockHandler).Methods("POST")
+	router.HandleFunc("/api/vaults/lock", LockHandler).Methods("POST")
+}
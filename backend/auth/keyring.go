@@ -0,0 +1,155 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/sondrus/tetrad/database"
+	"github.com/sondrus/tetrad/models"
+	"github.com/sondrus/tetrad/services"
+)
+
+// KeyringCookie - name of cookie with ID of unlocked keys (keys themselves stay in memory)
+const KeyringCookie = "tetrad_keyring"
+
+// KeyringTTL - unlocked keys are forgotten after this idle time
+const KeyringTTL = 30 * time.Minute
+
+// keyringEntry - unlocked keys of single client, vault IDs are unique just inside notebook
+type keyringEntry struct {
+	UserID   int64
+	Keyrings map[string]*services.Keyring // notebook => keys
+	Expires  time.Time
+}
+
+var (
+	keyrings      = make(map[string]*keyringEntry)
+	keyringsMutex sync.Mutex
+)
+
+// userID - ID of user (0 if authentication is disabled)
+func userID(user *models.User) int64 {
+	if user == nil {
+		return 0
+	}
+	return user.ID
+}
+
+// getKeyringEntry - get unlocked keys of client in all notebooks (nil if nothing is unlocked)
+// Keys are bound to user, so the cookie is useless after logout or in other account
+func getKeyringEntry(r *http.Request, user *models.User) *keyringEntry {
+	cookie, err := r.Cookie(KeyringCookie)
+	if err != nil {
+		return nil
+	}
+
+	keyringsMutex.Lock()
+	defer keyringsMutex.Unlock()
+
+	entry, ok := keyrings[cookie.Value]
+	if !ok {
+		return nil
+	}
+	if time.Now().After(entry.Expires) || entry.UserID != userID(user) {
+		delete(keyrings, cookie.Value)
+		return nil
+	}
+
+	// Sliding expiration
+	entry.Expires = time.Now().Add(KeyringTTL)
+
+	return entry
+}
+
+// GetKeyring - get unlocked keys of client in notebook of request (nil if nothing is unlocked)
+func GetKeyring(r *http.Request, user *models.User) *services.Keyring {
+	entry := getKeyringEntry(r, user)
+	if entry == nil {
+		return nil
+	}
+
+	keyringsMutex.Lock()
+	defer keyringsMutex.Unlock()
+
+	return entry.Keyrings[database.GetContextNotebook(r.Context()).Name]
+}
+
+// UnlockKey - save vault key for client, create keyring and cookie if needed
+func UnlockKey(w http.ResponseWriter, r *http.Request, user *models.User, vaultID int64, key []byte) error {
+	notebook := database.GetContextNotebook(r.Context()).Name
+
+	if entry := getKeyringEntry(r, user); entry != nil {
+		keyringsMutex.Lock()
+		keyring, ok := entry.Keyrings[notebook]
+		if !ok {
+			keyring = services.NewKeyring()
+			entry.Keyrings[notebook] = keyring
+		}
+		keyringsMutex.Unlock()
+
+		keyring.Set(vaultID, key)
+		return nil
+	}
+
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return err
+	}
+	id := hex.EncodeToString(buf)
+
+	keyring := services.NewKeyring()
+	keyring.Set(vaultID, key)
+
+	keyringsMutex.Lock()
+	keyrings[id] = &keyringEntry{
+		UserID:   userID(user),
+		Keyrings: map[string]*services.Keyring{notebook: keyring},
+		Expires:  time.Now().Add(KeyringTTL),
+	}
+	keyringsMutex.Unlock()
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     KeyringCookie,
+		Value:    id,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteStrictMode,
+	})
+
+	return nil
+}
+
+// LockAll - forget all unlocked keys of client
+func LockAll(w http.ResponseWriter, r *http.Request) {
+	if cookie, err := r.Cookie(KeyringCookie); err == nil {
+		keyringsMutex.Lock()
+		delete(keyrings, cookie.Value)
+		keyringsMutex.Unlock()
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     KeyringCookie,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteStrictMode,
+	})
+}
+
+// ForgetVault - remove vault key of notebook from all keyrings (vault is deleted)
+func ForgetVault(notebook string, vaultID int64) {
+	keyringsMutex.Lock()
+	defer keyringsMutex.Unlock()
+
+	for _, entry := range keyrings {
+		if keyring, ok := entry.Keyrings[notebook]; ok {
+			keyring.Remove(vaultID)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package auth
+
+import (
+	"errors"
+
+	"github.com/sondrus/tetrad/models"
+	"github.com/sondrus/tetrad/services"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// OptionPassword - option name for single password hash (before user accounts)
+const OptionPassword = "server.auth.password"
+
+// DefaultUser - user name for single-user setup
+const DefaultUser = "admin"
+
+// dummyHash - used for constant time check of unknown users
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("tetrad"), bcrypt.DefaultCost)
+
+// IsEnabled - check any user exists (authentication is required)
+func IsEnabled() bool {
+	return services.CountUsers() > 0
+}
+
+// HashPassword - get bcrypt hash for password
+func HashPassword(password string) (string, error) {
+	if password == "" {
+		return "", errors.New("password is empty")
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+
+	return string(hash), nil
+}
+
+// Authenticate - check user name and password
+func Authenticate(name string, password string) (*models.User, error) {
+	if name == "" {
+		name = DefaultUser
+	}
+
+	user, err := services.GetUserByName(name)
+	if err != nil {
+		bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+		return nil, errors.New("wrong user name or password")
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
+		return nil, errors.New("wrong user name or password")
+	}
+
+	return user, nil
+}
+
+// SetPassword - set password for user, create user if not exists (the first user is admin)
+// Empty password deletes user, authentication is disabled when there are no users
+func SetPassword(name string, password string) error {
+	if name == "" {
+		name = DefaultUser
+	}
+	user, err := services.GetUserByName(name)
+
+	// Delete user
+	if password == "" {
+		if err != nil {
+			return nil
+		}
+		DeleteUserSessions(user.ID)
+		return services.DeleteUser(user.ID)
+	}
+
+	hash, err := HashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	// Create new user
+	if user == nil {
+		_, err := services.CreateUser(name, hash, services.CountUsers() == 0)
+		return err
+	}
+
+	// Update password, logout everywhere
+	DeleteUserSessions(user.ID)
+	return services.UpdateUser(user.ID, map[string]any{"PASSWORD": hash})
+}
+
+// MigrateLegacyPassword - convert single password (option) to `admin` user
+func MigrateLegacyPassword() error {
+	hash, ok := services.GetServerOption(OptionPassword)
+	if !ok {
+		return nil
+	}
+
+	if hash != "" && services.CountUsers() == 0 {
+		if _, err := services.CreateUser(DefaultUser, hash, true); err != nil {
+			return err
+		}
+	}
+
+	return services.DeleteServerOption(OptionPassword)
+}
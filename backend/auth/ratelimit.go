@@ -0,0 +1,72 @@
+package auth
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/sondrus/tetrad/services"
+)
+
+const (
+	// loginMaxFailures - failed logins allowed per window
+	loginMaxFailures = 5
+
+	// loginWindow - window for counting failed logins (and lock duration)
+	loginWindow = 15 * time.Minute
+)
+
+// loginFailures - failed logins for single client address
+type loginFailures struct {
+	Count int
+	Since time.Time
+}
+
+var (
+	failures      = make(map[string]*loginFailures)
+	failuresMutex sync.Mutex
+)
+
+// LoginAllowed - check client is not locked out, returns time to wait
+func LoginAllowed(r *http.Request) (bool, time.Duration) {
+	failuresMutex.Lock()
+	defer failuresMutex.Unlock()
+
+	f, ok := failures[services.ClientAddress(r)]
+	if !ok {
+		return true, 0
+	}
+
+	elapsed := time.Since(f.Since)
+	if elapsed > loginWindow {
+		delete(failures, services.ClientAddress(r))
+		return true, 0
+	}
+
+	if f.Count >= loginMaxFailures {
+		return false, loginWindow - elapsed
+	}
+
+	return true, 0
+}
+
+// LoginFailed - register failed login
+func LoginFailed(r *http.Request) {
+	failuresMutex.Lock()
+	defer failuresMutex.Unlock()
+
+	address := services.ClientAddress(r)
+	f, ok := failures[address]
+	if !ok || time.Since(f.Since) > loginWindow {
+		f = &loginFailures{Since: time.Now()}
+		failures[address] = f
+	}
+	f.Count++
+}
+
+// LoginSucceeded - reset failed logins counter
+func LoginSucceeded(r *http.Request) {
+	failuresMutex.Lock()
+	delete(failures, services.ClientAddress(r))
+	failuresMutex.Unlock()
+}
@@ -0,0 +1,114 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/sondrus/tetrad/models"
+	"github.com/sondrus/tetrad/services"
+)
+
+// SessionCookie - name of session cookie
+const SessionCookie = "tetrad_session"
+
+// SessionTTL - session lifetime
+const SessionTTL = 30 * 24 * time.Hour
+
+// session - single logged in client
+type session struct {
+	UserID  int64
+	Expires time.Time
+}
+
+var (
+	sessions      = make(map[string]session)
+	sessionsMutex sync.Mutex
+)
+
+// CreateSession - create new session for user and set cookie
+func CreateSession(w http.ResponseWriter, r *http.Request, user *models.User) error {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return err
+	}
+	token := hex.EncodeToString(buf)
+	expires := time.Now().Add(SessionTTL)
+
+	sessionsMutex.Lock()
+	sessions[token] = session{UserID: user.ID, Expires: expires}
+	sessionsMutex.Unlock()
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     SessionCookie,
+		Value:    token,
+		Path:     "/",
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteStrictMode,
+	})
+
+	return nil
+}
+
+// DeleteSession - remove session and cookie
+func DeleteSession(w http.ResponseWriter, r *http.Request) {
+	if cookie, err := r.Cookie(SessionCookie); err == nil {
+		sessionsMutex.Lock()
+		delete(sessions, cookie.Value)
+		sessionsMutex.Unlock()
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     SessionCookie,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteStrictMode,
+	})
+}
+
+// DeleteUserSessions - logout user everywhere (eg, after password change)
+func DeleteUserSessions(userID int64) {
+	sessionsMutex.Lock()
+	defer sessionsMutex.Unlock()
+
+	for token, s := range sessions {
+		if s.UserID == userID {
+			delete(sessions, token)
+		}
+	}
+}
+
+// GetSessionUser - get user by session cookie (nil if not logged in)
+func GetSessionUser(r *http.Request) *models.User {
+	cookie, err := r.Cookie(SessionCookie)
+	if err != nil {
+		return nil
+	}
+
+	sessionsMutex.Lock()
+	s, ok := sessions[cookie.Value]
+	if ok && time.Now().After(s.Expires) {
+		delete(sessions, cookie.Value)
+		ok = false
+	}
+	sessionsMutex.Unlock()
+
+	if !ok {
+		return nil
+	}
+
+	// Load actual user data (could be changed or deleted)
+	user, err := services.GetUser(s.UserID)
+	if err != nil {
+		return nil
+	}
+
+	return user
+}
@@ -0,0 +1,28 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+
+	"github.com/sondrus/tetrad/models"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// GenerateShareToken - create new random token of share link, returns token and its hash
+func GenerateShareToken() (string, string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(buf)
+
+	return token, HashToken(token), nil
+}
+
+// CheckSharePassword - check password of share link (links without password are always open)
+func CheckSharePassword(share models.Share, password string) bool {
+	if share.Password == "" {
+		return true
+	}
+	return bcrypt.CompareHashAndPassword([]byte(share.Password), []byte(password)) == nil
+}
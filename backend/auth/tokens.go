@@ -0,0 +1,86 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sondrus/tetrad/models"
+	"github.com/sondrus/tetrad/services"
+)
+
+// TokenPrefix - prefix of personal API tokens (easy to find in scripts and logs)
+const TokenPrefix = "tetrad_"
+
+// GenerateToken - create new random API token, returns token and its hash
+func GenerateToken() (string, string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+	token := TokenPrefix + hex.EncodeToString(buf)
+
+	return token, HashToken(token), nil
+}
+
+// HashToken - get hash of API token for storage (tokens are random, so SHA-256 is enough)
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// GetBearerToken - get token from `Authorization: Bearer ...` header
+func GetBearerToken(r *http.Request) string {
+	header := r.Header.Get("Authorization")
+	scheme, token, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+// GetTokenUser - get user and token by Authorization header (nil if token is missing, wrong or expired)
+func GetTokenUser(r *http.Request) (*models.User, *models.Token) {
+	value := GetBearerToken(r)
+	if value == "" {
+		return nil, nil
+	}
+
+	token, err := services.GetTokenByHash(HashToken(value))
+	if err != nil {
+		return nil, nil
+	}
+	if token.DateExpires > 0 && time.Now().Unix() >= token.DateExpires {
+		return nil, nil
+	}
+
+	user, err := services.GetUser(token.UserID)
+	if err != nil {
+		return nil, nil
+	}
+
+	services.TouchToken(token)
+
+	return user, token
+}
+
+// TokenAllows - check token scope allows request (read-only tokens: GET and search)
+func TokenAllows(token *models.Token, r *http.Request) bool {
+	if token.Scope == services.ScopeReadWrite {
+		return true
+	}
+
+	switch {
+	case r.Method == http.MethodGet, r.Method == http.MethodHead:
+		return true
+	case r.Method == http.MethodPost && r.URL.Path == "/api/notes/search":
+		return true
+	case r.Method == http.MethodPost && r.URL.Path == "/api/markdown/render":
+		return true
+	}
+
+	return false
+}
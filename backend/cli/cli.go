@@ -0,0 +1,167 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/sondrus/tetrad/database"
+	"github.com/sondrus/tetrad/models"
+	"github.com/sondrus/tetrad/services"
+)
+
+// Command - subcommand for headless operation on database file
+type Command struct {
+	Name string
+	Args string
+	Help string
+	Run  func(args []string) error
+}
+
+// Commands - all subcommands, `serve` is run by main (it is the default command)
+var Commands []Command
+
+// init - fill commands list (commands refer to the list for own usage)
+func init() {
+	Commands = []Command{
+		{Name: "serve", Help: "Run web server (default)"},
+		{Name: "backup", Args: "[--notebook name] <file>", Help: "Write consistent copy of database to new file", Run: backupCommand},
+		{Name: "export", Args: "[--notebook name] [--note id|path] <directory>", Help: "Export notes to directory tree of files", Run: exportCommand},
+		{Name: "import", Args: "[--notebook name] [--parent id|path] joplin|evernote <file>...", Help: "Import Joplin (JEX) or Evernote (ENEX) export", Run: importCommand},
+		{Name: "search", Args: "[--notebook name] [--title] [--whole] <query>", Help: "Search notes, print IDs and paths", Run: searchCommand},
+		{Name: "cat", Args: "[--notebook name] <id|path>", Help: "Print note contents", Run: catCommand},
+		{Name: "add", Args: "[--notebook name] [--parent id|path] [--title title] [--type type] < file", Help: "Create note with contents from stdin", Run: addCommand},
+		{Name: "vacuum", Args: "[--notebook name]", Help: "Compress database file", Run: vacuumCommand},
+		{Name: "check", Args: "[--notebook name]", Help: "Check database integrity and notes tree", Run: checkCommand},
+		{Name: "rebuild-tree", Args: "[--notebook name]", Help: "Rebuild nested set values of notes tree", Run: rebuildTreeCommand},
+		{Name: "set-password", Args: "[user]", Help: "Set password for user (empty password deletes user)", Run: setPasswordCommand},
+	}
+}
+
+// FindCommand - get subcommand by name (nil if it is unknown)
+func FindCommand(name string) *Command {
+	for i := range Commands {
+		if Commands[i].Name == name {
+			return &Commands[i]
+		}
+	}
+	return nil
+}
+
+// Usage - print help for subcommands and global flags
+func Usage() {
+	out := flag.CommandLine.Output()
+	name := filepath.Base(os.Args[0])
+
+	fmt.Fprintf(out, "Usage: %s [flags] [command] [arguments]\n\nCommands:\n", name)
+	for _, c := range Commands {
+		fmt.Fprintf(out, "  %-13s %s\n", c.Name, c.Help)
+	}
+	fmt.Fprintf(out, "\nRun `%s <command> --help` for arguments of command.\n\nFlags:\n", name)
+	flag.PrintDefaults()
+}
+
+// Run - run subcommand (database must be loaded)
+func Run(name string, args []string) error {
+	command := FindCommand(name)
+	if command == nil || command.Run == nil {
+		return fmt.Errorf("unknown command %s", name)
+	}
+
+	// Help is printed by flag set already
+	if err := command.Run(args); !errors.Is(err, flag.ErrHelp) {
+		return err
+	}
+	return nil
+}
+
+// commandFlags - flag set of subcommand (notebook is nil for commands without --notebook)
+type commandFlags struct {
+	*flag.FlagSet
+	notebook *string
+}
+
+// newFlags - create flag set of subcommand
+func newFlags(name string) *commandFlags {
+	f := newPlainFlags(name)
+	f.notebook = f.String("notebook", "", "Notebook name (default: main database)")
+	return f
+}
+
+// newPlainFlags - create flag set of subcommand which does not work with notebook
+func newPlainFlags(name string) *commandFlags {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		command := FindCommand(name)
+		fmt.Fprintf(fs.Output(), "Usage: %s %s %s\n\n%s\n", filepath.Base(os.Args[0]), name, command.Args, command.Help)
+		fs.PrintDefaults()
+	}
+
+	return &commandFlags{FlagSet: fs}
+}
+
+// parse - parse flags which could be mixed with positional arguments, returns positional arguments
+func (f *commandFlags) parse(args []string, count int) ([]string, error) {
+	var positional []string
+	for {
+		if err := f.Parse(args); err != nil {
+			return nil, err
+		}
+		args = f.Args()
+		if len(args) == 0 {
+			break
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+
+	if count >= 0 && len(positional) != count {
+		f.Usage()
+		return nil, errors.New("wrong count of arguments")
+	}
+
+	return positional, nil
+}
+
+// context - context with selected notebook (commands run as admin, like without authentication)
+func (f *commandFlags) context() (context.Context, error) {
+	notebook, err := database.GetNotebook(*f.notebook)
+	if err != nil {
+		return nil, fmt.Errorf("notebook %s: %w", *f.notebook, err)
+	}
+
+	return database.WithNotebook(context.Background(), notebook), nil
+}
+
+// findNote - get note by ID or by path of titles
+func findNote(ctx context.Context, ref string) (models.NoteDB, error) {
+	if id, err := strconv.Atoi(ref); err == nil {
+		return services.GetNote(ctx, id)
+	}
+
+	return services.FindNoteByPath(ctx, ref)
+}
+
+// notePaths - paths of all notes (titles from root joined by "/")
+func notePaths(ctx context.Context) (map[int64]string, error) {
+	notes, err := services.GetNotesList(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Notes are sorted by LEFT, so parents are always before children
+	paths := make(map[int64]string, len(notes))
+	for _, note := range notes {
+		if parent, ok := paths[note.ParentID]; ok {
+			paths[note.ID] = parent + "/" + note.Title
+		} else {
+			paths[note.ID] = note.Title
+		}
+	}
+
+	return paths, nil
+}
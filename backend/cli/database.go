@@ -0,0 +1,93 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/sondrus/tetrad/database"
+	"github.com/sondrus/tetrad/services"
+)
+
+// backupCommand - write copy of database file (safe while server is running)
+func backupCommand(args []string) error {
+	f := newFlags("backup")
+	positional, err := f.parse(args, 1)
+	if err != nil {
+		return err
+	}
+	ctx, err := f.context()
+	if err != nil {
+		return err
+	}
+
+	path, err := filepath.Abs(positional[0])
+	if err != nil {
+		return err
+	}
+	if err := database.GetContextNotebook(ctx).Backup(path); err != nil {
+		return err
+	}
+
+	fmt.Printf("Backup is written to %s\n", path)
+	return nil
+}
+
+// vacuumCommand - compress database file
+func vacuumCommand(args []string) error {
+	f := newFlags("vacuum")
+	if _, err := f.parse(args, 0); err != nil {
+		return err
+	}
+	ctx, err := f.context()
+	if err != nil {
+		return err
+	}
+
+	notebook := database.GetContextNotebook(ctx)
+	before := notebook.Filesize()
+	if err := notebook.Vacuum(); err != nil {
+		return err
+	}
+	after := notebook.Filesize()
+
+	services.AuditCommand(ctx, services.AuditEntry{
+		Operation: services.AuditVacuum,
+		Details:   fmt.Sprintf("%d => %d bytes", before, after),
+	})
+
+	fmt.Printf("Database is compressed: %d => %d bytes\n", before, after)
+	return nil
+}
+
+// checkCommand - check SQLite integrity and notes tree, fails if problems are found
+func checkCommand(args []string) error {
+	f := newFlags("check")
+	if _, err := f.parse(args, 0); err != nil {
+		return err
+	}
+	ctx, err := f.context()
+	if err != nil {
+		return err
+	}
+
+	problems, err := database.GetContextNotebook(ctx).IntegrityCheck()
+	if err != nil {
+		return err
+	}
+	treeProblems, err := services.CheckNotesTree(ctx)
+	if err != nil {
+		return err
+	}
+	problems = append(problems, treeProblems...)
+
+	for _, problem := range problems {
+		fmt.Println(problem)
+	}
+	if len(problems) > 0 {
+		return errors.New("database has problems")
+	}
+
+	fmt.Println("Database is fine")
+	return nil
+}
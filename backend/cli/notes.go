@@ -0,0 +1,371 @@
+package cli
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sondrus/tetrad/database"
+	"github.com/sondrus/tetrad/importers"
+	"github.com/sondrus/tetrad/models"
+	"github.com/sondrus/tetrad/services"
+)
+
+// reservedFileChars - characters which are not allowed in exported file names
+const reservedFileChars = `/\:*?"<>|`
+
+// searchCommand - search notes, print ID and path of found notes
+func searchCommand(args []string) error {
+	f := newFlags("search")
+	title := f.Bool("title", false, "Search in titles only")
+	whole := f.Bool("whole", false, "Search whole phrase, not separate words")
+	positional, err := f.parse(args, -1)
+	if err != nil {
+		return err
+	}
+	ctx, err := f.context()
+	if err != nil {
+		return err
+	}
+
+	notes, err := services.SearchNotes(ctx, nil, services.SearchOptions{
+		Query: strings.Join(positional, " "),
+		Title: *title,
+		Whole: *whole,
+	})
+	if err != nil {
+		return err
+	}
+
+	paths, err := notePaths(ctx)
+	if err != nil {
+		return err
+	}
+	for _, note := range notes {
+		fmt.Printf("%d\t%s\n", note.ID, paths[note.ID])
+	}
+
+	return nil
+}
+
+// catCommand - print note contents
+func catCommand(args []string) error {
+	f := newFlags("cat")
+	positional, err := f.parse(args, 1)
+	if err != nil {
+		return err
+	}
+	ctx, err := f.context()
+	if err != nil {
+		return err
+	}
+
+	note, err := findNote(ctx, positional[0])
+	if err != nil {
+		return err
+	}
+	if note.Locked {
+		return services.ErrLocked
+	}
+
+	switch note.Type {
+	case "URL":
+		fmt.Println(note.URL)
+	default:
+		fmt.Print(note.Contents)
+		if note.Contents != "" && !strings.HasSuffix(note.Contents, "\n") {
+			fmt.Println()
+		}
+	}
+
+	return nil
+}
+
+// addCommand - create note with contents from stdin, print ID of new note
+func addCommand(args []string) error {
+	f := newFlags("add")
+	parent := f.String("parent", "", "Parent note ID or path (default: root)")
+	title := f.String("title", "", "Note title (default: first line of contents)")
+	noteType := f.String("type", "MD", "Note type: MD, HTML, CODE or TEXT")
+	if _, err := f.parse(args, 0); err != nil {
+		return err
+	}
+	ctx, err := f.context()
+	if err != nil {
+		return err
+	}
+
+	var parentID int64
+	if *parent != "" {
+		note, err := findNote(ctx, *parent)
+		if err != nil {
+			return fmt.Errorf("parent: %w", err)
+		}
+		parentID = note.ID
+	}
+
+	contents, err := io.ReadAll(bufio.NewReader(os.Stdin))
+	if err != nil {
+		return err
+	}
+	if *title == "" {
+		*title = titleFromContents(string(contents))
+	}
+
+	fields := map[string]any{
+		"PARENT_ID": parentID,
+		"TYPE":      strings.ToUpper(*noteType),
+		"TITLE":     *title,
+		"CONTENTS":  string(contents),
+	}
+	id, err := services.CreateNote(ctx, nil, nil, fields)
+	if err != nil {
+		return err
+	}
+
+	services.AuditCommand(ctx, services.AuditEntry{
+		Operation: services.AuditNoteCreate,
+		NoteIDs:   []int64{id},
+		Fields:    []string{"CONTENTS", "PARENT_ID", "TITLE", "TYPE"},
+		Details:   fmt.Sprintf("parent %d", parentID),
+	})
+
+	fmt.Println(id)
+	return nil
+}
+
+// importCommand - import Joplin or Evernote export files
+func importCommand(args []string) error {
+	f := newFlags("import")
+	parent := f.String("parent", "", "Parent note ID or path (default: root)")
+	positional, err := f.parse(args, -1)
+	if err != nil {
+		return err
+	}
+	if len(positional) < 2 {
+		f.Usage()
+		return errors.New("format and files are required")
+	}
+	ctx, err := f.context()
+	if err != nil {
+		return err
+	}
+
+	var parentID int64
+	if *parent != "" {
+		note, err := findNote(ctx, *parent)
+		if err != nil {
+			return fmt.Errorf("parent: %w", err)
+		}
+		if vault, _ := services.GetVaultByNote(ctx, note); vault != nil {
+			return errors.New("import into encrypted subtree is not supported")
+		}
+		parentID = note.ID
+	}
+
+	// Open all files
+	var sources []importers.Source
+	for _, path := range positional[1:] {
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		sources = append(sources, importers.Source{Name: filepath.Base(path), Reader: file})
+	}
+
+	// Import
+	var report *importers.Report
+	format := strings.ToLower(positional[0])
+	switch format {
+	case "joplin":
+		if len(sources) != 1 {
+			return errors.New("exactly one JEX file is expected")
+		}
+		report, err = importers.ImportJoplin(database.GetContextORM(ctx), sources[0].Reader, parentID)
+	case "evernote":
+		report, err = importers.ImportEvernote(database.GetContextORM(ctx), sources, parentID)
+	default:
+		return fmt.Errorf("unknown import format %s (joplin or evernote)", positional[0])
+	}
+	if err != nil {
+		return err
+	}
+
+	// Nested set rebuild and tasks of imported notes
+	services.RebuildNotesTree(ctx)
+	services.ReindexTasks(ctx)
+
+	var noteIDs []int64
+	if parentID > 0 {
+		noteIDs = []int64{parentID}
+	}
+	services.AuditCommand(ctx, services.AuditEntry{
+		Operation: services.AuditNotesImport,
+		NoteIDs:   noteIDs,
+		Details:   fmt.Sprintf("%s: %s", format, report),
+	})
+
+	fmt.Printf("Imported: %s\n", report)
+	for _, skipped := range report.Skipped {
+		fmt.Printf("Skipped %s (%s): %s\n", skipped.Title, skipped.Source, skipped.Reason)
+	}
+
+	return nil
+}
+
+// exportCommand - write notes to directory, notes with children become directories too
+func exportCommand(args []string) error {
+	f := newFlags("export")
+	root := f.String("note", "", "Export just subtree of note ID or path (default: all notes)")
+	positional, err := f.parse(args, 1)
+	if err != nil {
+		return err
+	}
+	ctx, err := f.context()
+	if err != nil {
+		return err
+	}
+
+	// Target directory must be new or empty
+	dir := positional[0]
+	if entries, err := os.ReadDir(dir); err == nil && len(entries) > 0 {
+		return fmt.Errorf("directory %s is not empty", dir)
+	}
+
+	opts := services.NoteQueryOptions{Order: "LEFT ASC"}
+	if *root != "" {
+		note, err := findNote(ctx, *root)
+		if err != nil {
+			return err
+		}
+		opts.Where = `"LEFT" >= ? AND "RIGHT" <= ?`
+		opts.Args = []any{note.Left, note.Right}
+	}
+	notes, err := services.GetNotes(ctx, opts)
+	if err != nil {
+		return err
+	}
+
+	exported, locked, err := exportNotes(notes, dir)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Exported %d notes to %s\n", exported, dir)
+	if locked > 0 {
+		fmt.Printf("Skipped %d encrypted notes\n", locked)
+	}
+
+	return nil
+}
+
+// exportNotes - write notes (sorted by LEFT) to files, returns count of written and encrypted notes
+func exportNotes(notes []models.NoteDB, dir string) (int, int, error) {
+	// Directory of every note (for its children) and used names in every directory
+	dirs := make(map[int64]string)
+	used := make(map[string]bool)
+	hasChildren := make(map[int64]bool)
+	for _, note := range notes {
+		hasChildren[note.ParentID] = true
+	}
+
+	exported, locked := 0, 0
+	for _, note := range notes {
+		parentDir, ok := dirs[note.ParentID]
+		if !ok {
+			parentDir = dir
+		}
+
+		// Unique name inside directory
+		name := exportName(note.Title)
+		if used[filepath.Join(parentDir, strings.ToLower(name))] {
+			name = fmt.Sprintf("%s (%d)", name, note.ID)
+		}
+		used[filepath.Join(parentDir, strings.ToLower(name))] = true
+
+		if hasChildren[note.ID] {
+			dirs[note.ID] = filepath.Join(parentDir, name)
+		}
+		if err := os.MkdirAll(parentDir, 0700); err != nil {
+			return exported, locked, err
+		}
+
+		if note.Locked {
+			locked++
+			continue
+		}
+
+		contents, ext := exportContents(note)
+		if err := os.WriteFile(filepath.Join(parentDir, name+ext), []byte(contents), 0600); err != nil {
+			return exported, locked, err
+		}
+		exported++
+	}
+
+	return exported, locked, nil
+}
+
+// exportName - note title => file name without extension
+func exportName(title string) string {
+	name := strings.Map(func(r rune) rune {
+		if r < 32 || strings.ContainsRune(reservedFileChars, r) {
+			return '_'
+		}
+		return r
+	}, strings.TrimSpace(title))
+
+	name = strings.Trim(name, ".")
+	if name == "" {
+		name = "Untitled"
+	}
+	return name
+}
+
+// exportContents - file contents and extension by note type
+func exportContents(note models.NoteDB) (string, string) {
+	switch note.Type {
+	case "HTML", "IFRAME":
+		return note.Contents, ".html"
+	case "CODE", "TEXT":
+		return note.Contents, ".txt"
+	case "URL":
+		return fmt.Sprintf("[InternetShortcut]\r\nURL=%s\r\n", note.URL), ".url"
+	default:
+		return note.Contents, ".md"
+	}
+}
+
+// titleFromContents - first non-empty line without markdown heading marks
+func titleFromContents(contents string) string {
+	for line := range strings.Lines(contents) {
+		line = strings.TrimSpace(strings.TrimLeft(line, "# \t"))
+		if line != "" {
+			return line
+		}
+	}
+	return "Untitled"
+}
+
+// rebuildTreeCommand - rebuild nested set values
+func rebuildTreeCommand(args []string) error {
+	f := newFlags("rebuild-tree")
+	if _, err := f.parse(args, 0); err != nil {
+		return err
+	}
+	ctx, err := f.context()
+	if err != nil {
+		return err
+	}
+
+	if err := services.RebuildNotesTree(ctx); err != nil {
+		return err
+	}
+
+	fmt.Println("Notes tree is rebuilt")
+	return nil
+}
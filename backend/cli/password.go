@@ -0,0 +1,63 @@
+package cli
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/sondrus/tetrad/auth"
+	"github.com/sondrus/tetrad/config"
+	"golang.org/x/term"
+)
+
+// setPasswordCommand - read new password from terminal (or stdin) and save it
+func setPasswordCommand(args []string) error {
+	f := newPlainFlags("set-password")
+	positional, err := f.parse(args, -1)
+	if err != nil {
+		return err
+	}
+
+	// User from argument or --user flag
+	user := config.AppConfig.User
+	switch len(positional) {
+	case 0:
+	case 1:
+		user = positional[0]
+	default:
+		f.Usage()
+		return errors.New("wrong count of arguments")
+	}
+
+	fmt.Printf("New password for %s (empty to delete user): ", user)
+
+	var password string
+	if term.IsTerminal(int(os.Stdin.Fd())) {
+		input, err := term.ReadPassword(int(os.Stdin.Fd()))
+		fmt.Println()
+		if err != nil {
+			return err
+		}
+		password = string(input)
+	} else {
+		input, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && len(input) == 0 {
+			return err
+		}
+		password = strings.TrimRight(input, "\r\n")
+	}
+
+	if err := auth.SetPassword(user, password); err != nil {
+		return err
+	}
+
+	if password == "" {
+		fmt.Printf("User %s is deleted\n", user)
+	} else {
+		fmt.Printf("Password for %s is set\n", user)
+	}
+
+	return nil
+}
@@ -0,0 +1,322 @@
+package clipper
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/sondrus/tetrad/config"
+	"github.com/sondrus/tetrad/htmlmd"
+	"github.com/sondrus/tetrad/meta"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/charset"
+)
+
+// maxRedirects - redirects followed while fetching page
+const maxRedirects = 5
+
+// maxFaviconSize - favicons are small, bigger files are skipped
+const maxFaviconSize = 256 << 10
+
+var (
+	// ErrForbidden - host is denied by allow/deny lists or it is private network
+	ErrForbidden = errors.New("host is not allowed")
+
+	// ErrTooLarge - page is bigger than size limit
+	ErrTooLarge = errors.New("page is too large")
+
+	// ErrNotHTML - response is not HTML page
+	ErrNotHTML = errors.New("page is not HTML")
+)
+
+// Options - limits for fetching pages
+type Options struct {
+	Timeout time.Duration // whole request, including redirects and reading body
+	MaxSize int64         // max size of page (bytes)
+	Allow   []string      // hosts, domains (*.example.com), IPs or networks (10.0.0.0/8), empty = any public host
+	Deny    []string      // same as allow list, deny list wins
+}
+
+// Favicon - icon of page
+type Favicon struct {
+	Name string
+	Mime string
+	Data []byte
+}
+
+// Page - readable copy of web page
+type Page struct {
+	URL      string // final URL (after redirects)
+	Title    string
+	Markdown string
+	Favicon  *Favicon // nil if page has no icon
+	Date     time.Time
+}
+
+// DefaultOptions - limits from config
+func DefaultOptions() Options {
+	return Options{
+		Timeout: config.AppConfig.ClipperTimeout,
+		MaxSize: config.AppConfig.ClipperMaxSize,
+		Allow:   config.AppConfig.ClipperAllow,
+		Deny:    config.AppConfig.ClipperDeny,
+	}
+}
+
+// Clip - fetch page and extract its readable content as markdown
+func Clip(ctx context.Context, pageURL string, opts Options) (*Page, error) {
+	target, err := url.Parse(strings.TrimSpace(pageURL))
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return nil, fmt.Errorf("wrong page URL %q", pageURL)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, opts.Timeout)
+	defer cancel()
+
+	client := newClient(opts)
+	body, contentType, final, err := fetch(ctx, client, target.String(), opts.MaxSize)
+	if err != nil {
+		return nil, err
+	}
+	if mediaType, _, _ := mime.ParseMediaType(contentType); mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return nil, fmt.Errorf("%w: %s", ErrNotHTML, contentType)
+	}
+
+	// Page encoding by header, <meta charset> or content
+	reader, err := charset.NewReader(bytes.NewReader(body), contentType)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := html.Parse(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	article := extract(doc, final)
+	page := &Page{
+		URL:      final.String(),
+		Title:    article.Title,
+		Markdown: htmlmd.ConvertNode(article.Content, htmlmd.Options{}),
+		Date:     time.Now(),
+	}
+	if page.Title == "" {
+		page.Title = final.Host
+	}
+
+	// Icon is optional, page is saved without it
+	if article.Favicon != "" {
+		page.Favicon = fetchFavicon(ctx, client, article.Favicon, min(opts.MaxSize, maxFaviconSize))
+	}
+
+	return page, nil
+}
+
+// fetch - GET URL, returns body, content type and final URL
+func fetch(ctx context.Context, client *http.Client, target string, maxSize int64) ([]byte, string, *url.URL, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	req.Header.Set("User-Agent", meta.Server+" (web clipper)")
+	req.Header.Set("Accept", "text/html,application/xhtml+xml;q=0.9,*/*;q=0.5")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, "", nil, fmt.Errorf("page responded with status %d", resp.StatusCode)
+	}
+	if resp.ContentLength > maxSize {
+		return nil, "", nil, ErrTooLarge
+	}
+
+	// Length could be unknown, so body is limited anyway
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxSize+1))
+	if err != nil {
+		return nil, "", nil, err
+	}
+	if int64(len(body)) > maxSize {
+		return nil, "", nil, ErrTooLarge
+	}
+
+	return body, resp.Header.Get("Content-Type"), resp.Request.URL, nil
+}
+
+// fetchFavicon - download icon of page (nil if it is missing or not an image)
+func fetchFavicon(ctx context.Context, client *http.Client, iconURL string, maxSize int64) *Favicon {
+	data, contentType, final, err := fetch(ctx, client, iconURL, maxSize)
+	if err != nil || len(data) == 0 {
+		return nil
+	}
+
+	// Icons are often served with wrong type
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if !strings.HasPrefix(mediaType, "image/") {
+		mediaType, _, _ = mime.ParseMediaType(http.DetectContentType(data))
+	}
+	if !strings.HasPrefix(mediaType, "image/") {
+		return nil
+	}
+
+	name := path.Base(final.Path)
+	if name == "." || name == "/" {
+		name = "favicon"
+	}
+
+	return &Favicon{Name: name, Mime: mediaType, Data: data}
+}
+
+// newClient - HTTP client which checks every connection by allow/deny lists
+// Addresses are checked after DNS resolution, so redirects and DNS tricks could not reach denied hosts
+func newClient(opts Options) *http.Client {
+	dialer := &net.Dialer{Timeout: opts.Timeout}
+
+	transport := &http.Transport{
+		Proxy: nil, // proxy would connect instead of us, so addresses could not be checked
+		DialContext: func(ctx context.Context, network string, address string) (net.Conn, error) {
+			host, port, err := net.SplitHostPort(address)
+			if err != nil {
+				return nil, err
+			}
+			addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, addr := range addrs {
+				if err := checkAddress(opts, host, addr.IP); err != nil {
+					return nil, err
+				}
+			}
+			if len(addrs) == 0 {
+				return nil, fmt.Errorf("no address for %s", host)
+			}
+
+			return dialer.DialContext(ctx, network, net.JoinHostPort(addrs[0].IP.String(), port))
+		},
+		TLSHandshakeTimeout:   opts.Timeout,
+		ResponseHeaderTimeout: opts.Timeout,
+	}
+
+	return &http.Client{
+		Transport: transport,
+		Timeout:   opts.Timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return errors.New("too many redirects")
+			}
+			if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+				return fmt.Errorf("redirect to %s is not allowed", req.URL.Scheme)
+			}
+			return nil
+		},
+	}
+}
+
+// checkAddress - check host with resolved IP by allow/deny lists
+// Private networks are denied unless they are in allow list
+func checkAddress(opts Options, host string, ip net.IP) error {
+	for _, rule := range opts.Deny {
+		if matchRule(rule, host, ip) {
+			return fmt.Errorf("%w: %s", ErrForbidden, host)
+		}
+	}
+
+	if len(opts.Allow) > 0 {
+		for _, rule := range opts.Allow {
+			if matchRule(rule, host, ip) {
+				return nil
+			}
+		}
+		return fmt.Errorf("%w: %s", ErrForbidden, host)
+	}
+
+	if !isPublic(ip) {
+		return fmt.Errorf("%w: %s is private address", ErrForbidden, host)
+	}
+
+	return nil
+}
+
+// nonPublicNetworks - special-purpose ranges which are not covered by net.IP helpers (RFC 6890 and later)
+var nonPublicNetworks = parseNetworks(
+	"0.0.0.0/8",       // "this" network
+	"100.64.0.0/10",   // shared address space (CGNAT)
+	"192.0.0.0/24",    // IETF protocol assignments
+	"192.0.2.0/24",    // documentation (TEST-NET-1)
+	"198.18.0.0/15",   // benchmarking
+	"198.51.100.0/24", // documentation (TEST-NET-2)
+	"203.0.113.0/24",  // documentation (TEST-NET-3)
+	"240.0.0.0/4",     // reserved, including broadcast
+	"64:ff9b:1::/48",  // local-use IPv4/IPv6 translation
+	"100::/64",        // discard-only
+	"2001::/23",       // IETF protocol assignments
+	"2001:db8::/32",   // documentation
+	"2002::/16",       // 6to4 (embeds any IPv4)
+	"fc00::/7",        // unique local
+)
+
+// nat64Network - well-known NAT64 prefix, last 32 bits are IPv4 address
+var nat64Network = parseNetworks("64:ff9b::/96")[0]
+
+// isPublic - IP is global unicast address on the internet
+func isPublic(ip net.IP) bool {
+	// IPv4 embedded to NAT64 address is checked itself
+	if nat64Network.Contains(ip) {
+		return isPublic(net.IP(ip.To16()[12:16]))
+	}
+
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsUnspecified() || ip.IsMulticast() {
+		return false
+	}
+	for _, network := range nonPublicNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// parseNetworks - parse CIDR list (panics on wrong value, it is used for constants)
+func parseNetworks(values ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, 0, len(values))
+	for _, value := range values {
+		_, network, err := net.ParseCIDR(value)
+		if err != nil {
+			panic(err)
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
+
+// matchRule - check host or IP matches rule: host, *.domain, IP or network
+func matchRule(rule string, host string, ip net.IP) bool {
+	rule = strings.ToLower(strings.TrimSpace(rule))
+	host = strings.ToLower(host)
+
+	if _, network, err := net.ParseCIDR(rule); err == nil {
+		return network.Contains(ip)
+	}
+	if ruleIP := net.ParseIP(rule); ruleIP != nil {
+		return ruleIP.Equal(ip)
+	}
+	if domain, ok := strings.CutPrefix(rule, "*."); ok {
+		return host == domain || strings.HasSuffix(host, "."+domain)
+	}
+	return host == rule
+}
@@ -0,0 +1,305 @@
+package clipper
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testPage - article with navigation, sidebar and scripts around it
+const testPage = `<!DOCTYPE html>
+<html><head>
+<meta charset="utf-8">
+<title>Site title | Example</title>
+<meta property="og:title" content="Readable Article">
+<link rel="shortcut icon" href="/static/icon.png">
+</head><body>
+<nav><a href="/">Home</a> <a href="/about">About</a></nav>
+<div class="sidebar"><p>Sidebar text which is long enough to be counted, but it is not content.</p></div>
+<article class="post-content">
+<h1>Readable Article</h1>
+<p>First paragraph of the article, with commas, and enough text to be counted as content.</p>
+<p>Second paragraph has a <a href="/link">relative link</a> and an image, more words to pass the limit.</p>
+<img data-src="/img.png" alt="pic">
+<script>alert(1)</script>
+</article>
+<footer>Copyright footer</footer>
+</body></html>`
+
+// testIcon - PNG signature is enough to be detected as image
+var testIcon = []byte("\x89PNG\r\n\x1a\n0000000000")
+
+// allowLocal - options which allow test servers on loopback
+func allowLocal() Options {
+	return Options{Timeout: 5 * time.Second, MaxSize: 1 << 20, Allow: []string{"127.0.0.1"}}
+}
+
+func TestClipExtractsArticle(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/article", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(testPage))
+	})
+	mux.HandleFunc("/static/icon.png", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/octet-stream") // wrong type is sniffed
+		w.Write(testIcon)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	page, err := Clip(context.Background(), server.URL+"/article", allowLocal())
+	if err != nil {
+		t.Fatalf("Clip: %v", err)
+	}
+
+	if page.Title != "Readable Article" {
+		t.Errorf("title = %q, want og:title", page.Title)
+	}
+	if page.Favicon == nil || page.Favicon.Mime != "image/png" || page.Favicon.Name != "icon.png" {
+		t.Errorf("favicon = %+v, want icon.png as image/png", page.Favicon)
+	}
+	for _, want := range []string{
+		"First paragraph of the article",
+		"[relative link](" + server.URL + "/link)",
+		"![pic](" + server.URL + "/img.png)",
+	} {
+		if !strings.Contains(page.Markdown, want) {
+			t.Errorf("markdown has no %q:\n%s", want, page.Markdown)
+		}
+	}
+	for _, unwanted := range []string{"Home", "Sidebar", "alert", "Copyright", "# Readable Article"} {
+		if strings.Contains(page.Markdown, unwanted) {
+			t.Errorf("markdown has %q:\n%s", unwanted, page.Markdown)
+		}
+	}
+}
+
+func TestClipDefaultFavicon(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head><title> Plain  title </title></head><body><p>Some text of the page which is long enough.</p></body></html>`))
+	})
+	mux.HandleFunc("/favicon.ico", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/x-icon")
+		w.Write(testIcon)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	page, err := Clip(context.Background(), server.URL+"/", allowLocal())
+	if err != nil {
+		t.Fatalf("Clip: %v", err)
+	}
+	if page.Title != "Plain title" {
+		t.Errorf("title = %q, want <title>", page.Title)
+	}
+	if page.Favicon == nil || page.Favicon.Mime != "image/x-icon" {
+		t.Errorf("favicon = %+v, want /favicon.ico", page.Favicon)
+	}
+}
+
+func TestClipDeniesPrivateHosts(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("denied server is requested")
+	}))
+	defer server.Close()
+
+	opts := Options{Timeout: 5 * time.Second, MaxSize: 1 << 20}
+	for _, target := range []string{
+		server.URL,                   // loopback
+		"http://10.1.2.3/",           // private
+		"http://100.64.0.1/",         // CGNAT
+		"http://[64:ff9b::a01:203]/", // NAT64 of 10.1.2.3
+	} {
+		if _, err := Clip(context.Background(), target, opts); !errors.Is(err, ErrForbidden) {
+			t.Errorf("Clip(%s) error = %v, want ErrForbidden", target, err)
+		}
+	}
+}
+
+func TestClipAllowAndDenyLists(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(testPage))
+	}))
+	defer server.Close()
+
+	// Allow list opens loopback
+	for _, rule := range []string{"127.0.0.1", "127.0.0.0/8"} {
+		opts := allowLocal()
+		opts.Allow = []string{rule}
+		if _, err := Clip(context.Background(), server.URL, opts); err != nil {
+			t.Errorf("allow %s: %v", rule, err)
+		}
+	}
+
+	// Deny list wins
+	opts := allowLocal()
+	opts.Deny = []string{"127.0.0.0/8"}
+	if _, err := Clip(context.Background(), server.URL, opts); !errors.Is(err, ErrForbidden) {
+		t.Errorf("deny error = %v, want ErrForbidden", err)
+	}
+
+	// Public host which is not in allow list
+	opts = allowLocal()
+	opts.Allow = []string{"*.example.com"}
+	if _, err := Clip(context.Background(), server.URL, opts); !errors.Is(err, ErrForbidden) {
+		t.Errorf("not allowed error = %v, want ErrForbidden", err)
+	}
+}
+
+func TestClipRedirects(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/private", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://10.1.2.3/secret", http.StatusFound)
+	})
+	mux.HandleFunc("/loop", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/loop", http.StatusFound)
+	})
+	mux.HandleFunc("/file", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "file:///etc/passwd", http.StatusFound)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	if _, err := Clip(context.Background(), server.URL+"/private", allowLocal()); !errors.Is(err, ErrForbidden) {
+		t.Errorf("redirect to private host error = %v, want ErrForbidden", err)
+	}
+	if _, err := Clip(context.Background(), server.URL+"/loop", allowLocal()); err == nil || !strings.Contains(err.Error(), "too many redirects") {
+		t.Errorf("redirect loop error = %v, want too many redirects", err)
+	}
+	if _, err := Clip(context.Background(), server.URL+"/file", allowLocal()); err == nil {
+		t.Error("redirect to file URL is followed")
+	}
+}
+
+func TestClipSizeLimit(t *testing.T) {
+	body := "<html><body><p>" + strings.Repeat("a", 4096) + "</p></body></html>"
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/length", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		w.Write([]byte(body))
+	})
+	mux.HandleFunc("/chunked", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		for i := 0; i < len(body); i += 512 {
+			w.Write([]byte(body[i:min(i+512, len(body))]))
+			w.(http.Flusher).Flush() // no Content-Length
+		}
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	opts := allowLocal()
+	opts.MaxSize = 1024
+	for _, target := range []string{"/length", "/chunked"} {
+		if _, err := Clip(context.Background(), server.URL+target, opts); !errors.Is(err, ErrTooLarge) {
+			t.Errorf("%s error = %v, want ErrTooLarge", target, err)
+		}
+	}
+
+	opts.MaxSize = int64(len(body)) + 100
+	if _, err := Clip(context.Background(), server.URL+"/length", opts); err != nil {
+		t.Errorf("page within limit: %v", err)
+	}
+}
+
+func TestClipNotHTML(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"a":1}`))
+	}))
+	defer server.Close()
+
+	if _, err := Clip(context.Background(), server.URL, allowLocal()); !errors.Is(err, ErrNotHTML) {
+		t.Errorf("error = %v, want ErrNotHTML", err)
+	}
+}
+
+func TestClipTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(2 * time.Second):
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+
+	opts := allowLocal()
+	opts.Timeout = 100 * time.Millisecond
+	start := time.Now()
+	if _, err := Clip(context.Background(), server.URL, opts); err == nil {
+		t.Error("slow page is fetched")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("timeout took %s", elapsed)
+	}
+}
+
+func TestCheckAddress(t *testing.T) {
+	tests := []struct {
+		ip      string
+		allowed bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", true},
+		{"64:ff9b::5db8:d822", true}, // NAT64 of public IPv4
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"10.0.0.1", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"0.1.2.3", false},
+		{"100.64.0.1", false},
+		{"100.127.255.255", false},
+		{"192.0.0.8", false},
+		{"198.18.0.1", false},
+		{"198.19.255.255", false},
+		{"255.255.255.255", false},
+		{"fc00::1", false},
+		{"fe80::1", false},
+		{"::ffff:10.0.0.1", false},
+		{"64:ff9b::7f00:1", false}, // NAT64 of 127.0.0.1
+		{"64:ff9b::c0a8:101", false},
+	}
+	for _, test := range tests {
+		err := checkAddress(Options{}, "host", net.ParseIP(test.ip))
+		if (err == nil) != test.allowed {
+			t.Errorf("checkAddress(%s) = %v, want allowed %t", test.ip, err, test.allowed)
+		}
+	}
+}
+
+func TestMatchRule(t *testing.T) {
+	tests := []struct {
+		rule  string
+		host  string
+		ip    string
+		match bool
+	}{
+		{"example.com", "example.com", "93.184.216.34", true},
+		{"example.com", "www.example.com", "93.184.216.34", false},
+		{"*.example.com", "www.example.com", "93.184.216.34", true},
+		{"*.example.com", "example.com", "93.184.216.34", true},
+		{"*.example.com", "badexample.com", "93.184.216.34", false},
+		{"EXAMPLE.com", "Example.COM", "93.184.216.34", true},
+		{"10.0.0.0/8", "intranet", "10.2.3.4", true},
+		{"10.0.0.0/8", "intranet", "11.2.3.4", false},
+		{"10.2.3.4", "intranet", "10.2.3.4", true},
+	}
+	for _, test := range tests {
+		if got := matchRule(test.rule, test.host, net.ParseIP(test.ip)); got != test.match {
+			t.Errorf("matchRule(%q, %q, %s) = %t, want %t", test.rule, test.host, test.ip, got, test.match)
+		}
+	}
+}
@@ -0,0 +1,388 @@
+package clipper
+
+import (
+	"math"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// minParagraph - shorter paragraphs are not counted for content score
+const minParagraph = 25
+
+var (
+	// Class or ID of blocks which are not content most likely
+	reUnlikely = regexp.MustCompile(`(?i)banner|breadcrumb|combx|comment|community|cookie|disqus|extra|footer|gdpr|header|legends|menu|related|remark|replies|rss|shoutbox|sidebar|skyscraper|social|sponsor|ad-break|agegate|pagination|pager|popup|promo|share|subscribe|newsletter`)
+
+	// Class or ID of blocks which could be content anyway
+	reMaybe = regexp.MustCompile(`(?i)and|article|body|column|content|main|shadow`)
+
+	// Class or ID which increases or decreases score
+	rePositive = regexp.MustCompile(`(?i)article|body|content|entry|hentry|h-entry|main|page|post|text|blog|story`)
+	reNegative = regexp.MustCompile(`(?i)hidden|^hid$|\shid$|\shid\s|^hid\s|banner|combx|comment|com-|contact|foot|footer|footnote|gdpr|masthead|media|meta|outbrain|promo|related|scroll|share|shoutbox|sidebar|skyscraper|sponsor|shopping|tags|tool|widget`)
+
+	// Hidden by inline style
+	reHidden = regexp.MustCompile(`(?i)display\s*:\s*none|visibility\s*:\s*hidden`)
+)
+
+// removedElements - elements which are never part of article
+var removedElements = map[atom.Atom]bool{
+	atom.Script: true, atom.Style: true, atom.Noscript: true, atom.Template: true,
+	atom.Iframe: true, atom.Object: true, atom.Embed: true, atom.Canvas: true, atom.Svg: true,
+	atom.Form: true, atom.Button: true, atom.Input: true, atom.Select: true, atom.Textarea: true,
+	atom.Nav: true, atom.Aside: true, atom.Footer: true, atom.Dialog: true,
+}
+
+// article - readable part of page
+type article struct {
+	Title   string
+	Content *html.Node
+	Favicon string // absolute URL of icon
+}
+
+// extract - find readable content of page, its title and icon (links are made absolute)
+func extract(doc *html.Node, base *url.URL) article {
+	var result article
+
+	// Metadata from <head>
+	var ogTitle, title, icon string
+	walk(doc, func(n *html.Node) bool {
+		switch n.DataAtom {
+		case atom.Base:
+			if href, err := base.Parse(attr(n, "href")); err == nil && attr(n, "href") != "" {
+				base = href
+			}
+		case atom.Title:
+			if title == "" {
+				title = normalizeSpaces(textContent(n))
+			}
+		case atom.Meta:
+			if strings.EqualFold(attr(n, "property"), "og:title") && ogTitle == "" {
+				ogTitle = normalizeSpaces(attr(n, "content"))
+			}
+		case atom.Link:
+			rel := strings.Fields(strings.ToLower(attr(n, "rel")))
+			for _, value := range rel {
+				if value == "icon" && icon == "" {
+					icon = attr(n, "href")
+				}
+			}
+		case atom.Body:
+			return false
+		}
+		return true
+	})
+	result.Title = ogTitle
+	if result.Title == "" {
+		result.Title = title
+	}
+
+	// Icon of page or default one
+	if icon == "" {
+		icon = "/favicon.ico"
+	}
+	if iconURL, err := base.Parse(icon); err == nil && (iconURL.Scheme == "http" || iconURL.Scheme == "https") {
+		result.Favicon = iconURL.String()
+	}
+
+	body := findElement(doc, atom.Body)
+	if body == nil {
+		body = doc
+	}
+
+	absoluteLinks(body, base)
+	clean(body)
+	result.Content = topCandidate(body)
+	cleanConditionally(result.Content)
+
+	// Title is kept in note title, so the same heading is removed
+	if h1 := findElement(result.Content, atom.H1); h1 != nil && result.Title != "" &&
+		strings.EqualFold(normalizeSpaces(textContent(h1)), result.Title) {
+		h1.Parent.RemoveChild(h1)
+	}
+	if result.Title == "" {
+		if h1 := findElement(body, atom.H1); h1 != nil {
+			result.Title = normalizeSpaces(textContent(h1))
+		}
+	}
+
+	return result
+}
+
+// absoluteLinks - make links and images absolute, lazy images get real source
+func absoluteLinks(root *html.Node, base *url.URL) {
+	walk(root, func(n *html.Node) bool {
+		switch n.DataAtom {
+		case atom.A:
+			resolveAttr(n, "href", base)
+		case atom.Img:
+			for _, lazy := range []string{"data-src", "data-original", "data-lazy-src"} {
+				if value := attr(n, lazy); value != "" {
+					setAttr(n, "src", value)
+					break
+				}
+			}
+			resolveAttr(n, "src", base)
+		}
+		return true
+	})
+}
+
+// resolveAttr - make URL in attribute absolute (script links are removed)
+func resolveAttr(n *html.Node, name string, base *url.URL) {
+	value := strings.TrimSpace(attr(n, name))
+	if value == "" || strings.HasPrefix(value, "#") {
+		return
+	}
+
+	resolved, err := base.Parse(value)
+	if err != nil || (resolved.Scheme != "http" && resolved.Scheme != "https" && resolved.Scheme != "mailto" && resolved.Scheme != "data") {
+		setAttr(n, name, "")
+		return
+	}
+	setAttr(n, name, resolved.String())
+}
+
+// clean - remove elements which are never content: scripts, forms, navigation, hidden and unlikely blocks
+func clean(root *html.Node) {
+	var remove []*html.Node
+	walk(root, func(n *html.Node) bool {
+		if n == root {
+			return true
+		}
+		if removedElements[n.DataAtom] || isHidden(n) {
+			remove = append(remove, n)
+			return false
+		}
+
+		// Unlikely blocks by class and ID (but not whole article)
+		match := attr(n, "class") + " " + attr(n, "id")
+		if n.DataAtom != atom.Body && n.DataAtom != atom.A && n.DataAtom != atom.Article && n.DataAtom != atom.Main &&
+			reUnlikely.MatchString(match) && !reMaybe.MatchString(match) {
+			remove = append(remove, n)
+			return false
+		}
+		return true
+	})
+
+	for _, n := range remove {
+		n.Parent.RemoveChild(n)
+	}
+}
+
+// isHidden - element is not visible
+func isHidden(n *html.Node) bool {
+	return hasAttr(n, "hidden") || strings.EqualFold(attr(n, "aria-hidden"), "true") || reHidden.MatchString(attr(n, "style"))
+}
+
+// topCandidate - element with the best content score
+// Paragraphs give points to their parent and grandparent, score is reduced by density of links
+func topCandidate(root *html.Node) *html.Node {
+	scores := map[*html.Node]float64{}
+	var candidates []*html.Node
+
+	addScore := func(n *html.Node, score float64) {
+		if n == nil || n.Type != html.ElementNode {
+			return
+		}
+		if _, ok := scores[n]; !ok {
+			scores[n] = initialScore(n)
+			candidates = append(candidates, n)
+		}
+		scores[n] += score
+	}
+
+	walk(root, func(n *html.Node) bool {
+		switch n.DataAtom {
+		case atom.P, atom.Pre, atom.Td, atom.Blockquote:
+		default:
+			return true
+		}
+
+		text := normalizeSpaces(textContent(n))
+		if len(text) < minParagraph {
+			return false
+		}
+
+		score := 1 + float64(strings.Count(text, ",")) + math.Min(float64(len(text))/100, 3)
+		addScore(n.Parent, score)
+		if n.Parent != nil {
+			addScore(n.Parent.Parent, score/2)
+		}
+		return false
+	})
+
+	var top *html.Node
+	best := 0.0
+	for _, n := range candidates {
+		score := scores[n] * (1 - linkDensity(n))
+		if top == nil || score > best {
+			top, best = n, score
+		}
+	}
+	if top == nil {
+		return root
+	}
+
+	// Article is split to several blocks: take their common parent
+	for top.Parent != nil && top.Parent != root && top.Parent.Type == html.ElementNode {
+		parentScore, ok := scores[top.Parent]
+		if !ok || parentScore*(1-linkDensity(top.Parent)) < best*0.75 {
+			break
+		}
+		top = top.Parent
+	}
+
+	return top
+}
+
+// initialScore - score of element by tag, class and ID
+func initialScore(n *html.Node) float64 {
+	score := 0.0
+	switch n.DataAtom {
+	case atom.Article, atom.Main:
+		score += 10
+	case atom.Div, atom.Section:
+		score += 5
+	case atom.Pre, atom.Td, atom.Blockquote:
+		score += 3
+	case atom.Address, atom.Ol, atom.Ul, atom.Dl, atom.Dd, atom.Dt, atom.Li:
+		score -= 3
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6, atom.Th:
+		score -= 5
+	}
+
+	for _, value := range []string{attr(n, "class"), attr(n, "id")} {
+		if value == "" {
+			continue
+		}
+		if reNegative.MatchString(value) {
+			score -= 25
+		}
+		if rePositive.MatchString(value) {
+			score += 25
+		}
+	}
+
+	return score
+}
+
+// cleanConditionally - remove blocks inside content which are mostly links (related posts, tags, share buttons)
+func cleanConditionally(root *html.Node) {
+	var remove []*html.Node
+	walk(root, func(n *html.Node) bool {
+		if n == root {
+			return true
+		}
+		switch n.DataAtom {
+		case atom.Div, atom.Section, atom.Ul, atom.Ol, atom.Table, atom.Header:
+		default:
+			return true
+		}
+
+		text := normalizeSpaces(textContent(n))
+		if (linkDensity(n) > 0.5 && len(text) < 300) || (text == "" && findElement(n, atom.Img) == nil) {
+			remove = append(remove, n)
+			return false
+		}
+		return true
+	})
+
+	for _, n := range remove {
+		n.Parent.RemoveChild(n)
+	}
+}
+
+// linkDensity - part of text inside links
+func linkDensity(n *html.Node) float64 {
+	length := len(normalizeSpaces(textContent(n)))
+	if length == 0 {
+		return 0
+	}
+
+	links := 0
+	walk(n, func(child *html.Node) bool {
+		if child.DataAtom == atom.A {
+			links += len(normalizeSpaces(textContent(child)))
+			return false
+		}
+		return true
+	})
+
+	return float64(links) / float64(length)
+}
+
+// walk - visit elements in document order, visit returns false to skip children
+func walk(n *html.Node, visit func(n *html.Node) bool) {
+	if n.Type == html.ElementNode && !visit(n) {
+		return
+	}
+	for child := n.FirstChild; child != nil; {
+		next := child.NextSibling
+		walk(child, visit)
+		child = next
+	}
+}
+
+// findElement - first element of type inside node
+func findElement(n *html.Node, a atom.Atom) *html.Node {
+	var found *html.Node
+	walk(n, func(child *html.Node) bool {
+		if found == nil && child.DataAtom == a {
+			found = child
+		}
+		return found == nil
+	})
+	return found
+}
+
+// textContent - all text inside node
+func textContent(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	var b strings.Builder
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		b.WriteString(textContent(child))
+	}
+	return b.String()
+}
+
+// normalizeSpaces - trim text and collapse whitespaces
+func normalizeSpaces(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// attr - get attribute value
+func attr(n *html.Node, name string) string {
+	for _, a := range n.Attr {
+		if strings.EqualFold(a.Key, name) {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// hasAttr - check attribute exists
+func hasAttr(n *html.Node, name string) bool {
+	for _, a := range n.Attr {
+		if strings.EqualFold(a.Key, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// setAttr - set attribute value (added if missing)
+func setAttr(n *html.Node, name string, value string) {
+	for i, a := range n.Attr {
+		if strings.EqualFold(a.Key, name) {
+			n.Attr[i].Val = value
+			return
+		}
+	}
+	n.Attr = append(n.Attr, html.Attribute{Key: name, Val: value})
+}
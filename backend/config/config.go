@@ -6,6 +6,9 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
 )
 
 // Config - main struct for command-line options
@@ -13,6 +16,55 @@ type Config struct {
 	Host     string
 	Port     string
 	Database string
+	User     string
+
+	// HTTPS
+	TLSCert       string
+	TLSKey        string
+	TLSSelfSigned bool
+	RedirectPort  string
+
+	// Origins allowed for cross-origin requests (CORS)
+	CORSOrigins []string
+
+	// IFRAME notes isolation
+	IFramePort       string
+	IFrameOrigin     string
+	IFrameScripts    []string
+	IFrameConnect    []string
+	IFrameSameOrigin bool
+
+	// Days to keep audit log records (0 = forever)
+	AuditRetention int
+
+	// Logging
+	LogLevel    string
+	LogFormat   string
+	LogFile     string
+	LogMaxSize  int
+	LogMaxFiles int
+	SQLTrace    bool
+
+	// Prometheus metrics
+	Metrics        bool
+	MetricsAddress string
+
+	// Web clipper for URL notes
+	ClipperAuto    bool
+	ClipperTimeout time.Duration
+	ClipperMaxSize int64
+	ClipperAllow   []string
+	ClipperDeny    []string
+
+	// Show effective config and exit
+	PrintConfig bool
+
+	// Subcommand (serve by default) and its arguments
+	Command string
+	Args    []string
+
+	// Directory for app data (~/.tetrad)
+	DataDir string
 }
 
 // AppConfig - config values
@@ -30,7 +82,8 @@ func Load() {
 	// Defaults
 	defaultHost := "localhost"
 	defaultPort := "8888"
-	defaultDB := filepath.Join(homeDir, ".tetrad", "database.db")
+	dataDir := filepath.Join(homeDir, ".tetrad")
+	defaultDB := filepath.Join(dataDir, "database.db")
 
 	// Host and port
 	host := flag.String("host", defaultHost, "Host to bind the server to")
@@ -39,9 +92,68 @@ func Load() {
 	// Database
 	database := flag.String("database", defaultDB, "Path to the SQLite database file")
 
+	// Authentication
+	setPassword := flag.Bool("set-password", false, "Set password for web access user and exit, same as set-password command")
+	user := flag.String("user", "admin", "User name for set-password command")
+
+	// HTTPS
+	tlsCert := flag.String("tls-cert", "", "Path to TLS certificate file (PEM), enables HTTPS")
+	tlsKey := flag.String("tls-key", "", "Path to TLS private key file (PEM)")
+	tlsSelfSigned := flag.Bool("tls-self-signed", false, "Enable HTTPS with self-signed certificate (generated once and kept in ~/.tetrad)")
+	redirectPort := flag.String("redirect-port", "", "Port for HTTP listener which redirects to HTTPS (empty = disabled)")
+
+	// CORS
+	corsOrigins := flag.String("cors-origins", "", "Comma-separated origins allowed for cross-origin requests, eg https://example.com (empty = disabled)")
+
+	// IFRAME notes
+	iframePort := flag.String("iframe-port", "", "Port for separate origin which serves IFRAME notes (empty = same origin)")
+	iframeOrigin := flag.String("iframe-origin", "", "Public origin of IFRAME notes, eg https://iframe.example.com (default: same host with --iframe-port)")
+	iframeScripts := flag.String("iframe-scripts", "inline https:", "Script sources allowed in IFRAME notes: none, inline, eval, self or origins")
+	iframeConnect := flag.String("iframe-connect", "none", "Network (fetch, XHR, WebSocket) allowed in IFRAME notes: none, self, * or origins")
+	iframeSameOrigin := flag.Bool("iframe-same-origin", false, "Give IFRAME notes own origin (cookies, storage), just with --iframe-port or --iframe-origin")
+
+	// Audit log
+	auditRetention := flag.Int("audit-retention", 365, "Days to keep audit log records (0 = keep forever)")
+
+	// Logging
+	logLevel := flag.String("log-level", "info", "Log level: debug, info, warn or error")
+	logFormat := flag.String("log-format", "text", "Log format: text or json")
+	logFile := flag.String("log-file", "", "Write log to file too, relative path is inside ~/.tetrad, eg tetrad.log (empty = console only)")
+	logMaxSize := flag.Int("log-max-size", 10, "Rotate log file when it is bigger (MB)")
+	logMaxFiles := flag.Int("log-max-files", 5, "Count of rotated log files to keep")
+	sqlTrace := flag.Bool("sql-trace", false, "Log all SQL queries (could be switched at runtime)")
+
+	// Metrics
+	metrics := flag.Bool("metrics", false, "Expose Prometheus metrics on /metrics of main server (authentication is required)")
+	metricsAddress := flag.String("metrics-address", "", "Expose Prometheus metrics on separate address without authentication, eg localhost:9100 (empty = disabled)")
+
+	// Web clipper
+	clipperAuto := flag.Bool("clipper-auto", true, "Archive page of URL note when the note is created")
+	clipperTimeout := flag.Int("clipper-timeout", 20, "Timeout for fetching page by web clipper (seconds)")
+	clipperMaxSize := flag.Int("clipper-max-size", 5, "Max size of page fetched by web clipper (MB)")
+	clipperAllow := flag.String("clipper-allow", "", "Comma-separated hosts, domains (*.example.com) or networks (10.0.0.0/8) which web clipper could fetch (empty = any public host)")
+	clipperDeny := flag.String("clipper-deny", "", "Comma-separated hosts, domains or networks which web clipper must not fetch")
+
+	// Config file
+	configPath := flag.String("config", "", "Path to TOML or YAML config file (default: ~/.tetrad/config, config.toml or config.yaml if exists)")
+	printConfig := flag.Bool("print-config", false, "Print effective config values with their sources and exit")
+
 	// Parse data
 	flag.Parse()
 
+	// Values which are not given on command line: environment (TETRAD_*) and config file
+	if err := applySources(flag.CommandLine, dataDir, *configPath); err != nil {
+		log.Fatal(err)
+	}
+
+	if (*tlsCert == "") != (*tlsKey == "") {
+		log.Fatal("both --tls-cert and --tls-key are required")
+	}
+
+	if *clipperTimeout <= 0 || *clipperMaxSize <= 0 {
+		log.Fatal("--clipper-timeout and --clipper-max-size must be positive")
+	}
+
 	// Create database directory if it doesn't exist
 	dir := filepath.Dir(*database)
 	err = os.MkdirAll(dir, 0700)
@@ -55,6 +167,53 @@ func Load() {
 		Host:     *host,
 		Port:     *port,
 		Database: *database,
+		User:     *user,
+
+		TLSCert:       *tlsCert,
+		TLSKey:        *tlsKey,
+		TLSSelfSigned: *tlsSelfSigned,
+		RedirectPort:  *redirectPort,
+
+		CORSOrigins: splitList(*corsOrigins),
+
+		IFramePort:       *iframePort,
+		IFrameOrigin:     strings.TrimRight(*iframeOrigin, "/"),
+		IFrameScripts:    splitFields(*iframeScripts),
+		IFrameConnect:    splitFields(*iframeConnect),
+		IFrameSameOrigin: *iframeSameOrigin,
+
+		AuditRetention: *auditRetention,
+
+		LogLevel:    *logLevel,
+		LogFormat:   *logFormat,
+		LogFile:     resolvePath(dataDir, *logFile),
+		LogMaxSize:  *logMaxSize,
+		LogMaxFiles: *logMaxFiles,
+		SQLTrace:    *sqlTrace,
+
+		Metrics:        *metrics,
+		MetricsAddress: *metricsAddress,
+
+		ClipperAuto:    *clipperAuto,
+		ClipperTimeout: time.Duration(*clipperTimeout) * time.Second,
+		ClipperMaxSize: int64(*clipperMaxSize) << 20,
+		ClipperAllow:   splitList(*clipperAllow),
+		ClipperDeny:    splitList(*clipperDeny),
+
+		PrintConfig: *printConfig,
+
+		Command: "serve",
+
+		DataDir: dataDir,
+	}
+
+	// Flags are given before subcommand: tetrad [flags] <command> [arguments]
+	if args := flag.Args(); len(args) > 0 {
+		AppConfig.Command = args[0]
+		AppConfig.Args = args[1:]
+	}
+	if *setPassword {
+		AppConfig.Command = "set-password"
 	}
 }
 
@@ -69,3 +228,49 @@ func GetLocalAddress() string {
 
 	return fmt.Sprintf("%s:%s", host, port)
 }
+
+// IsTLS - check HTTPS is enabled
+func IsTLS() bool {
+	return AppConfig.TLSCert != "" || AppConfig.TLSSelfSigned
+}
+
+// IsAllowedOrigin - check origin is in CORS allowlist ("*" allows any origin)
+func IsAllowedOrigin(origin string) bool {
+	for _, allowed := range AppConfig.CORSOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolvePath - relative path => path inside app data directory (empty path is kept)
+func resolvePath(dataDir string, path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dataDir, path)
+}
+
+// splitList - split comma-separated value, skip empty items
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimRight(strings.TrimSpace(item), "/"); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// splitFields - split value separated by spaces or commas (lists from config file are joined with commas)
+func splitFields(value string) []string {
+	return strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || unicode.IsSpace(r)
+	})
+}
+
+// HasIFrameOrigin - check IFRAME notes are served from separate origin
+func HasIFrameOrigin() bool {
+	return AppConfig.IFramePort != "" || AppConfig.IFrameOrigin != ""
+}
@@ -0,0 +1,230 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Sources of config values, from highest priority to lowest
+const (
+	SourceFlag    = "flag"
+	SourceEnv     = "env"
+	SourceFile    = "file"
+	SourceDefault = "default"
+)
+
+// envPrefix - prefix of environment variables (TETRAD_PORT => --port)
+const envPrefix = "TETRAD_"
+
+// defaultConfigNames - config files which are searched in app data directory (first found is used)
+var defaultConfigNames = []string{"config", "config.toml", "config.yaml", "config.yml"}
+
+// actionFlags - flags which are set just on command line (not by env or config file)
+var actionFlags = map[string]bool{
+	"config":       true,
+	"print-config": true,
+	"set-password": true,
+}
+
+var (
+	// Flag name => source of its value
+	valueSources = make(map[string]string)
+
+	// Loaded config file (empty if there is no file)
+	configFile string
+)
+
+// applySources - set flags which are not given on command line from env and config file
+// Precedence: flag > env > file > default
+func applySources(flags *flag.FlagSet, dataDir string, explicitPath string) error {
+	// Flags from command line
+	for name := range valueSources {
+		delete(valueSources, name)
+	}
+	flags.Visit(func(f *flag.Flag) {
+		valueSources[f.Name] = SourceFlag
+	})
+
+	// Config file: --config, TETRAD_CONFIG or default one
+	path := explicitPath
+	if path == "" {
+		path = os.Getenv(envPrefix + "CONFIG")
+		if path != "" {
+			flags.Set("config", path)
+			valueSources["config"] = SourceEnv
+		}
+	}
+	required := path != ""
+	if path == "" {
+		path = findConfigFile(dataDir)
+	}
+
+	fileValues := map[string]string{}
+	if path != "" {
+		values, err := readConfigFile(path)
+		if err != nil && (required || !errors.Is(err, os.ErrNotExist)) {
+			return err
+		}
+		if err == nil {
+			fileValues = values
+			configFile = path
+		}
+	}
+
+	// Unknown keys are typos most likely
+	var unknown []string
+	for name := range fileValues {
+		if f := flags.Lookup(name); f == nil || actionFlags[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("unknown options in %s: %s", path, strings.Join(unknown, ", "))
+	}
+
+	var err error
+	flags.VisitAll(func(f *flag.Flag) {
+		if err != nil || valueSources[f.Name] != "" {
+			return
+		}
+		valueSources[f.Name] = SourceDefault
+		if actionFlags[f.Name] {
+			return
+		}
+
+		if value, ok := os.LookupEnv(EnvName(f.Name)); ok {
+			if setErr := f.Value.Set(value); setErr != nil {
+				err = fmt.Errorf("invalid value %q for %s: %w", value, EnvName(f.Name), setErr)
+				return
+			}
+			valueSources[f.Name] = SourceEnv
+			return
+		}
+
+		if value, ok := fileValues[f.Name]; ok {
+			if setErr := f.Value.Set(value); setErr != nil {
+				err = fmt.Errorf("invalid value %q for %s in %s: %w", value, f.Name, path, setErr)
+				return
+			}
+			valueSources[f.Name] = SourceFile
+		}
+	})
+
+	return err
+}
+
+// EnvName - environment variable for flag (log-level => TETRAD_LOG_LEVEL)
+func EnvName(flagName string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
+
+// findConfigFile - get first existing default config file (empty if there is no one)
+func findConfigFile(dataDir string) string {
+	for _, name := range defaultConfigNames {
+		path := filepath.Join(dataDir, name)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+	}
+	return ""
+}
+
+// readConfigFile - read TOML or YAML file into flat map: option name => value
+// Format is detected by extension, file without extension could be any of them
+func readConfigFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	tree := map[string]any{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".toml":
+		err = toml.Unmarshal(data, &tree)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &tree)
+	default:
+		if tomlErr := toml.Unmarshal(data, &tree); tomlErr != nil {
+			tree = map[string]any{}
+			if yaml.Unmarshal(data, &tree) != nil {
+				err = tomlErr
+			}
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+
+	values := map[string]string{}
+	if err := flattenConfig(tree, "", values); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+
+	return values, nil
+}
+
+// flattenConfig - nested sections => option names ([log] level => log-level, tls_cert => tls-cert)
+func flattenConfig(tree map[string]any, prefix string, values map[string]string) error {
+	for key, value := range tree {
+		name := strings.ToLower(strings.ReplaceAll(key, "_", "-"))
+		if prefix != "" {
+			name = prefix + "-" + name
+		}
+
+		switch v := value.(type) {
+		case map[string]any:
+			if err := flattenConfig(v, name, values); err != nil {
+				return err
+			}
+		case []any:
+			items := make([]string, len(v))
+			for i, item := range v {
+				items[i] = fmt.Sprint(item)
+			}
+			values[name] = strings.Join(items, ",")
+		case string:
+			values[name] = v
+		case bool:
+			values[name] = strconv.FormatBool(v)
+		case int, int64, uint64, float64:
+			values[name] = fmt.Sprint(v)
+		case nil:
+			values[name] = ""
+		default:
+			return fmt.Errorf("unsupported value of %s", name)
+		}
+	}
+
+	return nil
+}
+
+// PrintConfig - print effective values of all options with their sources
+func PrintConfig(w io.Writer) {
+	if configFile != "" {
+		fmt.Fprintf(w, "Config file: %s\n\n", configFile)
+	} else {
+		fmt.Fprintf(w, "Config file: none\n\n")
+	}
+
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "OPTION\tVALUE\tSOURCE\tENV")
+	flag.CommandLine.VisitAll(func(f *flag.Flag) {
+		if actionFlags[f.Name] && f.Name != "config" {
+			return
+		}
+		fmt.Fprintf(table, "%s\t%q\t%s\t%s\n", f.Name, f.Value.String(), valueSources[f.Name], EnvName(f.Name))
+	})
+	table.Flush()
+}
@@ -0,0 +1,114 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// Diagnostics - SQLite and database file details
+type Diagnostics struct {
+	Path           string   `json:"path"`
+	Size           int64    `json:"size"`
+	WALSize        int64    `json:"walSize"`
+	SQLiteVersion  string   `json:"sqliteVersion"`
+	CompileOptions []string `json:"compileOptions"`
+	JournalMode    string   `json:"journalMode"`
+	PageSize       int64    `json:"pageSize"`
+	PageCount      int64    `json:"pageCount"`
+	FreelistCount  int64    `json:"freelistCount"`
+}
+
+// Ping - check database connection is alive
+func (n *Notebook) Ping(ctx context.Context) error {
+	sqlDB, err := n.orm.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
+
+// CheckWritable - check database file and its directory (journal) are writable and write lock could be taken
+func (n *Notebook) CheckWritable(ctx context.Context) error {
+	file, err := os.OpenFile(n.Path, os.O_WRONLY, 0)
+	if err != nil {
+		return fmt.Errorf("database file is not writable: %w", err)
+	}
+	file.Close()
+
+	probe, err := os.CreateTemp(filepath.Dir(n.Path), ".tetrad-probe-*")
+	if err != nil {
+		return fmt.Errorf("database directory is not writable: %w", err)
+	}
+	probe.Close()
+	os.Remove(probe.Name())
+
+	// Write lock without changes
+	sqlDB, err := n.orm.DB()
+	if err != nil {
+		return err
+	}
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "BEGIN IMMEDIATE"); err != nil {
+		// Other connection is writing right now, so database is writable
+		var sqliteErr sqlite3.Error
+		if errors.As(err, &sqliteErr) && sqliteErr.Code == sqlite3.ErrBusy {
+			return nil
+		}
+		return fmt.Errorf("failed to lock database for write: %w", err)
+	}
+	_, err = conn.ExecContext(context.Background(), "ROLLBACK")
+	return err
+}
+
+// GetDiagnostics - get SQLite version, options and page statistics
+func (n *Notebook) GetDiagnostics() (*Diagnostics, error) {
+	diagnostics := &Diagnostics{
+		Path:           n.Path,
+		Size:           n.Filesize(),
+		CompileOptions: []string{},
+	}
+	if info, err := os.Stat(n.Path + "-wal"); err == nil {
+		diagnostics.WALSize = info.Size()
+	}
+
+	queries := []struct {
+		sql   string
+		value any
+	}{
+		{"SELECT sqlite_version()", &diagnostics.SQLiteVersion},
+		{"PRAGMA journal_mode", &diagnostics.JournalMode},
+		{"PRAGMA page_size", &diagnostics.PageSize},
+		{"PRAGMA page_count", &diagnostics.PageCount},
+		{"PRAGMA freelist_count", &diagnostics.FreelistCount},
+	}
+	for _, query := range queries {
+		if err := n.orm.Raw(query.sql).Row().Scan(query.value); err != nil {
+			return nil, fmt.Errorf("%s: %w", query.sql, err)
+		}
+	}
+
+	rows, err := n.orm.Raw("PRAGMA compile_options").Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var option string
+		if err := rows.Scan(&option); err != nil {
+			return nil, err
+		}
+		diagnostics.CompileOptions = append(diagnostics.CompileOptions, option)
+	}
+
+	return diagnostics, rows.Err()
+}
@@ -3,37 +3,48 @@ package database
 import (
 	"database/sql"
 	"fmt"
-	"log"
 	"os"
 	"path/filepath"
 	"reflect"
 	"strings"
-	"time"
+	"sync"
 
 	"github.com/mattn/go-sqlite3"
 
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
 )
 
-var database *gorm.DB
+// driverName - sqlite driver with custom functions
+const driverName = "sqlite3_custom"
 
-var databasePath string = ""
+// registerDriver - driver is registered once for all notebooks
+var registerDriver sync.Once
 
-// GetORM - get ref to GORM
+// GetORM - get ref to GORM of main database (users, API tokens, audit log and notebooks registry)
 func GetORM() *gorm.DB {
-	return database
+	if defaultNotebook == nil {
+		return nil
+	}
+	return defaultNotebook.orm
 }
 
-// GetFilepath - get database filepath
+// GetFilepath - get main database filepath
 func GetFilepath() string {
-	return databasePath
+	if defaultNotebook == nil {
+		return ""
+	}
+	return defaultNotebook.Path
 }
 
-// GetFilesize - get database filesize
+// GetFilesize - get main database filesize
 func GetFilesize() int64 {
-	info, err := os.Stat(databasePath)
+	return fileSize(GetFilepath())
+}
+
+// fileSize - get file size (0 if file doesn't exist)
+func fileSize(path string) int64 {
+	info, err := os.Stat(path)
 	if err != nil {
 		return 0
 	}
@@ -41,69 +52,78 @@ func GetFilesize() int64 {
 	return info.Size()
 }
 
-// LoadDatabase - load database from file
+// LoadDatabase - load main database from file and open registered notebooks
 func LoadDatabase(path string) error {
 	filenameAbs, err := filepath.Abs(path)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
-	databasePath = filenameAbs
 
 	// If database file doesn't exist, extract from embedded static files
-	extractDatabase(databasePath)
+	extractDatabase(filenameAbs)
 
+	orm, err := openDatabase(filenameAbs, true)
+	if err != nil {
+		return err
+	}
+	defaultNotebook = &Notebook{Name: DefaultNotebook, Path: filenameAbs, orm: orm}
+
+	openRegisteredNotebooks()
+
+	return nil
+}
+
+// openDatabase - connect to database file and create missing tables
+func openDatabase(path string, samples bool) (*gorm.DB, error) {
 	// Prepare sqlite connection, including unicode extension
-	connection := fmt.Sprintf("file:%s?_pragma=foreign_keys(1)", databasePath)
+	connection := fmt.Sprintf("file:%s?_pragma=foreign_keys(1)", path)
 
-	driver := "sqlite3_custom"
-	sql.Register(driver, &sqlite3.SQLiteDriver{
-		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
-			return conn.RegisterFunc("custom_like", sqliteCustomLike, true)
-		},
+	registerDriver.Do(func() {
+		sql.Register(driverName, &sqlite3.SQLiteDriver{
+			ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+				return conn.RegisterFunc("custom_like", sqliteCustomLike, true)
+			},
+		})
 	})
 
 	// Connect to database
-	sqliteDb, err := sql.Open(driver, connection)
+	sqliteDb, err := sql.Open(driverName, connection)
 	if err != nil {
-		fmt.Println("Error opening database:", err)
-		return err
+		return nil, fmt.Errorf("error opening database: %w", err)
 	}
 
 	// GORM config
 	config := &gorm.Config{
-		Logger: logger.New(
-			log.New(os.Stdout, "\r\n", log.LstdFlags),
-			logger.Config{
-				SlowThreshold: time.Second,
-				LogLevel:      logger.Warn,
-			},
-		),
+		Logger: gormLogger{},
 	}
 
 	// Open database
-	database, err = gorm.Open(sqlite.Dialector{
+	orm, err := gorm.Open(sqlite.Dialector{
 		Conn: sqliteDb,
 	}, config)
 	if err != nil {
-		fmt.Println("Error connecting to database:", err)
-		return err
+		sqliteDb.Close()
+		return nil, fmt.Errorf("error connecting to database: %w", err)
 	}
 
 	// Auto create database with tables (not auto-migrate)
-	initDatabase(database)
+	if err := initDatabase(orm, samples); err != nil {
+		sqliteDb.Close()
+		return nil, err
+	}
 
-	return nil
+	return orm, nil
 }
 
-// Vacuum - compress sqlite database
-func Vacuum() (bool, error) {
-	err := database.Exec("VACUUM").Error
-	if err != nil {
-		log.Fatal("Error compressing (vacuum) database:", err)
-		return false, err
+// Close - close all notebooks and main database
+func Close() error {
+	closeNotebooks()
+
+	if defaultNotebook == nil {
+		return nil
 	}
 
-	return true, nil
+	return defaultNotebook.close()
 }
 
 // GetFields - get all fields (columns) for table (using gorm annotations)
@@ -121,7 +141,7 @@ func GetFields(model any, exclude []string) []string {
 	for i := range val.NumField() {
 		field := typ.Field(i)
 		column := field.Tag.Get("gorm")
-		if column == "" {
+		if column == "" || column == "-" {
 			continue
 		}
 
@@ -1,9 +1,11 @@
 package database
 
 import (
+	"fmt"
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/sondrus/tetrad/static"
 	"gorm.io/gorm"
@@ -41,8 +43,8 @@ func extractDatabase(filename string) {
 	return
 }
 
-// initDatabase - auto create tables
-func initDatabase(db *gorm.DB) {
+// initDatabase - auto create tables, sample notes are added just for demo (main) database
+func initDatabase(db *gorm.DB, samples bool) error {
 	tables := map[string][]string{
 		"icons": {
 			`CREATE TABLE IF NOT EXISTS "icons" (
@@ -81,6 +83,171 @@ func initDatabase(db *gorm.DB) {
 			`INSERT INTO notes VALUES(3,1,1,2,3,1,0,0,'URL','Markdown crash course','',
 				'https://www.youtube.com/embed/8owG83ozHYw',NULL,0,1744617724,1744617724);`,
 		},
+		"resources": {
+			`CREATE TABLE IF NOT EXISTS "resources" (
+				"ID"			INTEGER NOT NULL,
+				"NOTE_ID"		INTEGER NOT NULL DEFAULT 0,
+				"NAME"			TEXT NOT NULL,
+				"MIME"			TEXT NOT NULL,
+				"DATA"			BLOB NOT NULL,
+				"DATE_CREATED"	INTEGER NOT NULL,
+				PRIMARY KEY("ID" AUTOINCREMENT)
+			)`,
+			`CREATE INDEX IF NOT EXISTS "resources_note" ON "resources" ("NOTE_ID")`,
+		},
+		"users": {
+			`CREATE TABLE IF NOT EXISTS "users" (
+				"ID"			INTEGER NOT NULL,
+				"NAME"			TEXT NOT NULL UNIQUE,
+				"PASSWORD"		TEXT NOT NULL,
+				"ADMIN"			INTEGER NOT NULL DEFAULT 0,
+				"DATE_CREATED"	INTEGER NOT NULL,
+				"DATE_MODIFIED"	INTEGER NOT NULL,
+				PRIMARY KEY("ID" AUTOINCREMENT)
+			)`,
+		},
+		"permissions": {
+			`CREATE TABLE IF NOT EXISTS "permissions" (
+				"ID"			INTEGER NOT NULL,
+				"USER_ID"		INTEGER NOT NULL,
+				"NOTE_ID"		INTEGER NOT NULL,
+				"ROLE"			TEXT NOT NULL,
+				PRIMARY KEY("ID" AUTOINCREMENT),
+				UNIQUE("USER_ID", "NOTE_ID")
+			)`,
+		},
+		"reminders": {
+			`CREATE TABLE IF NOT EXISTS "reminders" (
+				"ID"			INTEGER NOT NULL,
+				"NOTE_ID"		INTEGER NOT NULL,
+				"USER_ID"		INTEGER NOT NULL DEFAULT 0,
+				"MESSAGE"		TEXT NOT NULL DEFAULT '',
+				"RECURRENCE"	TEXT NOT NULL DEFAULT '',
+				"STATE"			TEXT NOT NULL DEFAULT 'pending',
+				"DATE_START"	INTEGER NOT NULL,
+				"DATE_NEXT"		INTEGER NOT NULL,
+				"DATE_FIRED"	INTEGER NOT NULL DEFAULT 0,
+				PRIMARY KEY("ID" AUTOINCREMENT)
+			)`,
+			`CREATE INDEX IF NOT EXISTS "reminders_next" ON "reminders" ("STATE", "DATE_NEXT")`,
+		},
+		"shares": {
+			`CREATE TABLE IF NOT EXISTS "shares" (
+				"ID"				INTEGER NOT NULL,
+				"NOTE_ID"			INTEGER NOT NULL,
+				"USER_ID"			INTEGER NOT NULL DEFAULT 0,
+				"HASH"				TEXT NOT NULL UNIQUE,
+				"PREFIX"			TEXT NOT NULL,
+				"SUBTREE"			INTEGER NOT NULL DEFAULT 0,
+				"PASSWORD"			TEXT NOT NULL DEFAULT '',
+				"ACCESS_COUNT"		INTEGER NOT NULL DEFAULT 0,
+				"DATE_CREATED"		INTEGER NOT NULL,
+				"DATE_EXPIRES"		INTEGER NOT NULL DEFAULT 0,
+				"DATE_LAST_ACCESS"	INTEGER NOT NULL DEFAULT 0,
+				PRIMARY KEY("ID" AUTOINCREMENT)
+			)`,
+		},
+		"tasks": {
+			`CREATE TABLE IF NOT EXISTS "tasks" (
+				"ID"			INTEGER NOT NULL,
+				"NOTE_ID"		INTEGER NOT NULL,
+				"LINE"			INTEGER NOT NULL,
+				"TEXT"			TEXT NOT NULL,
+				"DONE"			INTEGER NOT NULL DEFAULT 0,
+				"DUE"			TEXT NOT NULL DEFAULT '',
+				"PRIORITY"		INTEGER NOT NULL DEFAULT 0,
+				PRIMARY KEY("ID" AUTOINCREMENT)
+			)`,
+			`CREATE INDEX IF NOT EXISTS "tasks_note" ON "tasks" ("NOTE_ID")`,
+		},
+		"index_versions": {
+			`CREATE TABLE IF NOT EXISTS "index_versions" (
+				"NAME"			TEXT NOT NULL,
+				"VERSION"		INTEGER NOT NULL,
+				"DATE_MODIFIED"	INTEGER NOT NULL,
+				PRIMARY KEY("NAME")
+			)`,
+		},
+		"tokens": {
+			`CREATE TABLE IF NOT EXISTS "tokens" (
+				"ID"				INTEGER NOT NULL,
+				"USER_ID"			INTEGER NOT NULL,
+				"NAME"				TEXT NOT NULL,
+				"HASH"				TEXT NOT NULL UNIQUE,
+				"PREFIX"			TEXT NOT NULL,
+				"SCOPE"				TEXT NOT NULL,
+				"DATE_CREATED"		INTEGER NOT NULL,
+				"DATE_EXPIRES"		INTEGER NOT NULL DEFAULT 0,
+				"DATE_LAST_USED"	INTEGER NOT NULL DEFAULT 0,
+				PRIMARY KEY("ID" AUTOINCREMENT)
+			)`,
+			`CREATE INDEX IF NOT EXISTS "tokens_user" ON "tokens" ("USER_ID")`,
+		},
+		"vaults": {
+			`CREATE TABLE IF NOT EXISTS "vaults" (
+				"ID"			INTEGER NOT NULL,
+				"NOTE_ID"		INTEGER NOT NULL UNIQUE,
+				"KDF"			TEXT NOT NULL,
+				"SALT"			TEXT NOT NULL,
+				"CHECK"			TEXT NOT NULL,
+				"DATE_CREATED"	INTEGER NOT NULL,
+				PRIMARY KEY("ID" AUTOINCREMENT)
+			)`,
+		},
+		"bridge_data": {
+			`CREATE TABLE IF NOT EXISTS "bridge_data" (
+				"NOTE_ID"		INTEGER NOT NULL,
+				"DATA"			TEXT NOT NULL,
+				"DATE_MODIFIED"	INTEGER NOT NULL,
+				PRIMARY KEY("NOTE_ID")
+			)`,
+		},
+		"bridge_grants": {
+			`CREATE TABLE IF NOT EXISTS "bridge_grants" (
+				"ID"			INTEGER NOT NULL,
+				"APP_ID"		INTEGER NOT NULL,
+				"NOTE_ID"		INTEGER NOT NULL,
+				"DATE_CREATED"	INTEGER NOT NULL,
+				PRIMARY KEY("ID" AUTOINCREMENT),
+				UNIQUE("APP_ID", "NOTE_ID")
+			)`,
+		},
+		"audit_log": {
+			`CREATE TABLE IF NOT EXISTS "audit_log" (
+				"ID"			INTEGER NOT NULL,
+				"DATE"			INTEGER NOT NULL,
+				"OPERATION"		TEXT NOT NULL,
+				"NOTE_IDS"		TEXT NOT NULL DEFAULT '',
+				"FIELDS"		TEXT NOT NULL DEFAULT '',
+				"DETAILS"		TEXT NOT NULL DEFAULT '',
+				"USER_ID"		INTEGER NOT NULL DEFAULT 0,
+				"USER_NAME"		TEXT NOT NULL DEFAULT '',
+				"TOKEN_ID"		INTEGER NOT NULL DEFAULT 0,
+				"ADDRESS"		TEXT NOT NULL DEFAULT '',
+				"NOTEBOOK"		TEXT NOT NULL DEFAULT '',
+				PRIMARY KEY("ID" AUTOINCREMENT)
+			)`,
+			`CREATE INDEX IF NOT EXISTS "audit_log_date" ON "audit_log" ("DATE")`,
+		},
+		"notebooks": {
+			`CREATE TABLE IF NOT EXISTS "notebooks" (
+				"ID"			INTEGER NOT NULL,
+				"NAME"			TEXT NOT NULL UNIQUE,
+				"PATH"			TEXT NOT NULL,
+				"OPEN"			INTEGER NOT NULL DEFAULT 1,
+				"DATE_CREATED"	INTEGER NOT NULL,
+				PRIMARY KEY("ID" AUTOINCREMENT)
+			)`,
+		},
+		"user_options": {
+			`CREATE TABLE IF NOT EXISTS "user_options" (
+				"USER_ID"	INTEGER NOT NULL,
+				"NAME"		TEXT NOT NULL,
+				"VALUE"		TEXT NOT NULL,
+				"TYPE"		TEXT NOT NULL,
+				PRIMARY KEY("USER_ID", "NAME")
+			)`,
+		},
 		"options": {
 			`CREATE TABLE IF NOT EXISTS "options" (
 				"NAME"	TEXT NOT NULL UNIQUE,
@@ -98,9 +265,39 @@ func initDatabase(db *gorm.DB) {
 		}
 
 		for _, query := range sql {
+			if !samples && strings.HasPrefix(query, "INSERT") {
+				continue
+			}
 			if err := db.Exec(query).Error; err != nil {
-				log.Fatalf("Error quering SQL: %v\nSQL: %s", err, query)
+				return fmt.Errorf("error quering SQL: %w\nSQL: %s", err, query)
 			}
 		}
 	}
+
+	// Columns which are added after release of table
+	columns := []struct {
+		table      string
+		column     string
+		definition string
+	}{
+		{"audit_log", "NOTEBOOK", `TEXT NOT NULL DEFAULT ''`},
+		{"notes", "TEMPLATE", `INTEGER NOT NULL DEFAULT 0`},
+		{"notes", "FAVICON", `INTEGER NOT NULL DEFAULT 0`},
+		{"notes", "DATE_ARCHIVED", `INTEGER NOT NULL DEFAULT 0`},
+	}
+	for _, c := range columns {
+		var count int64
+		if err := db.Raw("SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?", c.table, c.column).Scan(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			continue
+		}
+		query := fmt.Sprintf(`ALTER TABLE "%s" ADD COLUMN "%s" %s`, c.table, c.column, c.definition)
+		if err := db.Exec(query).Error; err != nil {
+			return fmt.Errorf("error quering SQL: %w\nSQL: %s", err, query)
+		}
+	}
+
+	return nil
 }
@@ -0,0 +1,56 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/sondrus/tetrad/logging"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// slowQueryThreshold - queries which are slower are logged as warnings
+const slowQueryThreshold = time.Second
+
+// gormLogger - GORM logger which writes to slog (all queries are logged if SQL tracing is enabled)
+type gormLogger struct{}
+
+// LogMode - level is controlled by slog, so it is ignored
+func (l gormLogger) LogMode(logger.LogLevel) logger.Interface {
+	return l
+}
+
+// Info - log GORM message with info level
+func (gormLogger) Info(ctx context.Context, message string, data ...any) {
+	slog.InfoContext(ctx, fmt.Sprintf(message, data...))
+}
+
+// Warn - log GORM message with warning level
+func (gormLogger) Warn(ctx context.Context, message string, data ...any) {
+	slog.WarnContext(ctx, fmt.Sprintf(message, data...))
+}
+
+// Error - log GORM message with error level
+func (gormLogger) Error(ctx context.Context, message string, data ...any) {
+	slog.ErrorContext(ctx, fmt.Sprintf(message, data...))
+}
+
+// Trace - log failed and slow queries (and all queries if SQL tracing is enabled)
+func (gormLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	elapsed := time.Since(begin)
+
+	switch {
+	case err != nil && !errors.Is(err, gorm.ErrRecordNotFound):
+		sql, rows := fc()
+		slog.ErrorContext(ctx, "SQL failed", "error", err, "sql", sql, "rows", rows, "duration", elapsed)
+	case elapsed > slowQueryThreshold:
+		sql, rows := fc()
+		slog.WarnContext(ctx, "SQL is slow", "sql", sql, "rows", rows, "duration", elapsed)
+	case logging.IsSQLTrace():
+		sql, rows := fc()
+		slog.InfoContext(ctx, "SQL", "sql", sql, "rows", rows, "duration", elapsed)
+	}
+}
@@ -0,0 +1,378 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sondrus/tetrad/models"
+	"gorm.io/gorm"
+)
+
+// DefaultNotebook - name of main database (--database)
+const DefaultNotebook = "default"
+
+var (
+	// ErrNotebookNotFound - notebook is not registered
+	ErrNotebookNotFound = errors.New("notebook not found")
+
+	// ErrNotebookClosed - notebook is registered, but its database is not opened
+	ErrNotebookClosed = errors.New("notebook is closed")
+)
+
+// reNotebookName - allowed notebook names (name is used in URL)
+var reNotebookName = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+// Notebook - opened database file with own notes, settings and resources
+type Notebook struct {
+	Name string
+	Path string
+	orm  *gorm.DB
+}
+
+var (
+	// Main database, it keeps users, API tokens, audit log and notebooks registry
+	defaultNotebook *Notebook
+
+	// Opened notebooks (except main database): name => notebook
+	notebooks      = make(map[string]*Notebook)
+	notebooksMutex sync.RWMutex
+)
+
+// ORM - get ref to GORM of notebook
+func (n *Notebook) ORM() *gorm.DB {
+	return n.orm
+}
+
+// Filesize - get database filesize
+func (n *Notebook) Filesize() int64 {
+	return fileSize(n.Path)
+}
+
+// IsDefault - check notebook is main database
+func (n *Notebook) IsDefault() bool {
+	return n.Name == DefaultNotebook
+}
+
+// Vacuum - compress sqlite database
+func (n *Notebook) Vacuum() error {
+	if err := n.orm.Exec("VACUUM").Error; err != nil {
+		slog.Error("Error compressing (vacuum) database", "notebook", n.Name, "error", err)
+		return err
+	}
+
+	return nil
+}
+
+// Backup - write consistent copy of database to new file (it must not exist)
+func (n *Notebook) Backup(path string) error {
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("file %s already exists", path)
+	}
+
+	if err := n.orm.Exec("VACUUM INTO ?", path).Error; err != nil {
+		slog.Error("Error writing database backup", "notebook", n.Name, "error", err)
+		return err
+	}
+
+	return nil
+}
+
+// IntegrityCheck - run SQLite integrity check, returns found problems (empty = database is fine)
+func (n *Notebook) IntegrityCheck() ([]string, error) {
+	var rows []string
+	if err := n.orm.Raw("PRAGMA integrity_check").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	if len(rows) == 1 && rows[0] == "ok" {
+		return nil, nil
+	}
+
+	return rows, nil
+}
+
+// close - write WAL contents to database file and close connection
+func (n *Notebook) close() error {
+	if err := n.orm.Exec("PRAGMA wal_checkpoint(TRUNCATE)").Error; err != nil {
+		slog.Error("Error checkpointing database", "notebook", n.Name, "error", err)
+	}
+
+	sqlDB, err := n.orm.DB()
+	if err != nil {
+		return err
+	}
+
+	return sqlDB.Close()
+}
+
+// IsValidNotebookName - check notebook name (lowercase letters, digits, "-" and "_")
+func IsValidNotebookName(name string) bool {
+	return reNotebookName.MatchString(name)
+}
+
+// GetNotebook - get opened notebook by name (empty name = main database)
+func GetNotebook(name string) (*Notebook, error) {
+	if name == "" || name == DefaultNotebook {
+		return defaultNotebook, nil
+	}
+
+	notebooksMutex.RLock()
+	notebook, ok := notebooks[name]
+	notebooksMutex.RUnlock()
+	if ok {
+		return notebook, nil
+	}
+
+	if _, err := findNotebook(name); err != nil {
+		return nil, err
+	}
+
+	return nil, ErrNotebookClosed
+}
+
+// OpenedNotebooks - main database and all opened notebooks (sorted by name)
+func OpenedNotebooks() []*Notebook {
+	notebooksMutex.RLock()
+	defer notebooksMutex.RUnlock()
+
+	list := make([]*Notebook, 0, len(notebooks)+1)
+	for _, notebook := range notebooks {
+		list = append(list, notebook)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+
+	return append([]*Notebook{defaultNotebook}, list...)
+}
+
+// findNotebook - get notebook from registry
+func findNotebook(name string) (*models.Notebook, error) {
+	var records []models.Notebook
+	if err := GetORM().Where("NAME = ?", name).Limit(1).Find(&records).Error; err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, ErrNotebookNotFound
+	}
+
+	return &records[0], nil
+}
+
+// GetNotebooks - get all registered notebooks, main database is the first
+func GetNotebooks() ([]models.Notebook, error) {
+	var records []models.Notebook
+	if err := GetORM().Order("NAME ASC").Find(&records).Error; err != nil {
+		return nil, err
+	}
+
+	list := []models.Notebook{{
+		Name:    DefaultNotebook,
+		Path:    GetFilepath(),
+		Open:    true,
+		Size:    GetFilesize(),
+		Default: true,
+	}}
+
+	notebooksMutex.RLock()
+	for _, record := range records {
+		_, record.Open = notebooks[record.Name]
+		record.Size = fileSize(record.Path)
+		list = append(list, record)
+	}
+	notebooksMutex.RUnlock()
+
+	return list, nil
+}
+
+// CreateNotebook - register database file as notebook and open it
+// New file is created just if create is set, otherwise the file must exist
+func CreateNotebook(name string, path string, create bool) (*models.Notebook, error) {
+	if !IsValidNotebookName(name) || name == DefaultNotebook {
+		return nil, fmt.Errorf("invalid notebook name %q", name)
+	}
+	if _, err := findNotebook(name); err == nil {
+		return nil, fmt.Errorf("notebook %s already exists", name)
+	}
+
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// Single file could not be opened as two notebooks
+	var used []models.Notebook
+	if err := GetORM().Where("PATH = ?", path).Limit(1).Find(&used).Error; err != nil {
+		return nil, err
+	}
+	if len(used) > 0 || path == GetFilepath() {
+		return nil, errors.New("database file is already used by other notebook")
+	}
+
+	_, statErr := os.Stat(path)
+	switch {
+	case create && statErr == nil:
+		return nil, errors.New("database file already exists")
+	case !create && statErr != nil:
+		return nil, fmt.Errorf("database file is not available: %w", statErr)
+	case create:
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			return nil, err
+		}
+	}
+
+	orm, err := openDatabase(path, false)
+	if err != nil {
+		if create {
+			os.Remove(path)
+		}
+		return nil, err
+	}
+	notebook := &Notebook{Name: name, Path: path, orm: orm}
+
+	record := models.Notebook{
+		Name:        name,
+		Path:        path,
+		Open:        true,
+		DateCreated: time.Now().Unix(),
+	}
+	if err := GetORM().Create(&record).Error; err != nil {
+		notebook.close()
+		if create {
+			os.Remove(path)
+		}
+		return nil, err
+	}
+
+	notebooksMutex.Lock()
+	notebooks[name] = notebook
+	notebooksMutex.Unlock()
+
+	record.Size = notebook.Filesize()
+
+	return &record, nil
+}
+
+// OpenNotebook - open registered notebook (it is opened again after restart)
+func OpenNotebook(name string) error {
+	record, err := findNotebook(name)
+	if err != nil {
+		return err
+	}
+
+	notebooksMutex.Lock()
+	defer notebooksMutex.Unlock()
+
+	if _, ok := notebooks[name]; ok {
+		return nil
+	}
+
+	if _, err := os.Stat(record.Path); err != nil {
+		return fmt.Errorf("database file is not available: %w", err)
+	}
+	orm, err := openDatabase(record.Path, false)
+	if err != nil {
+		return err
+	}
+	notebooks[name] = &Notebook{Name: name, Path: record.Path, orm: orm}
+
+	return GetORM().Model(record).Update("OPEN", true).Error
+}
+
+// CloseNotebook - close notebook database, it stays registered (and closed after restart)
+func CloseNotebook(name string) error {
+	if name == DefaultNotebook {
+		return errors.New("main database could not be closed")
+	}
+
+	record, err := findNotebook(name)
+	if err != nil {
+		return err
+	}
+
+	notebooksMutex.Lock()
+	notebook, ok := notebooks[name]
+	delete(notebooks, name)
+	notebooksMutex.Unlock()
+
+	if ok {
+		if err := notebook.close(); err != nil {
+			return err
+		}
+	}
+
+	return GetORM().Model(record).Update("OPEN", false).Error
+}
+
+// RemoveNotebook - close notebook and remove it from registry (database file is kept)
+func RemoveNotebook(name string) error {
+	if err := CloseNotebook(name); err != nil {
+		return err
+	}
+
+	return GetORM().Where("NAME = ?", name).Delete(&models.Notebook{}).Error
+}
+
+// openRegisteredNotebooks - open notebooks which were opened before restart
+// Unavailable files are skipped (eg, unmounted disk), they are tried again after next restart
+func openRegisteredNotebooks() {
+	var records []models.Notebook
+	if err := GetORM().Where("OPEN = ?", true).Order("NAME ASC").Find(&records).Error; err != nil {
+		slog.Error("Failed to load notebooks", "error", err)
+		return
+	}
+
+	notebooksMutex.Lock()
+	defer notebooksMutex.Unlock()
+
+	for _, record := range records {
+		if _, err := os.Stat(record.Path); err != nil {
+			slog.Warn("Notebook is not available", "notebook", record.Name, "error", err)
+			continue
+		}
+		orm, err := openDatabase(record.Path, false)
+		if err != nil {
+			slog.Warn("Failed to open notebook", "notebook", record.Name, "error", err)
+			continue
+		}
+		notebooks[record.Name] = &Notebook{Name: record.Name, Path: record.Path, orm: orm}
+	}
+}
+
+// closeNotebooks - close all opened notebooks (on shutdown)
+func closeNotebooks() {
+	notebooksMutex.Lock()
+	defer notebooksMutex.Unlock()
+
+	for name, notebook := range notebooks {
+		if err := notebook.close(); err != nil {
+			slog.Error("Failed to close notebook", "notebook", name, "error", err)
+		}
+	}
+	clear(notebooks)
+}
+
+// WithNotebook - put notebook of request to context
+func WithNotebook(ctx context.Context, notebook *Notebook) context.Context {
+	return context.WithValue(ctx, NotebookKey, notebook)
+}
+
+// GetContextNotebook - get notebook of request from context (main database if it is not selected)
+func GetContextNotebook(ctx context.Context) *Notebook {
+	if notebook, ok := ctx.Value(NotebookKey).(*Notebook); ok {
+		return notebook
+	}
+	return defaultNotebook
+}
+
+// GetContextORM - get ref to GORM of notebook from context
+func GetContextORM(ctx context.Context) *gorm.DB {
+	return GetContextNotebook(ctx).orm
+}
@@ -4,3 +4,15 @@ type contextKey string
 
 // IDKey - "ID" key for transfer any item ID in context
 const IDKey contextKey = "ID"
+
+// UserKey - key for transfer current user (*models.User) in context
+const UserKey contextKey = "USER"
+
+// TokenKey - key for transfer API token (*models.Token) in context, if request is authorized by token
+const TokenKey contextKey = "TOKEN"
+
+// KeyringKey - key for transfer unlocked vault keys (*services.Keyring) in context
+const KeyringKey contextKey = "KEYRING"
+
+// NotebookKey - key for transfer selected notebook (*database.Notebook) in context
+const NotebookKey contextKey = "NOTEBOOK"
@@ -0,0 +1,146 @@
+package events
+
+import (
+	"sync"
+	"time"
+)
+
+// Event types
+const (
+	Created   = "created"   // note is created
+	Updated   = "updated"   // note fields or contents are changed
+	Deleted   = "deleted"   // note with children is deleted
+	Moved     = "moved"     // note is moved to other parent
+	Expanded  = "expanded"  // notes are expanded or collapsed
+	Settings  = "settings"  // settings are saved
+	Reminder  = "reminder"  // reminder is fired
+	Reminders = "reminders" // reminders are created, changed or deleted
+	Reset     = "reset"     // events are lost (eg, after restart), client must reload everything
+)
+
+// historySize - count of events kept for resume after reconnect
+const historySize = 1000
+
+// subscriberBuffer - count of events queued for slow client
+const subscriberBuffer = 64
+
+// Event - single change notification
+type Event struct {
+	ID           int64   `json:"id"`
+	Type         string  `json:"type"`
+	NoteIDs      []int64 `json:"ids,omitempty"`
+	ParentID     int64   `json:"parentId,omitempty"`
+	DateModified int64   `json:"dateModified,omitempty"`
+	Expand       []int   `json:"expand,omitempty"`
+	Collapse     []int   `json:"collapse,omitempty"`
+	ReminderID   int64   `json:"reminderId,omitempty"`
+
+	// Settings and reminders are personal: 0 = shared (authentication is disabled)
+	UserID int64 `json:"-"`
+
+	// Deleted notes which were visible for users (user ID => note IDs), they could not be checked after deletion
+	Viewers map[int64][]int64 `json:"-"`
+
+	// Notebook of changes, client gets just events of notebook which it is subscribed to
+	Notebook string `json:"-"`
+}
+
+// Subscriber - channel of events for single client
+type Subscriber struct {
+	C      chan Event
+	closed bool
+}
+
+var (
+	mutex       sync.Mutex
+	history     []Event
+	subscribers = make(map[*Subscriber]struct{})
+	stopped     bool
+
+	// IDs are based on start time, so they keep growing after restart
+	lastID = time.Now().UnixMilli() * 1000
+)
+
+// Publish - send event to all subscribers and keep it in history
+func Publish(event Event) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	lastID++
+	event.ID = lastID
+
+	history = append(history, event)
+	if len(history) > historySize {
+		history = history[len(history)-historySize:]
+	}
+
+	for s := range subscribers {
+		select {
+		case s.C <- event:
+		default:
+			// Client is too slow: disconnect, it resumes by last event ID
+			close(s.C)
+			s.closed = true
+			delete(subscribers, s)
+		}
+	}
+}
+
+// Subscribe - subscribe to new events, returns missed events after lastEventID
+// If missed events are not in history anymore, the backlog has single Reset event
+func Subscribe(lastEventID int64) (*Subscriber, []Event) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	s := &Subscriber{C: make(chan Event, subscriberBuffer)}
+	if stopped {
+		close(s.C)
+		s.closed = true
+		return s, nil
+	}
+	subscribers[s] = struct{}{}
+
+	if lastEventID <= 0 || lastEventID == lastID {
+		return s, nil
+	}
+
+	// Client has ID from other run or history is overwritten
+	if lastEventID > lastID || len(history) == 0 || lastEventID < history[0].ID-1 {
+		return s, []Event{{ID: lastID, Type: Reset}}
+	}
+
+	var backlog []Event
+	for _, event := range history {
+		if event.ID > lastEventID {
+			backlog = append(backlog, event)
+		}
+	}
+
+	return s, backlog
+}
+
+// Unsubscribe - stop sending events to subscriber
+func Unsubscribe(s *Subscriber) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if s.closed {
+		return
+	}
+	close(s.C)
+	s.closed = true
+	delete(subscribers, s)
+}
+
+// Shutdown - disconnect all subscribers and refuse new ones (server is stopping)
+func Shutdown() {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	stopped = true
+	for s := range subscribers {
+		close(s.C)
+		s.closed = true
+		delete(subscribers, s)
+	}
+}
@@ -0,0 +1,400 @@
+package htmlmd
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// ElementHook - custom converter for single element
+// Returns markdown and true if element is handled by hook
+type ElementHook func(n *html.Node, convert func(n *html.Node) string) (string, bool)
+
+// Options - arguments struct for conversion
+type Options struct {
+	Hook ElementHook
+}
+
+// converter - conversion state
+type converter struct {
+	opts      Options
+	listDepth int
+}
+
+var (
+	reBlankLines = regexp.MustCompile(`\n{3,}`)
+	reSpaces     = regexp.MustCompile(`[ \t\r\n]+`)
+	reEscape     = regexp.MustCompile("([\\\\`*_\\[\\]])")
+)
+
+// Convert - convert HTML document (or fragment) to Markdown
+func Convert(r io.Reader, opts Options) (string, error) {
+	doc, err := html.Parse(r)
+	if err != nil {
+		return "", err
+	}
+
+	return ConvertNode(doc, opts), nil
+}
+
+// ConvertString - convert HTML string to Markdown
+func ConvertString(s string, opts Options) (string, error) {
+	return Convert(strings.NewReader(s), opts)
+}
+
+// ConvertNode - convert parsed HTML node to Markdown
+func ConvertNode(n *html.Node, opts Options) string {
+	c := &converter{opts: opts}
+	md := c.children(n)
+
+	// Cleanup whitespaces (lines of spaces between blocks become blank before they are collapsed)
+	lines := strings.Split(md, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	md = reBlankLines.ReplaceAllString(strings.Join(lines, "\n"), "\n\n")
+
+	return strings.TrimSpace(md)
+}
+
+// children - convert all child nodes
+func (c *converter) children(n *html.Node) string {
+	var b strings.Builder
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		b.WriteString(c.node(child))
+	}
+	return b.String()
+}
+
+// node - convert single node
+func (c *converter) node(n *html.Node) string {
+	switch n.Type {
+	case html.TextNode:
+		if isInside(n, atom.Pre) {
+			return n.Data
+		}
+		text := reSpaces.ReplaceAllString(n.Data, " ")
+		return reEscape.ReplaceAllString(text, `\$1`)
+	case html.DocumentNode:
+		return c.children(n)
+	case html.ElementNode:
+		// Custom elements first (eg, <en-media>)
+		if c.opts.Hook != nil {
+			if md, ok := c.opts.Hook(n, c.node); ok {
+				return md
+			}
+		}
+		return c.element(n)
+	default:
+		return ""
+	}
+}
+
+// element - convert HTML element
+func (c *converter) element(n *html.Node) string {
+	switch n.DataAtom {
+	case atom.Head, atom.Script, atom.Style, atom.Title, atom.Meta, atom.Link, atom.Noscript:
+		return ""
+	case atom.Br:
+		return "  \n"
+	case atom.Hr:
+		return "\n\n---\n\n"
+	case atom.P, atom.Div, atom.Section, atom.Article, atom.Header, atom.Footer, atom.Main, atom.Aside, atom.Figure:
+		return block(c.children(n))
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		level := int(n.Data[1] - '0')
+		text := strings.TrimSpace(c.inline(n))
+		if text == "" {
+			return ""
+		}
+		return block(strings.Repeat("#", level) + " " + text)
+	case atom.Strong, atom.B:
+		return wrap(c.children(n), "**")
+	case atom.Em, atom.I:
+		return wrap(c.children(n), "_")
+	case atom.S, atom.Strike, atom.Del:
+		return wrap(c.children(n), "~~")
+	case atom.Code, atom.Kbd, atom.Samp, atom.Tt:
+		if isInside(n, atom.Pre) {
+			return c.children(n)
+		}
+		return inlineCode(textContent(n))
+	case atom.Pre:
+		return c.pre(n)
+	case atom.A:
+		return c.link(n)
+	case atom.Img:
+		return image(attr(n, "alt"), attr(n, "src"))
+	case atom.Ul, atom.Ol:
+		return c.list(n)
+	case atom.Blockquote:
+		return c.blockquote(n)
+	case atom.Table:
+		return c.table(n)
+	default:
+		return c.children(n)
+	}
+}
+
+// inline - convert children to single line
+func (c *converter) inline(n *html.Node) string {
+	return reSpaces.ReplaceAllString(c.children(n), " ")
+}
+
+// pre - convert preformatted block to fenced code
+func (c *converter) pre(n *html.Node) string {
+	code := strings.Trim(textContent(n), "\n")
+
+	// Detect language by class (language-go, lang-go)
+	lang := ""
+	for _, el := range []*html.Node{n, firstElement(n, atom.Code)} {
+		if el == nil {
+			continue
+		}
+		for class := range strings.FieldsSeq(attr(el, "class")) {
+			if l, ok := strings.CutPrefix(class, "language-"); ok {
+				lang = l
+			} else if l, ok := strings.CutPrefix(class, "lang-"); ok {
+				lang = l
+			}
+		}
+	}
+
+	fence := "```"
+	for strings.Contains(code, fence) {
+		fence += "`"
+	}
+
+	return block(fence + lang + "\n" + code + "\n" + fence)
+}
+
+// link - convert <a>
+func (c *converter) link(n *html.Node) string {
+	text := strings.TrimSpace(c.inline(n))
+	href := attr(n, "href")
+	if href == "" || strings.HasPrefix(strings.ToLower(href), "javascript:") {
+		return text
+	}
+	if text == "" {
+		text = href
+	}
+
+	return fmt.Sprintf("[%s](%s)", text, escapeURL(href))
+}
+
+// list - convert <ul> and <ol>
+func (c *converter) list(n *html.Node) string {
+	c.listDepth++
+	defer func() { c.listDepth-- }()
+
+	indent := strings.Repeat("    ", c.listDepth-1)
+	ordered := n.DataAtom == atom.Ol
+
+	var b strings.Builder
+	index := 1
+	for li := n.FirstChild; li != nil; li = li.NextSibling {
+		if li.Type != html.ElementNode {
+			continue
+		}
+
+		marker := "- "
+		if ordered {
+			marker = fmt.Sprintf("%d. ", index)
+		}
+		index++
+
+		// Nested content must be indented to the list item level
+		item := strings.TrimSpace(reBlankLines.ReplaceAllString(c.children(li), "\n\n"))
+		item = strings.ReplaceAll(item, "\n\n", "\n")
+		lines := strings.Split(item, "\n")
+		for i := 1; i < len(lines); i++ {
+			if !strings.HasPrefix(lines[i], indent+"    ") {
+				lines[i] = indent + "    " + strings.TrimLeft(lines[i], " ")
+			}
+		}
+
+		b.WriteString(indent + marker + strings.Join(lines, "\n") + "\n")
+	}
+
+	if c.listDepth > 1 {
+		return "\n" + b.String()
+	}
+	return block(strings.TrimRight(b.String(), "\n"))
+}
+
+// blockquote - convert <blockquote>
+func (c *converter) blockquote(n *html.Node) string {
+	text := strings.TrimSpace(reBlankLines.ReplaceAllString(c.children(n), "\n\n"))
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight("> "+line, " ")
+	}
+	return block(strings.Join(lines, "\n"))
+}
+
+// table - convert <table> to GFM table
+func (c *converter) table(n *html.Node) string {
+	var rows [][]string
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			if child.Type != html.ElementNode {
+				continue
+			}
+			if child.DataAtom == atom.Tr {
+				var row []string
+				for cell := child.FirstChild; cell != nil; cell = cell.NextSibling {
+					if cell.DataAtom == atom.Td || cell.DataAtom == atom.Th {
+						text := strings.TrimSpace(c.inline(cell))
+						row = append(row, strings.ReplaceAll(text, "|", `\|`))
+					}
+				}
+				rows = append(rows, row)
+				continue
+			}
+			// Nested table is out of GFM scope, skip its rows
+			if child.DataAtom != atom.Table {
+				walk(child)
+			}
+		}
+	}
+	walk(n)
+
+	if len(rows) == 0 {
+		return ""
+	}
+
+	// Normalize column count
+	cols := 0
+	for _, row := range rows {
+		cols = max(cols, len(row))
+	}
+	if cols == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	for i, row := range rows {
+		for len(row) < cols {
+			row = append(row, "")
+		}
+		b.WriteString("| " + strings.Join(row, " | ") + " |\n")
+		if i == 0 {
+			b.WriteString("|" + strings.Repeat(" --- |", cols) + "\n")
+		}
+	}
+
+	return block(strings.TrimRight(b.String(), "\n"))
+}
+
+// block - surround text with blank lines
+func block(s string) string {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return ""
+	}
+	return "\n\n" + s + "\n\n"
+}
+
+// wrap - wrap inline text with markers, keep outer spaces
+func wrap(s string, marker string) string {
+	trimmed := strings.TrimSpace(s)
+	if trimmed == "" {
+		return s
+	}
+	prefix := s[:len(s)-len(strings.TrimLeft(s, " "))]
+	suffix := s[len(strings.TrimRight(s, " ")):]
+	return prefix + marker + trimmed + marker + suffix
+}
+
+// inlineCode - make `code` with proper amount of backticks
+func inlineCode(s string) string {
+	if s == "" {
+		return ""
+	}
+	fence := "`"
+	for strings.Contains(s, fence) {
+		fence += "`"
+	}
+	if strings.HasPrefix(s, "`") || strings.HasSuffix(s, "`") {
+		s = " " + s + " "
+	}
+	return fence + s + fence
+}
+
+// image - make image markdown
+func image(alt, src string) string {
+	if src == "" {
+		return ""
+	}
+	return fmt.Sprintf("![%s](%s)", reEscape.ReplaceAllString(alt, `\$1`), escapeURL(src))
+}
+
+// Image - make image markdown (for hooks)
+func Image(alt, src string) string {
+	return image(alt, src)
+}
+
+// Link - make link markdown (for hooks)
+func Link(text, href string) string {
+	return fmt.Sprintf("[%s](%s)", reEscape.ReplaceAllString(text, `\$1`), escapeURL(href))
+}
+
+// escapeURL - escape chars which break markdown link
+func escapeURL(s string) string {
+	return strings.NewReplacer(" ", "%20", "(", "%28", ")", "%29").Replace(strings.TrimSpace(s))
+}
+
+// attr - get attribute value
+func attr(n *html.Node, name string) string {
+	for _, a := range n.Attr {
+		if strings.EqualFold(a.Key, name) {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// Attr - get attribute value (for hooks)
+func Attr(n *html.Node, name string) string {
+	return attr(n, name)
+}
+
+// textContent - get all text inside node
+func textContent(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	var b strings.Builder
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		b.WriteString(textContent(child))
+	}
+	return b.String()
+}
+
+// isInside - check node has parent element of type
+func isInside(n *html.Node, a atom.Atom) bool {
+	for p := n.Parent; p != nil; p = p.Parent {
+		if p.Type == html.ElementNode && p.DataAtom == a {
+			return true
+		}
+	}
+	return false
+}
+
+// firstElement - search first child element of type
+func firstElement(n *html.Node, a atom.Atom) *html.Node {
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type == html.ElementNode && child.DataAtom == a {
+			return child
+		}
+		if found := firstElement(child, a); found != nil {
+			return found
+		}
+	}
+	return nil
+}
@@ -0,0 +1,310 @@
+package importers
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/sondrus/tetrad/htmlmd"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"gorm.io/gorm"
+)
+
+// Source - single uploaded file
+type Source struct {
+	Name   string
+	Reader io.Reader
+}
+
+// enexNote - <note> element in ENEX file
+type enexNote struct {
+	Title     string         `xml:"title"`
+	Content   string         `xml:"content"`
+	Created   string         `xml:"created"`
+	Updated   string         `xml:"updated"`
+	Tags      []string       `xml:"tag"`
+	Resources []enexResource `xml:"resource"`
+}
+
+// enexResource - <resource> element in ENEX file
+type enexResource struct {
+	Data struct {
+		Encoding string `xml:"encoding,attr"`
+		Value    string `xml:",chardata"`
+	} `xml:"data"`
+	Mime     string `xml:"mime"`
+	FileName string `xml:"resource-attributes>file-name"`
+}
+
+// reEnmlSelfClosing - self-closing ENML elements (<en-media ... />)
+var reEnmlSelfClosing = regexp.MustCompile(`<(en-media|en-todo|en-crypt)(\s[^>]*?)?\s*/>`)
+
+// enexMedia - decoded resource, ready for save
+type enexMedia struct {
+	ID   int64
+	Name string
+	Mime string
+	Data []byte
+	Used bool
+}
+
+// ImportEvernote - import Evernote export files (ENEX), one file is one notebook
+// File name may contain path (Stack/Notebook.enex), it is used as folder hierarchy
+func ImportEvernote(db *gorm.DB, sources []Source, parentID int64) (*Report, error) {
+	report := &Report{Skipped: []Skipped{}}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// Folder path => note ID (shared between files for stacks)
+		folders := make(map[string]int64)
+
+		for _, source := range sources {
+			notebookPath := strings.Trim(path.Clean("/"+strings.ReplaceAll(source.Name, `\`, "/")), "/")
+			notebookPath = strings.TrimSuffix(notebookPath, path.Ext(notebookPath))
+
+			notebookID, err := evernoteFolder(tx, folders, notebookPath, parentID, report)
+			if err != nil {
+				return err
+			}
+
+			if err := importEnex(tx, source, notebookID, report); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// evernoteFolder - get or create folder notes for notebook path
+func evernoteFolder(tx *gorm.DB, folders map[string]int64, folderPath string, parentID int64, report *Report) (int64, error) {
+	if folderPath == "" || folderPath == "." {
+		return parentID, nil
+	}
+	if id, ok := folders[folderPath]; ok {
+		return id, nil
+	}
+
+	parentID, err := evernoteFolder(tx, folders, path.Dir(folderPath), parentID, report)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := createNote(tx, parentID, path.Base(folderPath), "", time.Time{}, time.Time{})
+	if err != nil {
+		return 0, err
+	}
+	folders[folderPath] = id
+	report.Folders++
+
+	return id, nil
+}
+
+// importEnex - import all notes from single ENEX file
+func importEnex(tx *gorm.DB, source Source, parentID int64, report *Report) error {
+	decoder := xml.NewDecoder(source.Reader)
+	decoder.Strict = false
+	decoder.Entity = xml.HTMLEntity
+
+	found := false
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("invalid ENEX file %s: %w", source.Name, err)
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != "note" {
+			continue
+		}
+		found = true
+
+		var note enexNote
+		if err := decoder.DecodeElement(&note, &start); err != nil {
+			return fmt.Errorf("invalid note in ENEX file %s: %w", source.Name, err)
+		}
+
+		if err := importEnexNote(tx, source.Name, note, parentID, report); err != nil {
+			return err
+		}
+	}
+
+	if !found {
+		report.skip(source.Name, "", "no notes found in file")
+	}
+
+	return nil
+}
+
+// importEnexNote - import single note with its resources
+func importEnexNote(tx *gorm.DB, source string, note enexNote, parentID int64, report *Report) error {
+	title := strings.TrimSpace(note.Title)
+
+	// Decode resources, key is MD5 hash (used in <en-media hash="...">)
+	media := make(map[string]*enexMedia)
+	var order []string
+	for _, resource := range note.Resources {
+		name := resource.FileName
+		if name == "" {
+			name = "attachment"
+		}
+
+		if resource.Data.Encoding != "" && resource.Data.Encoding != "base64" {
+			report.skip(source, title+": "+name, "unsupported resource encoding %s", resource.Data.Encoding)
+			continue
+		}
+		data, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(resource.Data.Value), ""))
+		if err != nil || len(data) == 0 {
+			report.skip(source, title+": "+name, "invalid resource data")
+			continue
+		}
+
+		sum := md5.Sum(data)
+		hash := hex.EncodeToString(sum[:])
+		media[hash] = &enexMedia{Name: name, Mime: resource.Mime, Data: data}
+		order = append(order, hash)
+	}
+
+	created := enexTime(note.Created)
+	updated := enexTime(note.Updated)
+
+	// Create note first, contents need resource IDs
+	noteID, err := createNote(tx, parentID, title, "", created, updated)
+	if err != nil {
+		return err
+	}
+	report.Notes++
+
+	for _, hash := range order {
+		m := media[hash]
+		if m.ID > 0 {
+			continue
+		}
+		m.ID, err = createResource(tx, noteID, m.Name, m.Mime, m.Data, created)
+		if err != nil {
+			return err
+		}
+		report.Resources++
+	}
+
+	// Convert ENML to markdown
+	contents, err := enmlToMarkdown(note.Content, media)
+	if err != nil {
+		report.skip(source, title, "failed to convert contents: %v", err)
+	}
+	if strings.Contains(note.Content, "<en-crypt") {
+		report.skip(source, title, "encrypted fragments are not imported")
+	}
+
+	// Resources which are not placed in contents are appended as attachments
+	var attachments []string
+	for _, hash := range order {
+		if m := media[hash]; !m.Used {
+			attachments = append(attachments, "- "+mediaMarkdown(m))
+			m.Used = true
+		}
+	}
+	if len(attachments) > 0 {
+		contents = strings.TrimSpace(contents + "\n\n---\n\n" + strings.Join(attachments, "\n"))
+	}
+
+	if len(note.Tags) > 0 {
+		report.skip(source, title, "tags are not supported: %s", strings.Join(note.Tags, ", "))
+	}
+
+	return tx.Table("notes").Where("ID = ?", noteID).Update("CONTENTS", contents).Error
+}
+
+// enmlToMarkdown - convert ENML (<en-note>) to markdown
+func enmlToMarkdown(enml string, media map[string]*enexMedia) (string, error) {
+	hook := func(n *html.Node, convert func(n *html.Node) string) (string, bool) {
+		switch n.Data {
+		case "en-media":
+			m, ok := media[strings.ToLower(htmlmd.Attr(n, "hash"))]
+			if !ok {
+				return "", true
+			}
+			m.Used = true
+			return mediaMarkdown(m), true
+		case "en-todo":
+			if strings.EqualFold(htmlmd.Attr(n, "checked"), "true") {
+				return "[x] ", true
+			}
+			return "[ ] ", true
+		case "en-crypt":
+			return "", true
+		}
+
+		// Evernote 10 checklists: <ul style="--en-todo:true"><li style="--en-checked:true">
+		if n.DataAtom == atom.Ul && strings.Contains(htmlmd.Attr(n, "style"), "--en-todo:true") {
+			var lines []string
+			for li := n.FirstChild; li != nil; li = li.NextSibling {
+				if li.DataAtom != atom.Li {
+					continue
+				}
+				mark := "[ ]"
+				if strings.Contains(htmlmd.Attr(li, "style"), "--en-checked:true") {
+					mark = "[x]"
+				}
+				var text strings.Builder
+				for child := li.FirstChild; child != nil; child = child.NextSibling {
+					text.WriteString(convert(child))
+				}
+				lines = append(lines, "- "+mark+" "+strings.Join(strings.Fields(text.String()), " "))
+			}
+			return "\n\n" + strings.Join(lines, "\n") + "\n\n", true
+		}
+
+		return "", false
+	}
+
+	// ENML is XML, but HTML parser doesn't know self-closing custom tags
+	enml = reEnmlSelfClosing.ReplaceAllString(enml, "<$1$2></$1>")
+
+	md, err := htmlmd.ConvertString(enml, htmlmd.Options{Hook: hook})
+	if err != nil {
+		return "", err
+	}
+	// <en-todo> inside <div> should look like task list item
+	lines := strings.Split(md, "\n")
+	for i, line := range lines {
+		if strings.HasPrefix(line, "[ ] ") || strings.HasPrefix(line, "[x] ") {
+			lines[i] = "- " + line
+		}
+	}
+
+	return strings.Join(lines, "\n"), nil
+}
+
+// mediaMarkdown - markdown for resource: image or link
+func mediaMarkdown(m *enexMedia) string {
+	if strings.HasPrefix(m.Mime, "image/") {
+		return htmlmd.Image(m.Name, ResourceURL(m.ID))
+	}
+	return htmlmd.Link(m.Name, ResourceURL(m.ID))
+}
+
+// enexTime - parse ENEX date (20060102T150405Z)
+func enexTime(s string) time.Time {
+	t, err := time.Parse("20060102T150405Z", strings.TrimSpace(s))
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
@@ -0,0 +1,94 @@
+package importers
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/sondrus/tetrad/models"
+	"gorm.io/gorm"
+)
+
+// Skipped - item which was not imported
+type Skipped struct {
+	Source string `json:"source"`
+	Title  string `json:"title"`
+	Reason string `json:"reason"`
+}
+
+// Report - import result
+type Report struct {
+	Folders   int       `json:"folders"`
+	Notes     int       `json:"notes"`
+	Resources int       `json:"resources"`
+	Skipped   []Skipped `json:"skipped"`
+}
+
+// String - short summary of import result (for audit log and console)
+func (r *Report) String() string {
+	return fmt.Sprintf("%d notes, %d folders, %d resources, %d skipped", r.Notes, r.Folders, r.Resources, len(r.Skipped))
+}
+
+// skip - add item to skipped list
+func (r *Report) skip(source, title, format string, args ...any) {
+	r.Skipped = append(r.Skipped, Skipped{
+		Source: source,
+		Title:  title,
+		Reason: fmt.Sprintf(format, args...),
+	})
+}
+
+// ResourceURL - URL to download resource (used in markdown)
+func ResourceURL(id int64) string {
+	return fmt.Sprintf("/api/resource/%d", id)
+}
+
+// createNote - insert note (MD) into database, return new ID
+func createNote(tx *gorm.DB, parentID int64, title, contents string, created, modified time.Time) (int64, error) {
+	now := time.Now()
+	if created.IsZero() {
+		created = now
+	}
+	if modified.IsZero() {
+		modified = created
+	}
+	if title == "" {
+		title = "Untitled"
+	}
+
+	note := models.NoteDB{
+		ParentID:     parentID,
+		Type:         "MD",
+		Title:        title,
+		Contents:     contents,
+		DateCreated:  created.Unix(),
+		DateModified: modified.Unix(),
+	}
+	if err := tx.Omit("ContentsLength").Create(&note).Error; err != nil {
+		return 0, fmt.Errorf("failed to create note %q: %w", title, err)
+	}
+
+	return note.ID, nil
+}
+
+// createResource - insert resource into database, return new ID
+func createResource(tx *gorm.DB, noteID int64, name, mime string, data []byte, created time.Time) (int64, error) {
+	if created.IsZero() {
+		created = time.Now()
+	}
+	if mime == "" {
+		mime = "application/octet-stream"
+	}
+
+	resource := models.Resource{
+		NoteID:      noteID,
+		Name:        name,
+		Mime:        mime,
+		Data:        data,
+		DateCreated: created.Unix(),
+	}
+	if err := tx.Create(&resource).Error; err != nil {
+		return 0, fmt.Errorf("failed to create resource %q: %w", name, err)
+	}
+
+	return resource.ID, nil
+}
@@ -0,0 +1,294 @@
+package importers
+
+import (
+	"archive/tar"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sondrus/tetrad/htmlmd"
+	"gorm.io/gorm"
+)
+
+// Joplin item types (BaseModel.TYPE_*)
+const (
+	joplinTypeNote     = "1"
+	joplinTypeFolder   = "2"
+	joplinTypeResource = "4"
+	joplinTypeTag      = "5"
+	joplinTypeNoteTag  = "6"
+)
+
+// joplinItem - single serialized Joplin item (note, folder, resource, ...)
+type joplinItem struct {
+	Title string
+	Body  string
+	Props map[string]string
+	File  string
+}
+
+// reJoplinLink - internal Joplin link (:/0123456789abcdef0123456789abcdef)
+var reJoplinLink = regexp.MustCompile(`:/([0-9a-fA-F]{32})`)
+
+// ImportJoplin - import Joplin export file (JEX, tar archive with markdown and metadata)
+func ImportJoplin(db *gorm.DB, r io.Reader, parentID int64) (*Report, error) {
+	report := &Report{Skipped: []Skipped{}}
+
+	items, files, err := readJoplinArchive(r, report)
+	if err != nil {
+		return nil, err
+	}
+
+	// Split items by type
+	var folders, notes []*joplinItem
+	resources := make(map[string]*joplinItem)
+	noteTags := 0
+	for _, item := range items {
+		if item.Props["encryption_applied"] == "1" {
+			report.skip(item.File, item.Title, "item is encrypted by Joplin")
+			continue
+		}
+
+		switch item.Props["type_"] {
+		case joplinTypeNote:
+			notes = append(notes, item)
+		case joplinTypeFolder:
+			folders = append(folders, item)
+		case joplinTypeResource:
+			resources[strings.ToLower(item.Props["id"])] = item
+		case joplinTypeTag:
+			report.skip(item.File, item.Title, "tags are not supported")
+		case joplinTypeNoteTag:
+			noteTags++
+		default:
+			report.skip(item.File, item.Title, "unsupported item type %s", item.Props["type_"])
+		}
+	}
+	if noteTags > 0 {
+		report.skip("", "", "%d tag assignments are not supported", noteTags)
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		// Joplin ID => Tetrad ID
+		ids := make(map[string]int64)
+		parents := make(map[int64]string)
+
+		// Create folders and notes at import root, parents are set below
+		for _, folder := range folders {
+			id, err := createNote(tx, parentID, folder.Title, "", joplinTime(folder, "created_time"), joplinTime(folder, "updated_time"))
+			if err != nil {
+				return err
+			}
+			ids[folder.Props["id"]] = id
+			parents[id] = folder.Props["parent_id"]
+			report.Folders++
+		}
+
+		for _, note := range notes {
+			contents := note.Body
+
+			// HTML notes (markup_language = 2) are converted to markdown
+			if note.Props["markup_language"] == "2" {
+				md, err := htmlmd.ConvertString(contents, htmlmd.Options{})
+				if err != nil {
+					report.skip(note.File, note.Title, "failed to convert HTML: %v", err)
+					continue
+				}
+				contents = md
+			}
+
+			id, err := createNote(tx, parentID, note.Title, contents, joplinTime(note, "created_time"), joplinTime(note, "updated_time"))
+			if err != nil {
+				return err
+			}
+			ids[note.Props["id"]] = id
+			parents[id] = note.Props["parent_id"]
+			note.Body = contents
+			report.Notes++
+		}
+
+		// Restore hierarchy
+		for id, parent := range parents {
+			newParentID, ok := ids[parent]
+			if !ok {
+				continue
+			}
+			if err := tx.Table("notes").Where("ID = ?", id).Update("PARENT_ID", newParentID).Error; err != nil {
+				return fmt.Errorf("failed to update parent for note %d: %w", id, err)
+			}
+		}
+
+		// Create resources, owned by the first note which refers to it
+		resourceIDs := make(map[string]int64)
+		for _, note := range notes {
+			noteID, ok := ids[note.Props["id"]]
+			if !ok {
+				continue
+			}
+			for _, match := range reJoplinLink.FindAllStringSubmatch(note.Body, -1) {
+				resourceKey := strings.ToLower(match[1])
+				resource, ok := resources[resourceKey]
+				if !ok {
+					continue
+				}
+				if _, done := resourceIDs[resourceKey]; done {
+					continue
+				}
+
+				data, ok := files[resourceKey]
+				if !ok {
+					report.skip(resource.File, resource.Title, "resource file is missing in archive")
+					resourceIDs[resourceKey] = 0
+					continue
+				}
+
+				id, err := createResource(tx, noteID, resource.Title, resource.Props["mime"], data, joplinTime(resource, "created_time"))
+				if err != nil {
+					return err
+				}
+				resourceIDs[resourceKey] = id
+				report.Resources++
+			}
+		}
+
+		for key, resource := range resources {
+			if _, used := resourceIDs[key]; !used {
+				report.skip(resource.File, resource.Title, "resource is not referenced by any note")
+			}
+		}
+
+		// Rewrite links to resources
+		for _, note := range notes {
+			noteID, ok := ids[note.Props["id"]]
+			if !ok {
+				continue
+			}
+			contents := reJoplinLink.ReplaceAllStringFunc(note.Body, func(link string) string {
+				if id := resourceIDs[strings.ToLower(link[2:])]; id > 0 {
+					return ResourceURL(id)
+				}
+				return link
+			})
+			if contents == note.Body {
+				continue
+			}
+			if err := tx.Table("notes").Where("ID = ?", noteID).Update("CONTENTS", contents).Error; err != nil {
+				return fmt.Errorf("failed to update contents for note %d: %w", noteID, err)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// readJoplinArchive - read all items (*.md) and resource files from JEX
+func readJoplinArchive(r io.Reader, report *Report) ([]*joplinItem, map[string][]byte, error) {
+	var items []*joplinItem
+	files := make(map[string][]byte)
+
+	archive := tar.NewReader(r)
+	for {
+		header, err := archive.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid JEX archive: %w", err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		data, err := io.ReadAll(archive)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read %s: %w", header.Name, err)
+		}
+
+		name := strings.TrimPrefix(path.Clean(header.Name), "./")
+		switch {
+		case strings.HasPrefix(name, "resources/"):
+			// resources/<id>.<ext>
+			base := path.Base(name)
+			key := strings.ToLower(strings.TrimSuffix(base, path.Ext(base)))
+			files[key] = data
+		case strings.HasSuffix(name, ".md") && !strings.Contains(name, "/"):
+			item, err := parseJoplinItem(string(data))
+			if err != nil {
+				report.skip(name, "", "failed to parse item: %v", err)
+				continue
+			}
+			item.File = name
+			items = append(items, item)
+		}
+	}
+
+	if len(items) == 0 {
+		return nil, nil, errors.New("no Joplin items found in archive")
+	}
+
+	// Stable order by creation date
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].Props["created_time"] < items[j].Props["created_time"]
+	})
+
+	return items, files, nil
+}
+
+// parseJoplinItem - parse serialized item: title, empty line, body, empty line, properties
+func parseJoplinItem(s string) (*joplinItem, error) {
+	lines := strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n")
+
+	// Read properties from the end until empty line
+	props := make(map[string]string)
+	end := len(lines)
+	for end > 0 && strings.TrimSpace(lines[end-1]) == "" {
+		end--
+	}
+	for ; end > 0; end-- {
+		line := strings.TrimSpace(lines[end-1])
+		if line == "" {
+			break
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid property line %q", line)
+		}
+		props[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+
+	if props["id"] == "" || props["type_"] == "" {
+		return nil, errors.New("missing id or type_ property")
+	}
+
+	// Title is the first line, body starts after empty line
+	body := lines[:max(end-1, 0)]
+	item := &joplinItem{Props: props}
+	if len(body) > 0 {
+		item.Title = strings.TrimSpace(body[0])
+	}
+	if len(body) > 2 {
+		item.Body = strings.Join(body[2:], "\n")
+	}
+
+	return item, nil
+}
+
+// joplinTime - parse Joplin date property (user_* has priority)
+func joplinTime(item *joplinItem, name string) time.Time {
+	for _, key := range []string{"user_" + name, name} {
+		if t, err := time.Parse(time.RFC3339Nano, item.Props[key]); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
@@ -0,0 +1,98 @@
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync/atomic"
+)
+
+// Options - logging settings
+type Options struct {
+	Level    string // debug, info, warn, error
+	Format   string // text or json
+	File     string // log file path, empty = console only
+	MaxSize  int64  // file is rotated when it gets bigger (bytes)
+	MaxFiles int    // count of rotated files to keep
+	SQLTrace bool   // log all SQL queries
+}
+
+var (
+	// Current level, could be changed at runtime
+	level = new(slog.LevelVar)
+
+	// SQL tracing flag, could be changed at runtime
+	sqlTrace atomic.Bool
+
+	// Log file (nil if logging to console only)
+	logFile *rotatingFile
+)
+
+// Setup - set default slog logger (standard `log` package goes to it too)
+func Setup(options Options) error {
+	if err := SetLevel(options.Level); err != nil {
+		return err
+	}
+	sqlTrace.Store(options.SQLTrace)
+
+	var writer io.Writer = os.Stderr
+	if options.File != "" {
+		file, err := openRotatingFile(options.File, options.MaxSize, options.MaxFiles)
+		if err != nil {
+			return fmt.Errorf("failed to open log file: %w", err)
+		}
+		logFile = file
+		writer = io.MultiWriter(os.Stderr, file)
+	}
+
+	handlerOptions := &slog.HandlerOptions{Level: level}
+
+	var handler slog.Handler
+	switch strings.ToLower(options.Format) {
+	case "", "text":
+		handler = slog.NewTextHandler(writer, handlerOptions)
+	case "json":
+		handler = slog.NewJSONHandler(writer, handlerOptions)
+	default:
+		return fmt.Errorf("unknown log format %s (text or json)", options.Format)
+	}
+
+	slog.SetDefault(slog.New(handler))
+
+	return nil
+}
+
+// Close - close log file
+func Close() error {
+	if logFile == nil {
+		return nil
+	}
+	return logFile.Close()
+}
+
+// GetLevel - get current level name (debug, info, warn, error)
+func GetLevel() string {
+	return strings.ToLower(level.Level().String())
+}
+
+// SetLevel - change level (debug, info, warn, error)
+func SetLevel(name string) error {
+	var value slog.Level
+	if err := value.UnmarshalText([]byte(name)); err != nil {
+		return fmt.Errorf("unknown log level %s (debug, info, warn or error)", name)
+	}
+	level.Set(value)
+	return nil
+}
+
+// IsSQLTrace - check all SQL queries are logged
+func IsSQLTrace() bool {
+	return sqlTrace.Load()
+}
+
+// SetSQLTrace - enable or disable logging of all SQL queries
+func SetSQLTrace(enabled bool) {
+	sqlTrace.Store(enabled)
+}
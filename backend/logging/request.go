@@ -0,0 +1,141 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// requestInfoKey - context key for request details
+type requestInfoKey struct{}
+
+// requestInfo - details which are known just inside router (filled by Annotate and RecordError)
+type requestInfo struct {
+	Route  string
+	NoteID string
+	User   string
+	Error  string
+}
+
+// responseRecorder - response writer which keeps status and size
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	size   int64
+	info   *requestInfo
+}
+
+// WriteHeader - keep status
+func (w *responseRecorder) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Write - keep size (status is 200 if it was not set)
+func (w *responseRecorder) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// Flush - support streaming responses
+func (w *responseRecorder) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap - original writer for http.ResponseController
+func (w *responseRecorder) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Requests - middleware which logs every request: method, route, status, duration, note ID
+// API requests are logged with info level, other successful ones (static files) with debug level
+func Requests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		info := &requestInfo{}
+		recorder := &responseRecorder{ResponseWriter: w, info: info}
+		next.ServeHTTP(recorder, r.WithContext(context.WithValue(r.Context(), requestInfoKey{}, info)))
+
+		status := recorder.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		logLevel := slog.LevelInfo
+		switch {
+		case status >= 500:
+			logLevel = slog.LevelError
+		case status >= 400:
+			logLevel = slog.LevelWarn
+		case !strings.HasPrefix(r.URL.Path, "/api/"):
+			logLevel = slog.LevelDebug
+		}
+
+		ctx := r.Context()
+		if !slog.Default().Enabled(ctx, logLevel) {
+			return
+		}
+
+		attrs := []slog.Attr{
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", status),
+			slog.Duration("duration", time.Since(start)),
+			slog.Int64("size", recorder.size),
+			slog.String("address", r.RemoteAddr),
+		}
+		if info.Route != "" {
+			attrs = append(attrs, slog.String("route", info.Route))
+		}
+		if info.NoteID != "" {
+			attrs = append(attrs, slog.String("note", info.NoteID))
+		}
+		if info.User != "" {
+			attrs = append(attrs, slog.String("user", info.User))
+		}
+		if info.Error != "" {
+			attrs = append(attrs, slog.String("error", info.Error))
+		}
+
+		slog.LogAttrs(ctx, logLevel, "Request", attrs...)
+	})
+}
+
+// Annotate - add route template, note ID and user name to request log
+func Annotate(r *http.Request, route string, noteID string, user string) {
+	if info, ok := r.Context().Value(requestInfoKey{}).(*requestInfo); ok {
+		info.Route = route
+		info.NoteID = noteID
+		info.User = user
+	}
+}
+
+// RecordError - add error to request log (response writer must be passed through Requests)
+func RecordError(w http.ResponseWriter, message string, err error) {
+	for {
+		if recorder, ok := w.(*responseRecorder); ok {
+			recorder.info.Error = message
+			if err != nil {
+				recorder.info.Error += ": " + err.Error()
+			}
+			return
+		}
+
+		unwrapper, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			return
+		}
+		w = unwrapper.Unwrap()
+	}
+}
@@ -1,19 +1,76 @@
 package main
 
 import (
+	"flag"
+	"fmt"
 	"log"
+	"log/slog"
+	"os"
 
+	"github.com/sondrus/tetrad/auth"
+	"github.com/sondrus/tetrad/cli"
 	"github.com/sondrus/tetrad/config"
 	"github.com/sondrus/tetrad/database"
+	"github.com/sondrus/tetrad/logging"
 	"github.com/sondrus/tetrad/server"
 )
 
 func main() {
+	flag.Usage = cli.Usage
 	config.Load()
 
+	if config.AppConfig.PrintConfig {
+		config.PrintConfig(os.Stdout)
+		return
+	}
+
+	command := config.AppConfig.Command
+	if cli.FindCommand(command) == nil {
+		fmt.Fprintf(os.Stderr, "Unknown command %s\n\n", command)
+		cli.Usage()
+		os.Exit(2)
+	}
+
+	err := logging.Setup(logging.Options{
+		Level:    config.AppConfig.LogLevel,
+		Format:   config.AppConfig.LogFormat,
+		File:     config.AppConfig.LogFile,
+		MaxSize:  int64(config.AppConfig.LogMaxSize) << 20,
+		MaxFiles: config.AppConfig.LogMaxFiles,
+		SQLTrace: config.AppConfig.SQLTrace,
+	})
+	if err != nil {
+		log.Fatalf("Failed to set up logging: %s", err)
+	}
+	defer logging.Close()
+
 	if err := database.LoadDatabase(config.AppConfig.Database); err != nil {
-		log.Fatalf("Failed to load database: %s", err)
+		fatal("Failed to load database", err)
+	}
+
+	// Password from previous versions => admin user
+	if err := auth.MigrateLegacyPassword(); err != nil {
+		fatal("Failed to migrate password", err)
+	}
+
+	if command == "serve" {
+		err = server.Start(config.GetLocalAddress())
+	} else {
+		err = cli.Run(command, config.AppConfig.Args)
 	}
 
-	server.Start(config.GetLocalAddress())
+	// Database is closed anyway, even if server is failed
+	if closeErr := database.Close(); closeErr != nil {
+		slog.Error("Failed to close database", "error", closeErr)
+	}
+	if err != nil {
+		fatal(fmt.Sprintf("Command %s failed", command), err)
+	}
+}
+
+// fatal - log error and exit
+func fatal(message string, err error) {
+	slog.Error(message, "error", err)
+	logging.Close()
+	os.Exit(1)
 }
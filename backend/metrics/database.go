@@ -0,0 +1,28 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sondrus/tetrad/database"
+)
+
+// databaseSizeDesc - size of database file by notebook
+var databaseSizeDesc = prometheus.NewDesc(
+	prometheus.BuildFQName(namespace, "", "database_size_bytes"),
+	"Size of SQLite database file.",
+	[]string{"notebook"}, nil,
+)
+
+// databaseCollector - reads sizes of opened notebooks on every scrape
+type databaseCollector struct{}
+
+// Describe - send metric descriptions
+func (databaseCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- databaseSizeDesc
+}
+
+// Collect - get file size of every opened notebook
+func (databaseCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, notebook := range database.OpenedNotebooks() {
+		ch <- prometheus.MustNewConstMetric(databaseSizeDesc, prometheus.GaugeValue, float64(notebook.Filesize()), notebook.Name)
+	}
+}
@@ -0,0 +1,122 @@
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/sondrus/tetrad/config"
+)
+
+// namespace - prefix of all metric names
+const namespace = "tetrad"
+
+var (
+	// registry - own registry (default one has global state shared with libraries)
+	registry = prometheus.NewRegistry()
+
+	requestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "Count of HTTP requests by route, method and status code.",
+	}, []string{"route", "method", "code"})
+
+	requestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Duration of HTTP requests by route and method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method"})
+
+	treeRebuildDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "tree_rebuild_duration_seconds",
+		Help:      "Duration of notes tree (nested set) rebuild.",
+		Buckets:   prometheus.ExponentialBuckets(0.001, 2, 14),
+	})
+
+	searchDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "search_duration_seconds",
+		Help:      "Duration of notes search.",
+		Buckets:   prometheus.ExponentialBuckets(0.001, 2, 14),
+	})
+
+	maintenanceTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "maintenance_total",
+		Help:      "Count of maintenance operations (vacuum, backup) by result.",
+	}, []string{"operation", "result"})
+
+	maintenanceLastSuccess = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "maintenance_last_success_timestamp_seconds",
+		Help:      "Time of the last successful maintenance operation.",
+	}, []string{"operation"})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		requestsTotal,
+		requestDuration,
+		treeRebuildDuration,
+		searchDuration,
+		maintenanceTotal,
+		maintenanceLastSuccess,
+		databaseCollector{},
+		notesCollector{},
+	)
+}
+
+// IsEnabled - check metrics are enabled (on main server or on separate address)
+func IsEnabled() bool {
+	return config.AppConfig.Metrics || config.AppConfig.MetricsAddress != ""
+}
+
+// Handler - handler for /metrics
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// Middleware - count requests and measure latency per route template (router must be matched already)
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := "unknown"
+		if current := mux.CurrentRoute(r); current != nil {
+			if template, err := current.GetPathTemplate(); err == nil {
+				route = template
+			}
+		}
+
+		labels := prometheus.Labels{"route": route}
+		handler := promhttp.InstrumentHandlerDuration(requestDuration.MustCurryWith(labels),
+			promhttp.InstrumentHandlerCounter(requestsTotal.MustCurryWith(labels), next))
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// ObserveTreeRebuild - save duration of notes tree rebuild
+func ObserveTreeRebuild(started time.Time) {
+	treeRebuildDuration.Observe(time.Since(started).Seconds())
+}
+
+// ObserveSearch - save duration of notes search
+func ObserveSearch(started time.Time) {
+	searchDuration.Observe(time.Since(started).Seconds())
+}
+
+// ObserveMaintenance - save result of maintenance operation (vacuum, backup)
+func ObserveMaintenance(operation string, err error) {
+	if err != nil {
+		maintenanceTotal.WithLabelValues(operation, "error").Inc()
+		return
+	}
+
+	maintenanceTotal.WithLabelValues(operation, "success").Inc()
+	maintenanceLastSuccess.WithLabelValues(operation).SetToCurrentTime()
+}
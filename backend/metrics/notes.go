@@ -0,0 +1,46 @@
+package metrics
+
+import (
+	"log/slog"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sondrus/tetrad/database"
+)
+
+// notesDesc - count of notes by notebook and type
+var notesDesc = prometheus.NewDesc(
+	prometheus.BuildFQName(namespace, "", "notes"),
+	"Count of notes by type.",
+	[]string{"notebook", "type"}, nil,
+)
+
+// notesCollector - counts notes of every opened notebook on every scrape (cheap query, always actual)
+type notesCollector struct{}
+
+// Describe - send metric descriptions
+func (notesCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- notesDesc
+}
+
+// Collect - query counts of notes by type
+func (notesCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, notebook := range database.OpenedNotebooks() {
+		var rows []struct {
+			Type  string `gorm:"column:TYPE"`
+			Count int64  `gorm:"column:COUNT"`
+		}
+		err := notebook.ORM().
+			Table("notes").
+			Select("TYPE, COUNT(*) AS COUNT").
+			Group("TYPE").
+			Scan(&rows).Error
+		if err != nil {
+			slog.Error("Failed to count notes for metrics", "notebook", notebook.Name, "error", err)
+			continue
+		}
+
+		for _, row := range rows {
+			ch <- prometheus.MustNewConstMetric(notesDesc, prometheus.GaugeValue, float64(row.Count), notebook.Name, row.Type)
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package models
+
+// AuditRecord - struct for storage single mutating operation in audit log
+// Note IDs and fields are stored as comma-separated lists (",1,2," for search by note)
+type AuditRecord struct {
+	ID        int64    `gorm:"column:ID;primaryKey" json:"id"`
+	Date      int64    `gorm:"column:DATE" json:"date"`
+	Operation string   `gorm:"column:OPERATION" json:"operation"`
+	NoteList  string   `gorm:"column:NOTE_IDS" json:"-"`
+	FieldList string   `gorm:"column:FIELDS" json:"-"`
+	Details   string   `gorm:"column:DETAILS" json:"details"`
+	UserID    int64    `gorm:"column:USER_ID" json:"userId"`
+	UserName  string   `gorm:"column:USER_NAME" json:"userName"`
+	TokenID   int64    `gorm:"column:TOKEN_ID" json:"tokenId"`
+	Address   string   `gorm:"column:ADDRESS" json:"address"`
+	Notebook  string   `gorm:"column:NOTEBOOK" json:"notebook"`
+	NoteIDs   []int64  `gorm:"-" json:"noteIds"`
+	Fields    []string `gorm:"-" json:"fields"`
+}
+
+// TableName - set custom table name for GORM
+func (AuditRecord) TableName() string {
+	return "audit_log"
+}
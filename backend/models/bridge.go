@@ -0,0 +1,27 @@
+package models
+
+// BridgeData - struct for storage JSON data of IFRAME note (mini-app)
+type BridgeData struct {
+	NoteID       int64  `gorm:"column:NOTE_ID;primaryKey" json:"noteId"`
+	Data         string `gorm:"column:DATA" json:"-"`
+	DateModified int64  `gorm:"column:DATE_MODIFIED" json:"dateModified"`
+}
+
+// TableName - set custom table name for GORM
+func (BridgeData) TableName() string {
+	return "bridge_data"
+}
+
+// BridgeGrant - IFRAME note (mini-app) is allowed to read other note with its children
+type BridgeGrant struct {
+	ID          int64  `gorm:"column:ID;primaryKey" json:"id"`
+	AppID       int64  `gorm:"column:APP_ID" json:"appId"`
+	NoteID      int64  `gorm:"column:NOTE_ID" json:"noteId"`
+	NoteTitle   string `gorm:"column:NOTE_TITLE;->" json:"noteTitle"`
+	DateCreated int64  `gorm:"column:DATE_CREATED" json:"dateCreated"`
+}
+
+// TableName - set custom table name for GORM
+func (BridgeGrant) TableName() string {
+	return "bridge_grants"
+}
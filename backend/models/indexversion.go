@@ -0,0 +1,13 @@
+package models
+
+// IndexVersion - version of derived index (eg, tasks), index is rebuilt when its version is missing or outdated
+type IndexVersion struct {
+	Name         string `gorm:"column:NAME;primaryKey"`
+	Version      int64  `gorm:"column:VERSION"`
+	DateModified int64  `gorm:"column:DATE_MODIFIED"`
+}
+
+// TableName - set custom table name for GORM
+func (IndexVersion) TableName() string {
+	return "index_versions"
+}
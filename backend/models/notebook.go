@@ -0,0 +1,17 @@
+package models
+
+// Notebook - struct for storage registered database file (registry is kept in main database)
+type Notebook struct {
+	ID          int64  `gorm:"column:ID;primaryKey" json:"-"`
+	Name        string `gorm:"column:NAME" json:"name"`
+	Path        string `gorm:"column:PATH" json:"path"`
+	Open        bool   `gorm:"column:OPEN" json:"open"`
+	DateCreated int64  `gorm:"column:DATE_CREATED" json:"dateCreated"`
+	Size        int64  `gorm:"-" json:"size"`
+	Default     bool   `gorm:"-" json:"default"`
+}
+
+// TableName - set custom table name for GORM
+func (Notebook) TableName() string {
+	return "notebooks"
+}
@@ -18,11 +18,16 @@ type NoteDB struct {
 	URL          string `gorm:"column:URL" json:"url"`
 	Syntax       string `gorm:"column:SYNTAX" json:"syntax"`
 	Favorite     int64  `gorm:"column:FAVORITE" json:"favorite"`
+	Template     bool   `gorm:"column:TEMPLATE;type:INTEGER" json:"template"`
+	Favicon      int64  `gorm:"column:FAVICON" json:"favicon"`            // resource ID of page icon (URL notes)
+	DateArchived int64  `gorm:"column:DATE_ARCHIVED" json:"dateArchived"` // date of archived copy of page (URL notes)
 	DateCreated  int64  `gorm:"column:DATE_CREATED" json:"dateCreated"`
 	DateModified int64  `gorm:"column:DATE_MODIFIED" json:"dateModified"`
 
 	// Virtual fields
 	ContentsLength int64 `gorm:"column:CONTENTS_LENGTH" json:"contentsLength"`
+	Encrypted      bool  `gorm:"column:ENCRYPTED;->" json:"encrypted"`
+	Locked         bool  `gorm:"-" json:"locked"`
 }
 
 // TableName - set custom table name for GORM
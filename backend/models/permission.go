@@ -0,0 +1,14 @@
+package models
+
+// Permission - role of user on note subtree (inherited by children)
+type Permission struct {
+	ID     int64  `gorm:"column:ID;primaryKey" json:"id"`
+	UserID int64  `gorm:"column:USER_ID" json:"userId"`
+	NoteID int64  `gorm:"column:NOTE_ID" json:"noteId"`
+	Role   string `gorm:"column:ROLE" json:"role"`
+}
+
+// TableName - set custom table name for GORM
+func (Permission) TableName() string {
+	return "permissions"
+}
@@ -0,0 +1,19 @@
+package models
+
+// Reminder - personal reminder of note, it is fired at TIME and repeated by RECURRENCE
+type Reminder struct {
+	ID         int64  `gorm:"column:ID;primaryKey" json:"id"`
+	NoteID     int64  `gorm:"column:NOTE_ID" json:"noteId"`
+	UserID     int64  `gorm:"column:USER_ID" json:"-"`
+	Message    string `gorm:"column:MESSAGE" json:"message"`
+	Recurrence string `gorm:"column:RECURRENCE" json:"recurrence"`
+	State      string `gorm:"column:STATE" json:"state"`
+	DateStart  int64  `gorm:"column:DATE_START" json:"dateStart"`
+	DateNext   int64  `gorm:"column:DATE_NEXT" json:"dateNext"`
+	DateFired  int64  `gorm:"column:DATE_FIRED" json:"dateFired"`
+}
+
+// TableName - set custom table name for GORM
+func (Reminder) TableName() string {
+	return "reminders"
+}
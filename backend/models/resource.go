@@ -0,0 +1,16 @@
+package models
+
+// Resource - struct for storage attached files (images, documents, ...)
+type Resource struct {
+	ID          int64  `gorm:"column:ID;primaryKey" json:"id"`
+	NoteID      int64  `gorm:"column:NOTE_ID" json:"noteId"`
+	Name        string `gorm:"column:NAME" json:"name"`
+	Mime        string `gorm:"column:MIME" json:"mime"`
+	Data        []byte `gorm:"column:DATA" json:"-"`
+	DateCreated int64  `gorm:"column:DATE_CREATED" json:"dateCreated"`
+}
+
+// TableName - set custom table name for GORM
+func (Resource) TableName() string {
+	return "resources"
+}
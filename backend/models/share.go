@@ -0,0 +1,22 @@
+package models
+
+// Share - public read-only link to note or subtree (just hash of link token is stored)
+type Share struct {
+	ID             int64  `gorm:"column:ID;primaryKey" json:"id"`
+	NoteID         int64  `gorm:"column:NOTE_ID" json:"noteId"`
+	UserID         int64  `gorm:"column:USER_ID" json:"userId"`
+	Hash           string `gorm:"column:HASH" json:"-"`
+	Prefix         string `gorm:"column:PREFIX" json:"prefix"`
+	Subtree        bool   `gorm:"column:SUBTREE;type:INTEGER" json:"subtree"`
+	Password       string `gorm:"column:PASSWORD" json:"-"`
+	HasPassword    bool   `gorm:"-" json:"hasPassword"`
+	AccessCount    int64  `gorm:"column:ACCESS_COUNT" json:"accessCount"`
+	DateCreated    int64  `gorm:"column:DATE_CREATED" json:"dateCreated"`
+	DateExpires    int64  `gorm:"column:DATE_EXPIRES" json:"dateExpires"`
+	DateLastAccess int64  `gorm:"column:DATE_LAST_ACCESS" json:"dateLastAccess"`
+}
+
+// TableName - set custom table name for GORM
+func (Share) TableName() string {
+	return "shares"
+}
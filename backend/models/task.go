@@ -0,0 +1,17 @@
+package models
+
+// Task - checkbox item (`- [ ]`) of markdown note, it is indexed when note is saved
+type Task struct {
+	ID       int64  `gorm:"column:ID;primaryKey" json:"id"`
+	NoteID   int64  `gorm:"column:NOTE_ID" json:"noteId"`
+	Line     int64  `gorm:"column:LINE" json:"line"`
+	Text     string `gorm:"column:TEXT" json:"text"`
+	Done     bool   `gorm:"column:DONE;type:INTEGER" json:"done"`
+	Due      string `gorm:"column:DUE" json:"due"`
+	Priority int64  `gorm:"column:PRIORITY" json:"priority"`
+}
+
+// TableName - set custom table name for GORM
+func (Task) TableName() string {
+	return "tasks"
+}
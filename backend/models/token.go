@@ -0,0 +1,19 @@
+package models
+
+// Token - struct for storage personal API tokens (just hash of token is stored)
+type Token struct {
+	ID           int64  `gorm:"column:ID;primaryKey" json:"id"`
+	UserID       int64  `gorm:"column:USER_ID" json:"userId"`
+	Name         string `gorm:"column:NAME" json:"name"`
+	Hash         string `gorm:"column:HASH" json:"-"`
+	Prefix       string `gorm:"column:PREFIX" json:"prefix"`
+	Scope        string `gorm:"column:SCOPE" json:"scope"`
+	DateCreated  int64  `gorm:"column:DATE_CREATED" json:"dateCreated"`
+	DateExpires  int64  `gorm:"column:DATE_EXPIRES" json:"dateExpires"`
+	DateLastUsed int64  `gorm:"column:DATE_LAST_USED" json:"dateLastUsed"`
+}
+
+// TableName - set custom table name for GORM
+func (Token) TableName() string {
+	return "tokens"
+}
@@ -0,0 +1,29 @@
+package models
+
+// User - struct for storage user accounts
+type User struct {
+	ID           int64  `gorm:"column:ID;primaryKey" json:"id"`
+	Name         string `gorm:"column:NAME" json:"name"`
+	Password     string `gorm:"column:PASSWORD" json:"-"`
+	Admin        bool   `gorm:"column:ADMIN;type:INTEGER" json:"admin"`
+	DateCreated  int64  `gorm:"column:DATE_CREATED" json:"dateCreated"`
+	DateModified int64  `gorm:"column:DATE_MODIFIED" json:"dateModified"`
+}
+
+// TableName - set custom table name for GORM
+func (User) TableName() string {
+	return "users"
+}
+
+// UserOption - struct for storage per-user settings
+type UserOption struct {
+	UserID int64  `gorm:"column:USER_ID;primaryKey"`
+	Name   string `gorm:"column:NAME;primaryKey"`
+	Value  string `gorm:"column:VALUE"`
+	Type   string `gorm:"column:TYPE"`
+}
+
+// TableName - set custom table name for GORM
+func (UserOption) TableName() string {
+	return "user_options"
+}
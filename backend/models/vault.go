@@ -0,0 +1,16 @@
+package models
+
+// Vault - encrypted subtree, key is derived from passphrase (passphrase itself is not stored)
+type Vault struct {
+	ID          int64  `gorm:"column:ID;primaryKey" json:"id"`
+	NoteID      int64  `gorm:"column:NOTE_ID" json:"noteId"`
+	KDF         string `gorm:"column:KDF" json:"kdf"`
+	Salt        string `gorm:"column:SALT" json:"-"`
+	Check       string `gorm:"column:CHECK" json:"-"`
+	DateCreated int64  `gorm:"column:DATE_CREATED" json:"dateCreated"`
+}
+
+// TableName - set custom table name for GORM
+func (Vault) TableName() string {
+	return "vaults"
+}
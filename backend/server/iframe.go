@@ -0,0 +1,298 @@
+package server
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/sondrus/tetrad/config"
+	"github.com/sondrus/tetrad/database"
+	"github.com/sondrus/tetrad/models"
+	"github.com/sondrus/tetrad/services"
+)
+
+// iframeCapabilityTTL - lifetime of link to IFRAME note on separate origin
+const iframeCapabilityTTL = 12 * time.Hour
+
+// registerRoutesIFrame - register routes for view in <iframe>
+func registerRoutesIFrame(router *mux.Router) {
+	router.HandleFunc("/iframe/{id:[0-9]+}", iframeHandler).Methods("GET")
+}
+
+// newIFrameRouter - router for separate IFRAME origin: just notes by capability and script, no API
+func newIFrameRouter() *mux.Router {
+	router := mux.NewRouter()
+	router.Use(detectNoteIDByContext)
+	router.HandleFunc("/iframe/{id:[0-9]+}", isolatedIFrameHandler).Methods("GET")
+	router.HandleFunc("/iframe/script.js", handlerNotFound).Methods("GET")
+	router.NotFoundHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		services.RespondWithError(w, http.StatusNotFound, "File not found ..", nil)
+	})
+	return router
+}
+
+// splitIFrameHost - send requests for IFRAME origin host (eg, behind reverse proxy) to IFRAME router
+func splitIFrameHost(main http.Handler, iframe http.Handler) http.Handler {
+	if config.AppConfig.IFrameOrigin == "" {
+		return main
+	}
+	u, err := url.Parse(config.AppConfig.IFrameOrigin)
+	if err != nil || u.Host == "" {
+		return main
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.EqualFold(r.Host, u.Host) {
+			iframe.ServeHTTP(w, r)
+			return
+		}
+		main.ServeHTTP(w, r)
+	})
+}
+
+// iframeHandler - show note (TYPE=IFRAME) in iframe, or redirect to separate origin
+func iframeHandler(w http.ResponseWriter, r *http.Request) {
+	services.SetCommonResponseHeaders(w, r)
+
+	// Get note from database
+	ID := r.Context().Value(database.IDKey).(int)
+	note, err := services.GetUnlockedNote(r.Context(), services.GetContextKeyring(r.Context()), ID)
+	if err != nil {
+		services.RespondWithError(w, http.StatusNotFound, "Note is not found", nil)
+		return
+	}
+
+	// Check user can view note
+	if !services.CheckNoteRole(w, r, note, services.RoleViewer) {
+		return
+	}
+
+	// Encrypted note must be unlocked first
+	if note.Locked {
+		services.RespondWithError(w, http.StatusLocked, "Note is encrypted and locked", nil)
+		return
+	}
+
+	// Check note type is iframe
+	if note.Type != "IFRAME" {
+		services.RespondWithError(w, http.StatusBadRequest, "Note type is not iframe", nil)
+		return
+	}
+
+	// Separate origin: redirect with signed capability (session cookie may be not available there)
+	// Encrypted notes could not be decrypted without session, they are shown here (sandboxed anyway)
+	if config.HasIFrameOrigin() && !note.Encrypted {
+		var userID int64
+		if user := services.GetContextUser(r.Context()); user != nil {
+			userID = user.ID
+		}
+
+		capability, err := services.CreateCapability(services.Capability{
+			NoteID:   note.ID,
+			UserID:   userID,
+			Notebook: database.GetContextNotebook(r.Context()).Name,
+			Parent:   requestOrigin(r),
+		}, iframeCapabilityTTL)
+		if err != nil {
+			services.RespondWithError(w, http.StatusInternalServerError, "Failed to create link", err)
+			return
+		}
+
+		target := fmt.Sprintf("%s/iframe/%d?cap=%s", iframeOrigin(r), note.ID, url.QueryEscape(capability))
+		w.Header().Set("Referrer-Policy", "no-referrer")
+		http.Redirect(w, r, target, http.StatusFound)
+		return
+	}
+
+	serveIFrameNote(w, note, iframeCSP("'self'", false))
+}
+
+// isolatedIFrameHandler - show note (TYPE=IFRAME) on separate origin by capability
+func isolatedIFrameHandler(w http.ResponseWriter, r *http.Request) {
+	services.SetCommonResponseHeaders(w, r)
+
+	// Check capability
+	ID := r.Context().Value(database.IDKey).(int)
+	capability, err := services.ParseCapability(r.URL.Query().Get("cap"))
+	if err != nil || capability.NoteID != int64(ID) {
+		services.RespondWithError(w, http.StatusForbidden, "Access denied", nil)
+		return
+	}
+
+	// Notebook of note is set by capability
+	notebook, err := database.GetNotebook(capability.Notebook)
+	if err != nil {
+		services.RespondWithError(w, http.StatusNotFound, "Note is not found", nil)
+		return
+	}
+	r = r.WithContext(database.WithNotebook(r.Context(), notebook))
+
+	// Get note from database
+	note, err := services.GetNote(r.Context(), ID)
+	if err != nil || note.Type != "IFRAME" || note.Encrypted {
+		services.RespondWithError(w, http.StatusNotFound, "Note is not found", nil)
+		return
+	}
+
+	// User could lose access after link was created
+	var user *models.User
+	if capability.UserID > 0 {
+		user, err = services.GetUser(capability.UserID)
+		if err != nil {
+			services.RespondWithError(w, http.StatusForbidden, "Access denied", nil)
+			return
+		}
+	}
+	if !services.HasNoteRole(r.Context(), user, note, services.RoleViewer) {
+		services.RespondWithError(w, http.StatusForbidden, "Access denied", nil)
+		return
+	}
+
+	serveIFrameNote(w, note, iframeCSP(capability.Parent, config.AppConfig.IFrameSameOrigin))
+}
+
+// serveIFrameNote - send note HTML with injected script and sandbox policy
+func serveIFrameNote(w http.ResponseWriter, note models.NoteDB, csp string) {
+	// Prepare HTML
+	html := note.Contents
+
+	// Prepare HTML
+	if !strings.HasPrefix(strings.ToUpper(note.Contents), "<!DOCTYPE") {
+		html = fmt.Sprintf(`<div id="__iframe__">%s</div>`, html)
+		html = fmt.Sprintf(`<!DOCTYPE html>`+
+			`<html lang="en">`+
+			`<head>`+
+			`<meta charset="UTF-8">`+
+			`<link rel="icon" href="data:,">`+
+			`<style>html,body,#__iframe__{height:100%%;margin:0;padding:0;}</style>`+
+			`</head>`+
+			`<body>%s</body>`+
+			`</html>`, html)
+	}
+
+	// Inject JS to HTML <head> (just one match)
+	js := `<script src="script.js"></script>`
+	re := regexp.MustCompile(`(?i)` + regexp.QuoteMeta("<head>"))
+	found := false
+	html = re.ReplaceAllStringFunc(html, func(match string) string {
+		if found {
+			return match
+		}
+		found = true
+		return match + js
+	})
+
+	// Send response
+	w.Header().Set("Content-Security-Policy", csp)
+	w.Header().Del("X-Frame-Options")
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	w.Header().Set("Content-Type", "text/html")
+	w.Write([]byte(html))
+}
+
+// iframeCSP - content security policy for IFRAME note
+// Note is always sandboxed: without own origin it could not use session of main application
+func iframeCSP(parent string, sameOrigin bool) string {
+	scripts := cspSources(config.AppConfig.IFrameScripts)
+	connect := cspSources(config.AppConfig.IFrameConnect)
+
+	// Sandbox flags
+	sandbox := []string{"sandbox", "allow-forms", "allow-modals", "allow-popups", "allow-downloads", "allow-pointer-lock"}
+	if scripts != "'none'" {
+		sandbox = append(sandbox, "allow-scripts")
+	}
+	if sameOrigin {
+		sandbox = append(sandbox, "allow-same-origin")
+	}
+
+	// Injected script.js is always allowed
+	if scripts != "'none'" {
+		scripts = "'self' " + strings.TrimPrefix(scripts, "'self' ")
+	}
+
+	// Styles and fonts of main application are loaded from parent
+	assets := "'self' https: data:"
+	if parent != "'self'" {
+		assets += " " + parent
+	}
+
+	directives := []string{
+		strings.Join(sandbox, " "),
+		"default-src 'none'",
+		"script-src " + scripts,
+		"style-src 'unsafe-inline' " + assets,
+		"font-src " + assets,
+		"img-src * data: blob:",
+		"media-src * data: blob:",
+		"frame-src https:",
+		"connect-src " + connect,
+		"form-action " + connect,
+		"base-uri 'self'",
+		"frame-ancestors " + parent,
+	}
+
+	return strings.Join(directives, "; ")
+}
+
+// cspSources - convert configured sources to CSP (none, inline, eval, self => keywords)
+func cspSources(items []string) string {
+	keywords := map[string]string{
+		"none":   "'none'",
+		"inline": "'unsafe-inline'",
+		"eval":   "'unsafe-eval'",
+		"self":   "'self'",
+	}
+
+	var sources []string
+	for _, item := range items {
+		if keyword, ok := keywords[strings.ToLower(item)]; ok {
+			item = keyword
+		}
+		if item == "'none'" {
+			continue
+		}
+		sources = append(sources, item)
+	}
+
+	if len(sources) == 0 {
+		return "'none'"
+	}
+	return strings.Join(sources, " ")
+}
+
+// requestOrigin - origin of main application for request (eg, http://localhost:8888)
+func requestOrigin(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
+
+// iframeOrigin - origin of IFRAME notes: configured or same host with IFRAME port
+func iframeOrigin(r *http.Request) string {
+	if config.AppConfig.IFrameOrigin != "" {
+		return config.AppConfig.IFrameOrigin
+	}
+
+	host := r.Host
+	if h, _, err := net.SplitHostPort(r.Host); err == nil {
+		host = h
+	}
+
+	scheme := "http"
+	if config.IsTLS() {
+		scheme = "https"
+	}
+	return scheme + "://" + net.JoinHostPort(host, config.AppConfig.IFramePort)
+}
+
+// iframeAddress - listen address for IFRAME origin
+func iframeAddress() string {
+	return net.JoinHostPort(config.AppConfig.Host, config.AppConfig.IFramePort)
+}
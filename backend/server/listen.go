@@ -0,0 +1,107 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/sondrus/tetrad/events"
+)
+
+const (
+	// readHeaderTimeout - time to read request headers (slow clients could not hold connections)
+	readHeaderTimeout = 10 * time.Second
+
+	// readTimeout - time to read whole request, long enough for big imports
+	readTimeout = 10 * time.Minute
+
+	// writeTimeout - time to write response, long enough for database download (event stream disables it)
+	writeTimeout = 10 * time.Minute
+
+	// idleTimeout - keep-alive connections without requests are closed after it
+	idleTimeout = 2 * time.Minute
+
+	// maxHeaderBytes - limit for request headers size
+	maxHeaderBytes = 64 << 10
+
+	// shutdownTimeout - time to finish active requests after SIGINT/SIGTERM
+	shutdownTimeout = 30 * time.Second
+)
+
+// listener - HTTP server with its protocol
+type listener struct {
+	server *http.Server
+	tls    bool
+}
+
+// newHTTPServer - create HTTP server with timeouts and header limits
+func newHTTPServer(address string, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              address,
+		Handler:           handler,
+		ErrorLog:          slog.NewLogLogger(slog.Default().Handler(), slog.LevelWarn),
+		ReadHeaderTimeout: readHeaderTimeout,
+		ReadTimeout:       readTimeout,
+		WriteTimeout:      writeTimeout,
+		IdleTimeout:       idleTimeout,
+		MaxHeaderBytes:    maxHeaderBytes,
+	}
+}
+
+// serve - run all servers until error or SIGINT/SIGTERM, then stop them gracefully
+// Second signal during shutdown terminates the app immediately
+func serve(listeners []listener, certFile string, keyFile string) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	errs := make(chan error, len(listeners))
+	for _, l := range listeners {
+		go func() {
+			var err error
+			if l.tls {
+				err = l.server.ListenAndServeTLS(certFile, keyFile)
+			} else {
+				err = l.server.ListenAndServe()
+			}
+			if !errors.Is(err, http.ErrServerClosed) {
+				errs <- fmt.Errorf("%s: %w", l.server.Addr, err)
+			}
+		}()
+	}
+
+	// Wait for signal or failed server
+	var err error
+	select {
+	case <-ctx.Done():
+		slog.Info("Shutting down, waiting for active requests")
+	case err = <-errs:
+	}
+	stop()
+
+	// Event streams never end by themselves
+	events.Shutdown()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for _, l := range listeners {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if shutdownErr := l.server.Shutdown(shutdownCtx); shutdownErr != nil {
+				slog.Error("Failed to stop server gracefully", "address", l.server.Addr, "error", shutdownErr)
+			}
+		}()
+	}
+	wg.Wait()
+
+	return err
+}
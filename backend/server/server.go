@@ -2,56 +2,187 @@ package server
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
+	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
-	"regexp"
 	"strconv"
 	"strings"
-	"time"
 
 	"github.com/gorilla/mux"
 
 	api_about "github.com/sondrus/tetrad/api/about"
+	api_audit "github.com/sondrus/tetrad/api/audit"
+	api_auth "github.com/sondrus/tetrad/api/auth"
+	api_bridge "github.com/sondrus/tetrad/api/bridge"
 	api_database "github.com/sondrus/tetrad/api/database"
+	api_events "github.com/sondrus/tetrad/api/events"
+	api_health "github.com/sondrus/tetrad/api/health"
+	api_importer "github.com/sondrus/tetrad/api/importer"
+	api_journal "github.com/sondrus/tetrad/api/journal"
+	api_logging "github.com/sondrus/tetrad/api/logging"
+	api_markdown "github.com/sondrus/tetrad/api/markdown"
 	api_note "github.com/sondrus/tetrad/api/note"
+	api_notebooks "github.com/sondrus/tetrad/api/notebooks"
 	api_notes "github.com/sondrus/tetrad/api/notes"
+	api_reminders "github.com/sondrus/tetrad/api/reminders"
+	api_resource "github.com/sondrus/tetrad/api/resource"
 	api_settings "github.com/sondrus/tetrad/api/settings"
+	api_shares "github.com/sondrus/tetrad/api/shares"
+	api_tasks "github.com/sondrus/tetrad/api/tasks"
+	api_templates "github.com/sondrus/tetrad/api/templates"
+	api_tokens "github.com/sondrus/tetrad/api/tokens"
+	api_users "github.com/sondrus/tetrad/api/users"
+	api_vaults "github.com/sondrus/tetrad/api/vaults"
+	"github.com/sondrus/tetrad/auth"
+	"github.com/sondrus/tetrad/config"
 	"github.com/sondrus/tetrad/database"
+	"github.com/sondrus/tetrad/logging"
+	"github.com/sondrus/tetrad/meta"
+	"github.com/sondrus/tetrad/metrics"
 	"github.com/sondrus/tetrad/services"
 	"github.com/sondrus/tetrad/static"
 )
 
-// Start - start web-server
-func Start(address string) {
+// Notebook of request (main database if not set)
+const (
+	notebookHeader = "X-Tetrad-Notebook"
+	notebookPrefix = "/nb/"
+)
+
+// Start - start web-server, returns when it is stopped by SIGINT/SIGTERM (after active requests are finished)
+func Start(address string) error {
 	router := mux.NewRouter().StrictSlash(true)
 	registerAllRoutes(router)
 
-	handler := enableCORS(router)
+	// IFRAME notes on separate origin (own port or own host name)
+	iframeRouter := newIFrameRouter()
+
+	handler := logging.Requests(splitIFrameHost(enableCORS(protectCSRF(selectNotebook(router))), iframeRouter))
+
+	// Old audit log records
+	services.ScheduleAuditCleanup(config.AppConfig.AuditRetention)
+
+	// Tasks index could be outdated (eg, database of older version or changed by other app)
+	services.ReindexAllTasks()
+
+	// Reminders which are due (also missed while server was stopped)
+	services.ScheduleReminders()
+
+	// Plain HTTP
+	if !config.IsTLS() {
+		slog.Info("Tetrad started", "url", "http://"+address)
+		listeners := []listener{{server: newHTTPServer(address, handler)}}
+		if config.AppConfig.IFramePort != "" {
+			slog.Info("IFRAME notes", "url", "http://"+iframeAddress())
+			listeners = append(listeners, listener{server: newHTTPServer(iframeAddress(), iframeRouter)})
+		}
+		listeners = appendMetricsListener(listeners)
+		return serve(listeners, "", "")
+	}
+
+	// HTTPS with provided or self-signed certificate
+	certFile, keyFile, err := tlsFiles()
+	if err != nil {
+		return fmt.Errorf("failed to prepare TLS certificate: %w", err)
+	}
+	fingerprint, err := certificateFingerprint(certFile, keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+
+	slog.Info("Tetrad started", "url", "https://"+address)
+	slog.Info("Certificate", "file", certFile, "sha256", fingerprint)
+
+	listeners := []listener{{server: newHTTPServer(address, handler), tls: true}}
 
-	datetime := time.Now().Format("2006-01-02 15:04:05")
-	fmt.Printf("[%s] Tetrad started on http://%s\n", datetime, address)
+	// HTTP => HTTPS redirect
+	if config.AppConfig.RedirectPort != "" {
+		redirectAddress := net.JoinHostPort(config.AppConfig.Host, config.AppConfig.RedirectPort)
+		slog.Info("Redirect to HTTPS", "url", "http://"+redirectAddress)
+		listeners = append(listeners, listener{server: newHTTPServer(redirectAddress, http.HandlerFunc(redirectToHTTPS))})
+	}
+
+	// IFRAME origin
+	if config.AppConfig.IFramePort != "" {
+		slog.Info("IFRAME notes", "url", "https://"+iframeAddress())
+		listeners = append(listeners, listener{server: newHTTPServer(iframeAddress(), iframeRouter), tls: true})
+	}
+
+	listeners = appendMetricsListener(listeners)
+	return serve(listeners, certFile, keyFile)
+}
+
+// appendMetricsListener - add server for Prometheus metrics on separate address (plain HTTP, without authentication)
+func appendMetricsListener(listeners []listener) []listener {
+	address := config.AppConfig.MetricsAddress
+	if address == "" {
+		return listeners
+	}
+
+	slog.Info("Metrics", "url", "http://"+address+"/metrics")
+	metricsRouter := http.NewServeMux()
+	metricsRouter.Handle("GET /metrics", metrics.Handler())
+	metricsRouter.HandleFunc("GET /healthz", api_health.LiveHandler)
+	metricsRouter.HandleFunc("GET /readyz", api_health.ReadyHandler)
 
-	log.Fatal(http.ListenAndServe(address, handler))
+	return append(listeners, listener{server: newHTTPServer(address, metricsRouter)})
 }
 
 // registerAllRoutes - register all routes for app
 func registerAllRoutes(router *mux.Router) {
+	if metrics.IsEnabled() {
+		router.Use(metrics.Middleware)
+	}
+	router.Use(requireAuth)
+	router.Use(annotateRequestLog)
+	router.Use(attachKeyring)
 	router.Use(detectNoteIDByContext)
 
+	// Login page
+	registerRoutesLogin(router)
+
+	// Prometheus metrics on main server
+	if config.AppConfig.Metrics {
+		router.Handle("/metrics", metrics.Handler()).Methods("GET")
+	}
+
 	// API routes
+	api_auth.RegisterRoutes(router)
 	api_note.RegisterRoutes(router)
 	api_notes.RegisterRoutes(router)
 	api_database.RegisterRoutes(router)
+	api_notebooks.RegisterRoutes(router)
 	api_settings.RegisterRoutes(router)
+	api_users.RegisterRoutes(router)
+	api_tokens.RegisterRoutes(router)
+	api_vaults.RegisterRoutes(router)
+	api_events.RegisterRoutes(router)
+	api_audit.RegisterRoutes(router)
+	api_logging.RegisterRoutes(router)
+	api_health.RegisterRoutes(router)
+	api_bridge.RegisterRoutes(router)
 	api_about.RegisterRoutes(router)
+	api_resource.RegisterRoutes(router)
+	api_importer.RegisterRoutes(router)
+	api_markdown.RegisterRoutes(router)
+	api_templates.RegisterRoutes(router)
+	api_journal.RegisterRoutes(router)
+	api_tasks.RegisterRoutes(router)
+	api_reminders.RegisterRoutes(router)
+	api_shares.RegisterRoutes(router)
 
 	// IFrame
 	registerRoutesIFrame(router)
 
+	// Public share links
+	registerRoutesShare(router)
+
 	// Download database
 	registerRoutesDownload(router)
 
@@ -70,7 +201,7 @@ func registerRoutesHomepage(router *mux.Router) {
 
 // homepageHandler - handler for homepage (/, index.html, ...)
 func homepageHandler(w http.ResponseWriter, r *http.Request) {
-	services.SetCommonResponseHeaders(w)
+	services.SetCommonResponseHeaders(w, r)
 
 	// If empty path, then open index.html
 	file, ok := mux.Vars(r)["file"]
@@ -84,7 +215,7 @@ func homepageHandler(w http.ResponseWriter, r *http.Request) {
 
 // homepageHandlerStatic - handler for static files (html, css, js, ico, ...)
 func homepageHandlerStatic(w http.ResponseWriter, r *http.Request, file string) {
-	services.SetCommonResponseHeaders(w)
+	services.SetCommonResponseHeaders(w, r)
 
 	// If file exists in embedded files, send it
 	data, err := static.GetStaticFilesFS().ReadFile("files/homepage/" + file)
@@ -98,61 +229,30 @@ func homepageHandlerStatic(w http.ResponseWriter, r *http.Request, file string)
 	w.Write(data)
 }
 
-// registerRoutesIFrame - register routes for view in <iframe>
-func registerRoutesIFrame(router *mux.Router) {
-	router.HandleFunc("/iframe/{id:[0-9]+}", iframeHandler).Methods("GET")
+// registerRoutesLogin - register routes for login page
+func registerRoutesLogin(router *mux.Router) {
+	router.HandleFunc("/login", loginHandler).Methods("GET")
 }
 
-// iframeHandler - show note (TYPE=IFRAME) in iframe
-func iframeHandler(w http.ResponseWriter, r *http.Request) {
-	services.SetCommonResponseHeaders(w)
-
-	// Get note from database
-	ID := r.Context().Value(database.IDKey).(int)
-	note, err := services.GetNote(ID)
-	if err != nil {
-		services.RespondWithError(w, http.StatusNotFound, "Note is not found", nil)
+// loginHandler - show login page (if authentication is enabled)
+func loginHandler(w http.ResponseWriter, r *http.Request) {
+	if !auth.IsEnabled() || auth.GetSessionUser(r) != nil {
+		http.Redirect(w, r, "/", http.StatusSeeOther)
 		return
 	}
 
-	// Check note type is iframe
-	if note.Type != "IFRAME" {
-		services.RespondWithError(w, http.StatusBadRequest, "Note type is not iframe", nil)
+	data, err := static.GetStaticFilesFS().ReadFile("files/login/index.html")
+	if err != nil {
+		handlerNotFound(w, r)
 		return
 	}
 
-	// Prepare HTML
-	html := note.Contents
-
-	// Prepare HTML
-	if !strings.HasPrefix(strings.ToUpper(note.Contents), "<!DOCTYPE") {
-		html = fmt.Sprintf(`<div id="__iframe__">%s</div>`, html)
-		html = fmt.Sprintf(`<!DOCTYPE html>`+
-			`<html lang="en">`+
-			`<head>`+
-			`<meta charset="UTF-8">`+
-			`<link rel="icon" href="data:,">`+
-			`<style>html,body,#__iframe__{height:100%%;margin:0;padding:0;}</style>`+
-			`</head>`+
-			`<body>%s</body>`+
-			`</html>`, html)
-	}
-
-	// Inject JS to HTML <head> (just one match)
-	js := `<script src="script.js"></script>`
-	re := regexp.MustCompile(`(?i)` + regexp.QuoteMeta("<head>"))
-	found := false
-	html = re.ReplaceAllStringFunc(html, func(match string) string {
-		if found {
-			return match
-		}
-		found = true
-		return match + js
-	})
-
 	// Send response
-	w.Header().Set("Content-Type", "text/html")
-	w.Write([]byte(html))
+	w.Header().Set("Server", meta.Server)
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Frame-Options", "DENY")
+	w.Write(data)
 }
 
 // registerRoutesDownload - register routes for database download
@@ -162,10 +262,16 @@ func registerRoutesDownload(router *mux.Router) {
 
 // downloadDatabaseHandler - download database file
 func downloadDatabaseHandler(w http.ResponseWriter, r *http.Request) {
-	services.SetCommonResponseHeaders(w)
+	services.SetCommonResponseHeaders(w, r)
+
+	// Whole database contains notes of all users
+	if !services.CheckAdmin(w, r) {
+		return
+	}
 
 	// Open file for reading
-	filePath := database.GetFilepath()
+	notebook := database.GetContextNotebook(r.Context())
+	filePath := notebook.Path
 	file, err := os.Open(filePath)
 	if err != nil {
 		services.RespondWithError(w, http.StatusInternalServerError, "Failed to open database file", nil)
@@ -176,7 +282,7 @@ func downloadDatabaseHandler(w http.ResponseWriter, r *http.Request) {
 	// Set download headers
 	w.Header().Set("Content-Disposition", "attachment; filename="+filepath.Base(filePath))
 	w.Header().Set("Content-Type", "application/octet-stream")
-	w.Header().Set("Content-Length", fmt.Sprintf("%d", database.GetFilesize()))
+	w.Header().Set("Content-Length", fmt.Sprintf("%d", notebook.Filesize()))
 
 	// Write file to response
 	_, err = io.Copy(w, file)
@@ -188,7 +294,7 @@ func downloadDatabaseHandler(w http.ResponseWriter, r *http.Request) {
 
 // handler404 - handler for static files and non-existent files
 func handlerNotFound(w http.ResponseWriter, r *http.Request) {
-	services.SetCommonResponseHeaders(w)
+	services.SetCommonResponseHeaders(w, r)
 
 	// First, try to load static embedded files
 	file := r.URL.Path
@@ -203,17 +309,79 @@ func handlerNotFound(w http.ResponseWriter, r *http.Request) {
 	services.RespondWithError(w, http.StatusNotFound, "File not found ..", nil)
 }
 
-// enableCORS - for debug purpose
+// selectNotebook - middleware for put notebook of request to context: /nb/{name}/... prefix (removed from path)
+// or notebook header, main database is used by default
+func selectNotebook(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := r.Header.Get(notebookHeader)
+
+		if rest, ok := strings.CutPrefix(r.URL.Path, notebookPrefix); ok {
+			name, rest, _ = strings.Cut(rest, "/")
+			r = r.Clone(r.Context())
+			r.URL.Path = "/" + rest
+			r.URL.RawPath = ""
+		}
+
+		if name == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		notebook, err := database.GetNotebook(name)
+		switch {
+		case errors.Is(err, database.ErrNotebookClosed):
+			services.RespondWithError(w, http.StatusConflict, "Notebook is closed", nil)
+			return
+		case err != nil:
+			services.RespondWithError(w, http.StatusNotFound, "Notebook not found", nil)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(database.WithNotebook(r.Context(), notebook)))
+	})
+}
+
+// enableCORS - allow cross-origin requests from configured origins only (disabled by default)
 func enableCORS(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-		w.Header().Set("Access-Control-Expose-Headers", "*")
+		origin := r.Header.Get("Origin")
+		allowed := origin != "" && config.IsAllowedOrigin(origin)
+
+		if allowed {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, "+notebookHeader)
+			w.Header().Set("Access-Control-Expose-Headers", "*")
+		}
+		w.Header().Add("Vary", "Origin")
 
 		// If preflight-request (OPTIONS) => exit immediately
-		if r.Method == "OPTIONS" {
-			w.WriteHeader(http.StatusOK)
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// protectCSRF - reject state-changing requests from other sites (checks Origin, then Referer)
+func protectCSRF(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// API tokens are not sent by browsers automatically
+		if auth.GetBearerToken(r) != "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if !isSameSiteRequest(r) {
+			services.RespondWithError(w, http.StatusForbidden, "Cross-site request is rejected", nil)
 			return
 		}
 
@@ -221,6 +389,30 @@ func enableCORS(next http.Handler) http.Handler {
 	})
 }
 
+// isSameSiteRequest - check request comes from Tetrad page or allowed origin
+// Requests without Origin and Referer are not sent by browsers cross-site (curl, scripts), so they are allowed
+func isSameSiteRequest(r *http.Request) bool {
+	source := r.Header.Get("Origin")
+	if source == "" {
+		source = r.Header.Get("Referer")
+	}
+	if source == "" {
+		return r.Header.Get("Sec-Fetch-Site") != "cross-site"
+	}
+
+	// Sandboxed frames and privacy redirects send "null"
+	u, err := url.Parse(source)
+	if err != nil || u.Host == "" {
+		return false
+	}
+
+	if strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+
+	return config.IsAllowedOrigin(u.Scheme + "://" + u.Host)
+}
+
 // getContentType - fet file mime type by extension
 func getContentType(fileName string) string {
 	mimeTypes := map[string]string{
@@ -317,3 +509,97 @@ func detectNoteIDByContext(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
+
+// requireAuth - middleware for check session or API token and put current user to context (just if any user exists)
+func requireAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !auth.IsEnabled() {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// API token => put user and token to context
+		if auth.GetBearerToken(r) != "" {
+			user, token := auth.GetTokenUser(r)
+			if user == nil {
+				services.RespondWithError(w, http.StatusUnauthorized, "Invalid or expired API token", nil)
+				return
+			}
+			if !auth.TokenAllows(token, r) {
+				services.RespondWithError(w, http.StatusForbidden, "API token is read-only", nil)
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), database.UserKey, user)
+			ctx = context.WithValue(ctx, database.TokenKey, token)
+			next.ServeHTTP(w, r.WithContext(ctx))
+			return
+		}
+
+		// Logged in => put user to context
+		if user := auth.GetSessionUser(r); user != nil {
+			ctx := context.WithValue(r.Context(), database.UserKey, user)
+			next.ServeHTTP(w, r.WithContext(ctx))
+			return
+		}
+
+		if !isProtectedRoute(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// API and metrics => 401, pages => login page
+		if strings.HasPrefix(r.URL.Path, "/api/") || r.URL.Path == "/metrics" {
+			services.RespondWithError(w, http.StatusUnauthorized, "Authentication required", nil)
+			return
+		}
+
+		http.Redirect(w, r, "/login?next="+url.QueryEscape(r.URL.RequestURI()), http.StatusSeeOther)
+	})
+}
+
+// annotateRequestLog - middleware for add route, note ID and user to request log
+func annotateRequestLog(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := ""
+		if current := mux.CurrentRoute(r); current != nil {
+			route, _ = current.GetPathTemplate()
+		}
+
+		userName := ""
+		if user := services.GetContextUser(r.Context()); user != nil {
+			userName = user.Name
+		}
+
+		logging.Annotate(r, route, mux.Vars(r)["id"], userName)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// attachKeyring - middleware for put unlocked keys of encrypted notes to context
+func attachKeyring(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keyring := auth.GetKeyring(r, services.GetContextUser(r.Context()))
+		if keyring == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), database.KeyringKey, keyring)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// isProtectedRoute - check route requires authentication
+func isProtectedRoute(path string) bool {
+	switch {
+	case strings.HasPrefix(path, "/api/auth/"):
+		return false
+	case strings.HasPrefix(path, "/api/"), strings.HasPrefix(path, "/iframe/"):
+		return true
+	case path == "/", path == "/index.html", path == "/download", path == "/metrics":
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,294 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/sondrus/tetrad/auth"
+	"github.com/sondrus/tetrad/meta"
+	"github.com/sondrus/tetrad/models"
+	"github.com/sondrus/tetrad/services"
+)
+
+// shareCSP - share pages have no scripts, just sanitized HTML and images of note
+const shareCSP = "default-src 'none'; style-src 'unsafe-inline'; img-src 'self' data: https:; form-action 'self'; base-uri 'none'; frame-ancestors 'none'"
+
+// sharePage - data for share page template
+type sharePage struct {
+	Title    string
+	Base     string // URL path of share link
+	Contents template.HTML
+	Message  string      // error or notice instead of contents
+	Password bool        // show password form
+	Notes    []shareItem // tree of subtree links
+}
+
+// shareItem - note in tree of subtree link
+type shareItem struct {
+	ID      int64
+	Title   string
+	Indent  int64
+	Current bool
+}
+
+// shareTemplate - page of share link (server-rendered, works without JavaScript)
+var shareTemplate = template.Must(template.New("share").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="UTF-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex, nofollow">
+<link rel="icon" href="data:,">
+<title>{{.Title}}</title>
+<style>
+body{margin:0;font:16px/1.6 system-ui,sans-serif;color:#222;background:#fff;display:flex;min-height:100vh}
+nav{flex:0 0 16rem;border-right:1px solid #ddd;padding:1rem;background:#f7f7f7}
+nav ul{list-style:none;margin:0;padding:0}
+nav a{color:#333;text-decoration:none;display:block;padding:.1rem 0}
+nav a.current{font-weight:bold}
+main{flex:1;max-width:50rem;padding:1rem 2rem;overflow-wrap:break-word}
+pre{background:#f4f4f4;padding:.75rem;overflow:auto}
+img{max-width:100%}
+table{border-collapse:collapse}
+th,td{border:1px solid #ccc;padding:.25rem .5rem}
+.message{color:#666}
+@media (max-width:40rem){body{display:block}nav{border-right:0;border-bottom:1px solid #ddd}}
+</style>
+</head>
+<body>
+{{- if .Notes}}
+<nav><ul>
+{{- range .Notes}}
+<li style="padding-left: {{.Indent}}em"><a href="{{$.Base}}/{{.ID}}"{{if .Current}} class="current"{{end}}>{{.Title}}</a></li>
+{{- end}}
+</ul></nav>
+{{- end}}
+<main>
+<h1>{{.Title}}</h1>
+{{- if .Password}}
+<form method="post" action="{{.Base}}">
+<p><input type="password" name="password" placeholder="Password" autofocus required> <button type="submit">Open</button></p>
+</form>
+{{- end}}
+{{- if .Message}}
+<p class="message">{{.Message}}</p>
+{{- end}}
+{{.Contents}}
+</main>
+</body>
+</html>
+`))
+
+// registerRoutesShare - register routes for public share links (visitors are not logged in)
+func registerRoutesShare(router *mux.Router) {
+	router.HandleFunc("/share/{token}", shareHandler).Methods("GET")
+	router.HandleFunc("/share/{token}", sharePasswordHandler).Methods("POST")
+	router.HandleFunc("/share/{token}/{note:[0-9]+}", shareHandler).Methods("GET")
+	router.HandleFunc("/share/{token}/resource/{resource:[0-9]+}", shareResourceHandler).Methods("GET")
+}
+
+// shareHandler - show shared note (or note of shared subtree)
+func shareHandler(w http.ResponseWriter, r *http.Request) {
+	share, root, ok := loadShare(w, r)
+	if !ok {
+		return
+	}
+
+	// Password is entered in this browser
+	base := services.SharePath(r.Context(), mux.Vars(r)["token"])
+	if !hasShareAccess(r, share) {
+		renderSharePage(w, http.StatusUnauthorized, sharePage{Title: "Protected note", Base: base, Password: true})
+		return
+	}
+
+	id := root.ID
+	if value, ok := mux.Vars(r)["note"]; ok {
+		id, _ = strconv.ParseInt(value, 10, 64)
+	}
+	note, err := services.GetSharedNote(r.Context(), share, root, id)
+	if err != nil {
+		renderShareNotFound(w)
+		return
+	}
+
+	if err := services.TouchShare(r.Context(), share); err != nil {
+		slog.Error("Failed to count share link access", "share", share.ID, "error", err)
+	}
+
+	page := sharePage{Title: note.Title, Base: base}
+
+	// Tree of subtree link
+	if share.Subtree {
+		notes, err := services.GetSharedNotes(r.Context(), share, root)
+		if err != nil {
+			renderSharePage(w, http.StatusInternalServerError, sharePage{Title: "Error", Message: "Failed to load notes."})
+			return
+		}
+		for _, item := range notes {
+			page.Notes = append(page.Notes, shareItem{
+				ID:      item.ID,
+				Title:   item.Title,
+				Indent:  item.Depth - root.Depth,
+				Current: item.ID == note.ID,
+			})
+		}
+	}
+
+	// Contents (encrypted notes are never decrypted for visitors)
+	contents, err := services.RenderNote(r.Context(), note)
+	switch {
+	case errors.Is(err, services.ErrLocked):
+		page.Message = "This note is encrypted."
+	case err != nil:
+		page.Message = "This note could not be shown here."
+	default:
+		// Attached files are served by share link
+		contents = strings.ReplaceAll(contents, `"/api/resource/`, `"`+base+`/resource/`)
+		page.Contents = template.HTML(contents)
+	}
+
+	renderSharePage(w, http.StatusOK, page)
+}
+
+// sharePasswordHandler - check password of share link and remember it in cookie
+func sharePasswordHandler(w http.ResponseWriter, r *http.Request) {
+	share, _, ok := loadShare(w, r)
+	if !ok {
+		return
+	}
+	base := services.SharePath(r.Context(), mux.Vars(r)["token"])
+
+	// Same limit as for login
+	if allowed, _ := auth.LoginAllowed(r); !allowed {
+		renderSharePage(w, http.StatusTooManyRequests, sharePage{Title: "Protected note", Base: base, Password: true,
+			Message: "Too many wrong passwords, try again later."})
+		return
+	}
+
+	if !auth.CheckSharePassword(share, r.FormValue("password")) {
+		auth.LoginFailed(r)
+		renderSharePage(w, http.StatusUnauthorized, sharePage{Title: "Protected note", Base: base, Password: true,
+			Message: "Wrong password."})
+		return
+	}
+	auth.LoginSucceeded(r)
+
+	value, expires, err := services.CreateShareSession(share)
+	if err != nil {
+		renderSharePage(w, http.StatusInternalServerError, sharePage{Title: "Error", Message: "Failed to open note."})
+		return
+	}
+
+	// Cookie works just for this link
+	http.SetCookie(w, &http.Cookie{
+		Name:     shareCookie(share),
+		Value:    value,
+		Path:     base,
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteStrictMode,
+	})
+	http.Redirect(w, r, base, http.StatusSeeOther)
+}
+
+// shareResourceHandler - download attached file of shared note
+func shareResourceHandler(w http.ResponseWriter, r *http.Request) {
+	share, root, ok := loadShare(w, r)
+	if !ok {
+		return
+	}
+	if !hasShareAccess(r, share) {
+		renderShareNotFound(w)
+		return
+	}
+
+	id, _ := strconv.Atoi(mux.Vars(r)["resource"])
+	resource, err := services.GetResource(r.Context(), id)
+	if err != nil {
+		renderShareNotFound(w)
+		return
+	}
+	note, err := services.GetSharedNote(r.Context(), share, root, resource.NoteID)
+	if err != nil || note.Encrypted {
+		renderShareNotFound(w)
+		return
+	}
+
+	// Images are shown inline, other files are downloaded
+	disposition := "attachment"
+	if strings.HasPrefix(resource.Mime, "image/") && resource.Mime != "image/svg+xml" {
+		disposition = "inline"
+	}
+
+	// Send response
+	setShareHeaders(w)
+	w.Header().Set("Content-Type", resource.Mime)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": resource.Name}))
+	w.Header().Set("Content-Length", fmt.Sprintf("%d", len(resource.Data)))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Write(resource.Data)
+}
+
+// loadShare - get share link by token from URL and its note, respond with 404 if link is wrong, revoked or expired
+func loadShare(w http.ResponseWriter, r *http.Request) (models.Share, models.NoteDB, bool) {
+	share, err := services.GetShareByHash(r.Context(), auth.HashToken(mux.Vars(r)["token"]))
+	if err != nil {
+		renderShareNotFound(w)
+		return models.Share{}, models.NoteDB{}, false
+	}
+
+	root, err := services.GetSharedRoot(r.Context(), share)
+	if err != nil {
+		renderShareNotFound(w)
+		return models.Share{}, models.NoteDB{}, false
+	}
+
+	return share, root, true
+}
+
+// hasShareAccess - check share link has no password or it is entered (cookie)
+func hasShareAccess(r *http.Request, share models.Share) bool {
+	if share.Password == "" {
+		return true
+	}
+	cookie, err := r.Cookie(shareCookie(share))
+	return err == nil && services.CheckShareSession(share, cookie.Value)
+}
+
+// shareCookie - name of cookie with entered password of share link
+func shareCookie(share models.Share) string {
+	return fmt.Sprintf("tetrad_share_%d", share.ID)
+}
+
+// renderShareNotFound - same answer for wrong, revoked and expired links
+func renderShareNotFound(w http.ResponseWriter) {
+	renderSharePage(w, http.StatusNotFound, sharePage{Title: "Not found", Message: "This link does not exist or has expired."})
+}
+
+// renderSharePage - send share page
+func renderSharePage(w http.ResponseWriter, status int, page sharePage) {
+	setShareHeaders(w)
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	if err := shareTemplate.Execute(w, page); err != nil {
+		slog.Error("Failed to render share page", "error", err)
+	}
+}
+
+// setShareHeaders - headers of share pages (no notebook details, unlike API responses)
+func setShareHeaders(w http.ResponseWriter) {
+	w.Header().Set("Server", meta.Server)
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Security-Policy", shareCSP)
+	w.Header().Set("X-Frame-Options", "DENY")
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	w.Header().Set("X-Robots-Tag", "noindex, nofollow")
+}
@@ -0,0 +1,208 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sondrus/tetrad/database"
+	"github.com/sondrus/tetrad/models"
+)
+
+// Audited operations
+const (
+	AuditNoteCreate     = "note.create"
+	AuditNoteUpdate     = "note.update"
+	AuditNoteMove       = "note.move"
+	AuditNoteDelete     = "note.delete"
+	AuditNoteEncrypt    = "note.encrypt"
+	AuditNoteDecrypt    = "note.decrypt"
+	AuditNotePermission = "note.permission"
+	AuditNotesImport    = "notes.import"
+	AuditAppData        = "app.data"
+	AuditAppGrant       = "app.grant"
+	AuditSettings       = "settings.update"
+	AuditVacuum         = "database.vacuum"
+	AuditNotebookCreate = "notebook.create"
+	AuditNotebookOpen   = "notebook.open"
+	AuditNotebookClose  = "notebook.close"
+	AuditNotebookRemove = "notebook.remove"
+	AuditLogging        = "server.logging"
+	AuditUserCreate     = "user.create"
+	AuditUserUpdate     = "user.update"
+	AuditUserDelete     = "user.delete"
+	AuditTokenCreate    = "token.create"
+	AuditTokenDelete    = "token.delete"
+	AuditShareCreate    = "share.create"
+	AuditShareRevoke    = "share.revoke"
+)
+
+// commandAddress - address of audit records made by command-line subcommands
+const commandAddress = "cli"
+
+// AuditEntry - operation details for audit log
+type AuditEntry struct {
+	Operation string
+	NoteIDs   []int64
+	Fields    []string
+	Details   string
+}
+
+// AuditFilter - filters for audit log query (zero values are ignored)
+type AuditFilter struct {
+	NoteID     int64
+	From       int64
+	To         int64
+	Operations []string
+	UserID     int64
+	Notebook   string
+	Limit      int
+	Offset     int
+}
+
+// ClientAddress - get client IP address (without port)
+func ClientAddress(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// Audit - record mutating operation made by request (failures are logged, never break the request)
+func Audit(r *http.Request, entry AuditEntry) {
+	writeAudit(r.Context(), ClientAddress(r), entry)
+}
+
+// AuditCommand - record mutating operation made by command-line subcommand
+func AuditCommand(ctx context.Context, entry AuditEntry) {
+	writeAudit(ctx, commandAddress, entry)
+}
+
+// writeAudit - save audit record with user and token from context
+func writeAudit(ctx context.Context, address string, entry AuditEntry) {
+	record := models.AuditRecord{
+		Date:      time.Now().Unix(),
+		Operation: entry.Operation,
+		NoteList:  joinIDs(entry.NoteIDs),
+		FieldList: strings.Join(entry.Fields, ","),
+		Details:   entry.Details,
+		Address:   address,
+		Notebook:  database.GetContextNotebook(ctx).Name,
+	}
+	if user := GetContextUser(ctx); user != nil {
+		record.UserID = user.ID
+		record.UserName = user.Name
+	}
+	if token := GetContextToken(ctx); token != nil {
+		record.TokenID = token.ID
+	}
+
+	if err := database.GetORM().Create(&record).Error; err != nil {
+		slog.Error("Failed to write audit log", "operation", entry.Operation, "error", err)
+	}
+}
+
+// GetAuditRecords - get audit log records (newest first) with total count for filter
+func GetAuditRecords(filter AuditFilter) ([]models.AuditRecord, int64, error) {
+	query := database.GetORM().Model(&models.AuditRecord{})
+	if filter.NoteID > 0 {
+		query = query.Where("NOTE_IDS LIKE ?", fmt.Sprintf("%%,%d,%%", filter.NoteID))
+	}
+	if filter.From > 0 {
+		query = query.Where("DATE >= ?", filter.From)
+	}
+	if filter.To > 0 {
+		query = query.Where("DATE <= ?", filter.To)
+	}
+	if len(filter.Operations) > 0 {
+		query = query.Where("OPERATION IN ?", filter.Operations)
+	}
+	if filter.UserID > 0 {
+		query = query.Where("USER_ID = ?", filter.UserID)
+	}
+	if filter.Notebook != "" {
+		query = query.Where("NOTEBOOK = ?", filter.Notebook)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var records []models.AuditRecord
+	err := query.Order("ID DESC").Limit(filter.Limit).Offset(filter.Offset).Find(&records).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	for i := range records {
+		records[i].NoteIDs = splitIDs(records[i].NoteList)
+		records[i].Fields = []string{}
+		if records[i].FieldList != "" {
+			records[i].Fields = strings.Split(records[i].FieldList, ",")
+		}
+	}
+
+	return records, total, nil
+}
+
+// CleanupAudit - delete audit records older than retention period (0 days = keep forever)
+func CleanupAudit(retentionDays int) (int64, error) {
+	if retentionDays <= 0 {
+		return 0, nil
+	}
+
+	before := time.Now().AddDate(0, 0, -retentionDays).Unix()
+	result := database.GetORM().Where("DATE < ?", before).Delete(&models.AuditRecord{})
+	return result.RowsAffected, result.Error
+}
+
+// ScheduleAuditCleanup - apply retention period now and then once a day
+func ScheduleAuditCleanup(retentionDays int) {
+	if retentionDays <= 0 {
+		return
+	}
+
+	cleanup := func() {
+		if _, err := CleanupAudit(retentionDays); err != nil {
+			slog.Error("Failed to clean up audit log", "error", err)
+		}
+	}
+
+	cleanup()
+	go func() {
+		for range time.Tick(24 * time.Hour) {
+			cleanup()
+		}
+	}()
+}
+
+// joinIDs - note IDs => ",1,2,3," (empty string for no IDs)
+func joinIDs(ids []int64) string {
+	if len(ids) == 0 {
+		return ""
+	}
+
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = strconv.FormatInt(id, 10)
+	}
+	return "," + strings.Join(parts, ",") + ","
+}
+
+// splitIDs - ",1,2,3," => note IDs
+func splitIDs(list string) []int64 {
+	ids := []int64{}
+	for _, part := range strings.Split(strings.Trim(list, ","), ",") {
+		if id, err := strconv.ParseInt(part, 10, 64); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
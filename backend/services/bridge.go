@@ -0,0 +1,101 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sondrus/tetrad/database"
+	"github.com/sondrus/tetrad/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// BridgeDataLimit - max size of JSON data of single IFRAME note
+const BridgeDataLimit = 1 << 20
+
+// GetBridgeData - get JSON data of IFRAME note (null if not saved yet)
+func GetBridgeData(ctx context.Context, appID int64) (json.RawMessage, error) {
+	var rows []models.BridgeData
+	if err := database.GetContextORM(ctx).Where("NOTE_ID = ?", appID).Limit(1).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return json.RawMessage("null"), nil
+	}
+	return json.RawMessage(rows[0].Data), nil
+}
+
+// SaveBridgeData - save JSON data of IFRAME note
+func SaveBridgeData(ctx context.Context, appID int64, data json.RawMessage) error {
+	if len(data) > BridgeDataLimit {
+		return fmt.Errorf("data is too large (limit is %d bytes)", BridgeDataLimit)
+	}
+	if !json.Valid(data) {
+		return errors.New("data is not valid JSON")
+	}
+
+	row := models.BridgeData{
+		NoteID:       appID,
+		Data:         string(data),
+		DateModified: time.Now().Unix(),
+	}
+	return database.GetContextORM(ctx).Clauses(clause.OnConflict{UpdateAll: true}).Create(&row).Error
+}
+
+// GetBridgeGrants - get notes which IFRAME note is allowed to read
+func GetBridgeGrants(ctx context.Context, appID int64) ([]models.BridgeGrant, error) {
+	grants := []models.BridgeGrant{}
+	err := database.GetContextORM(ctx).
+		Table("bridge_grants AS g").
+		Select("g.*, n.TITLE AS NOTE_TITLE").
+		Joins("JOIN notes AS n ON n.ID = g.NOTE_ID").
+		Where("g.APP_ID = ?", appID).
+		Order("g.ID ASC").
+		Scan(&grants).Error
+	if err != nil {
+		return nil, err
+	}
+	return grants, nil
+}
+
+// SetBridgeGrant - allow (or disallow) IFRAME note to read note with its children
+func SetBridgeGrant(ctx context.Context, appID int64, noteID int64, allow bool) error {
+	db := database.GetContextORM(ctx)
+
+	if !allow {
+		return db.Where("APP_ID = ? AND NOTE_ID = ?", appID, noteID).Delete(&models.BridgeGrant{}).Error
+	}
+
+	grant := models.BridgeGrant{AppID: appID, NoteID: noteID, DateCreated: time.Now().Unix()}
+	return db.Clauses(clause.OnConflict{DoNothing: true}).Create(&grant).Error
+}
+
+// HasBridgeGrant - check IFRAME note is allowed to read note (grant for note itself or one of parents)
+func HasBridgeGrant(ctx context.Context, appID int64, note models.NoteDB) bool {
+	// Own contents is always readable
+	if appID == note.ID {
+		return true
+	}
+
+	var count int64
+	database.GetContextORM(ctx).
+		Table("bridge_grants AS g").
+		Joins("JOIN notes AS n ON n.ID = g.NOTE_ID").
+		Where(`g.APP_ID = ? AND n."LEFT" <= ? AND n."RIGHT" >= ?`, appID, note.Left, note.Right).
+		Count(&count)
+
+	return count > 0
+}
+
+// DeleteBridgeRecords - delete data and grants of deleted notes (as apps and as granted notes)
+func DeleteBridgeRecords(tx *gorm.DB, left int64, right int64) error {
+	ids := tx.Model(&models.NoteDB{}).Select("ID").Where(`"LEFT" >= ? AND "RIGHT" <= ?`, left, right)
+
+	if err := tx.Where("NOTE_ID IN (?)", ids).Delete(&models.BridgeData{}).Error; err != nil {
+		return err
+	}
+	return tx.Where("APP_ID IN (?) OR NOTE_ID IN (?)", ids, ids).Delete(&models.BridgeGrant{}).Error
+}
@@ -0,0 +1,111 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OptionCapabilitySecret - server option with key for sign capabilities
+const OptionCapabilitySecret = "server.capability.secret"
+
+// Capability - signed permission for IFRAME note, works without session cookie (separate origin)
+type Capability struct {
+	NoteID   int64  `json:"n"`
+	UserID   int64  `json:"u"`
+	Notebook string `json:"b,omitempty"` // empty = main database
+	Parent   string `json:"p"`           // origin of main application
+	Expires  int64  `json:"e"`
+}
+
+var (
+	capabilitySecret      []byte
+	capabilitySecretMutex sync.Mutex
+)
+
+// getCapabilitySecret - load or create key for sign capabilities (kept in database, so survives restart)
+func getCapabilitySecret() ([]byte, error) {
+	capabilitySecretMutex.Lock()
+	defer capabilitySecretMutex.Unlock()
+
+	if capabilitySecret != nil {
+		return capabilitySecret, nil
+	}
+
+	if value, ok := GetServerOption(OptionCapabilitySecret); ok {
+		if secret, err := hex.DecodeString(value); err == nil && len(secret) == 32 {
+			capabilitySecret = secret
+			return secret, nil
+		}
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	if err := SetServerOption(OptionCapabilitySecret, hex.EncodeToString(secret)); err != nil {
+		return nil, err
+	}
+	capabilitySecret = secret
+
+	return secret, nil
+}
+
+// signCapability - HMAC of payload
+func signCapability(secret []byte, payload string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// CreateCapability - create signed capability token with lifetime
+func CreateCapability(c Capability, ttl time.Duration) (string, error) {
+	secret, err := getCapabilitySecret()
+	if err != nil {
+		return "", err
+	}
+
+	c.Expires = time.Now().Add(ttl).Unix()
+	data, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+
+	payload := base64.RawURLEncoding.EncodeToString(data)
+	return payload + "." + signCapability(secret, payload), nil
+}
+
+// ParseCapability - check signature and lifetime of capability token
+func ParseCapability(token string) (*Capability, error) {
+	secret, err := getCapabilitySecret()
+	if err != nil {
+		return nil, err
+	}
+
+	payload, signature, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(signCapability(secret, payload))) {
+		return nil, errors.New("invalid capability")
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, errors.New("invalid capability")
+	}
+
+	var c Capability
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, errors.New("invalid capability")
+	}
+	if time.Now().Unix() >= c.Expires {
+		return nil, errors.New("capability is expired")
+	}
+
+	return &c, nil
+}
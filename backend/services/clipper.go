@@ -0,0 +1,165 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/sondrus/tetrad/clipper"
+	"github.com/sondrus/tetrad/database"
+	"github.com/sondrus/tetrad/events"
+	"github.com/sondrus/tetrad/htmlmd"
+	"github.com/sondrus/tetrad/models"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrNotURLNote - note is not URL note or it has no URL
+	ErrNotURLNote = errors.New("note has no URL")
+
+	// ErrNoteChanged - note contents or URL are changed while page was fetched, archived copy is not saved
+	ErrNoteChanged = errors.New("note is changed while page was fetched")
+)
+
+// ClipNote - fetch page of URL note and save its readable copy as note contents, with page icon and date of copy
+// Title is set from page just if note has no own title (empty or the same as URL). Returns changed fields
+// Fetching could take long, so note is read again before saving: edits made meanwhile are never overwritten
+func ClipNote(ctx context.Context, keyring *Keyring, id int64, opts clipper.Options) ([]string, error) {
+	note, err := GetUnlockedNote(ctx, keyring, int(id))
+	if err != nil {
+		return nil, err
+	}
+	if note.Type != "URL" || strings.TrimSpace(note.URL) == "" {
+		return nil, ErrNotURLNote
+	}
+	if note.Locked {
+		return nil, ErrLocked
+	}
+
+	// Stored (maybe encrypted) state of note before fetching
+	var before models.NoteDB
+	if err := database.GetContextORM(ctx).Select("ID", "CONTENTS").First(&before, note.ID).Error; err != nil {
+		return nil, err
+	}
+
+	page, err := clipper.Clip(ctx, note.URL, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// Archived copy starts with its source and date
+	contents := fmt.Sprintf("> Archived from %s on %s\n\n%s", htmlmd.Link(page.Title, page.URL),
+		page.Date.Format("2006-01-02 15:04"), page.Markdown)
+	vault, err := GetVaultByNote(ctx, note)
+	if err != nil {
+		return nil, err
+	}
+	contents, err = SealContents(keyring, vault, contents)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().Unix()
+	fields := map[string]any{
+		"CONTENTS":      contents,
+		"DATE_ARCHIVED": page.Date.Unix(),
+		"DATE_MODIFIED": now,
+	}
+
+	err = database.GetContextORM(ctx).Transaction(func(tx *gorm.DB) error {
+		var current models.NoteDB
+		if err := tx.Select("ID", "TYPE", "TITLE", "URL", "CONTENTS", "FAVICON").First(&current, note.ID).Error; err != nil {
+			return err
+		}
+
+		// Edited contents or other URL: copy is not saved; other changes (e.g. title) are kept
+		// Values are compared, modification date has one-second resolution
+		if current.Contents != before.Contents || current.URL != note.URL || current.Type != note.Type {
+			return ErrNoteChanged
+		}
+		title := strings.TrimSpace(current.Title)
+		if title == "" || title == strings.TrimSpace(current.URL) {
+			fields["TITLE"] = page.Title
+		}
+
+		// Icon is replaced by new one
+		if current.Favicon > 0 {
+			if err := tx.Where("ID = ? AND NOTE_ID = ?", current.Favicon, note.ID).Delete(&models.Resource{}).Error; err != nil {
+				return err
+			}
+			fields["FAVICON"] = 0
+		}
+		if page.Favicon != nil {
+			resource := models.Resource{
+				NoteID:      note.ID,
+				Name:        page.Favicon.Name,
+				Mime:        page.Favicon.Mime,
+				Data:        page.Favicon.Data,
+				DateCreated: page.Date.Unix(),
+			}
+			if err := tx.Create(&resource).Error; err != nil {
+				return err
+			}
+			fields["FAVICON"] = resource.ID
+		}
+
+		if err := tx.Model(&models.NoteDB{}).Where("ID = ?", note.ID).Updates(fields).Error; err != nil {
+			return err
+		}
+		return indexTasks(tx, note.ID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Nested set is sorted by title
+	if _, ok := fields["TITLE"]; ok {
+		if err := RebuildNotesTree(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	PublishEvent(ctx, events.Event{
+		Type:         events.Updated,
+		NoteIDs:      []int64{note.ID},
+		ParentID:     note.ParentID,
+		DateModified: now,
+	})
+
+	changed := make([]string, 0, len(fields))
+	for field := range fields {
+		if field != "DATE_MODIFIED" {
+			changed = append(changed, field)
+		}
+	}
+	slices.Sort(changed)
+	return changed, nil
+}
+
+// ClipNoteInBackground - archive page of URL note without waiting for it, note update is published when it is done
+func ClipNoteInBackground(r *http.Request, id int64, opts clipper.Options) {
+	ctx := context.WithoutCancel(r.Context())
+	address := ClientAddress(r)
+
+	go func() {
+		start := time.Now()
+		fields, err := ClipNote(ctx, GetContextKeyring(ctx), id, opts)
+		if err != nil {
+			slog.Warn("Failed to archive page of note", "note", id, "error", err)
+			return
+		}
+		slog.Debug("Page of note archived", "note", id, "duration", time.Since(start))
+
+		writeAudit(ctx, address, AuditEntry{
+			Operation: AuditNoteUpdate,
+			NoteIDs:   []int64{id},
+			Fields:    fields,
+			Details:   "web clipper",
+		})
+	}()
+}
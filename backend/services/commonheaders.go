@@ -8,16 +8,18 @@ import (
 	"github.com/sondrus/tetrad/meta"
 )
 
-// SetCommonResponseHeaders - set common HTTP headers to response
-func SetCommonResponseHeaders(w http.ResponseWriter) {
+// SetCommonResponseHeaders - set common HTTP headers to response (database is notebook of request)
+func SetCommonResponseHeaders(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Pragma", "no-cache")
 	w.Header().Set("Server", meta.Server)
 
 	w.Header().Set("Content-Security-Policy", "frame-ancestors 'self'")
 	w.Header().Set("X-Frame-Options", "SAMEORIGIN")
 
-	w.Header().Set("X-Database-File", database.GetFilepath())
-	w.Header().Set("X-Database-Size", strconv.FormatInt(database.GetFilesize(), 10))
+	notebook := database.GetContextNotebook(r.Context())
+	w.Header().Set("X-Notebook", notebook.Name)
+	w.Header().Set("X-Database-File", notebook.Path)
+	w.Header().Set("X-Database-Size", strconv.FormatInt(notebook.Filesize(), 10))
 }
 
 // SetAboutResponseHeaders - set `About` HTTP headers to response
@@ -0,0 +1,425 @@
+package services
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sondrus/tetrad/database"
+	"github.com/sondrus/tetrad/models"
+	"golang.org/x/crypto/scrypt"
+	"gorm.io/gorm"
+)
+
+// EncryptedPrefix - prefix of encrypted contents: $tetrad-aesgcm$v1$<vault ID>$<base64(nonce + ciphertext)>
+const EncryptedPrefix = "$tetrad-aesgcm$v1$"
+
+// Key derivation parameters (scrypt, recommended values for interactive logins)
+const (
+	kdfName    = "scrypt"
+	kdfN       = 1 << 15
+	kdfR       = 8
+	kdfP       = 1
+	kdfKeySize = 32
+	kdfSalt    = 16
+)
+
+// vaultCheck - known plaintext, encrypted with vault key to verify passphrase
+const vaultCheck = "tetrad-vault"
+
+var (
+	// ErrLocked - encrypted note is not unlocked in current session
+	ErrLocked = errors.New("encrypted note is locked")
+
+	// ErrWrongPassphrase - passphrase does not match vault
+	ErrWrongPassphrase = errors.New("wrong passphrase")
+)
+
+// Keyring - vault keys unlocked in single session
+type Keyring struct {
+	mutex sync.Mutex
+	keys  map[int64][]byte
+}
+
+// NewKeyring - create empty keyring
+func NewKeyring() *Keyring {
+	return &Keyring{keys: make(map[int64][]byte)}
+}
+
+// Get - get key for vault (nil if locked)
+func (k *Keyring) Get(vaultID int64) []byte {
+	if k == nil {
+		return nil
+	}
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+	return k.keys[vaultID]
+}
+
+// Set - save unlocked key for vault
+func (k *Keyring) Set(vaultID int64, key []byte) {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+	k.keys[vaultID] = key
+}
+
+// Remove - forget key for vault
+func (k *Keyring) Remove(vaultID int64) {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+	delete(k.keys, vaultID)
+}
+
+// Unlocked - IDs of unlocked vaults
+func (k *Keyring) Unlocked() []int64 {
+	ids := []int64{}
+	if k == nil {
+		return ids
+	}
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+	for id := range k.keys {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// GetContextKeyring - get keyring of current session from context (nil if nothing is unlocked)
+func GetContextKeyring(ctx context.Context) *Keyring {
+	keyring, _ := ctx.Value(database.KeyringKey).(*Keyring)
+	return keyring
+}
+
+// IsEncrypted - check stored contents is encrypted
+func IsEncrypted(contents string) bool {
+	return strings.HasPrefix(contents, EncryptedPrefix)
+}
+
+// deriveKey - get AES key from passphrase
+func deriveKey(passphrase string, salt []byte) ([]byte, error) {
+	return scrypt.Key([]byte(passphrase), salt, kdfN, kdfR, kdfP, kdfKeySize)
+}
+
+// sealWithKey - encrypt text with AES-GCM, returns base64(nonce + ciphertext)
+func sealWithKey(key []byte, plaintext string) (string, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	sealed := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// openWithKey - decrypt base64(nonce + ciphertext) with AES-GCM
+func openWithKey(key []byte, data string) (string, error) {
+	sealed, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return "", err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return "", errors.New("encrypted data is too short")
+	}
+
+	plaintext, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", err
+	}
+
+	return string(plaintext), nil
+}
+
+// parseEncrypted - get vault ID and data from stored contents
+func parseEncrypted(contents string) (int64, string, error) {
+	idStr, data, ok := strings.Cut(strings.TrimPrefix(contents, EncryptedPrefix), "$")
+	if !ok {
+		return 0, "", errors.New("invalid encrypted contents")
+	}
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		return 0, "", errors.New("invalid encrypted contents")
+	}
+	return id, data, nil
+}
+
+// encryptContents - encrypt contents for storage in vault
+func encryptContents(vaultID int64, key []byte, contents string) (string, error) {
+	data, err := sealWithKey(key, contents)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s%d$%s", EncryptedPrefix, vaultID, data), nil
+}
+
+// decryptContents - decrypt stored contents with keys from keyring
+func decryptContents(keyring *Keyring, contents string) (string, error) {
+	vaultID, data, err := parseEncrypted(contents)
+	if err != nil {
+		return "", err
+	}
+
+	key := keyring.Get(vaultID)
+	if key == nil {
+		return "", ErrLocked
+	}
+
+	return openWithKey(key, data)
+}
+
+// decryptNotes - decrypt contents of notes (locked notes get empty contents)
+func decryptNotes(keyring *Keyring, notes []models.NoteDB) {
+	for i := range notes {
+		note := &notes[i]
+		if !IsEncrypted(note.Contents) {
+			continue
+		}
+
+		note.Encrypted = true
+		contents, err := decryptContents(keyring, note.Contents)
+		if err != nil {
+			note.Locked = true
+			note.Contents = ""
+			note.ContentsLength = 0
+			continue
+		}
+		note.Contents = contents
+		note.ContentsLength = int64(len(contents))
+	}
+}
+
+// GetVaults - get all encrypted subtrees
+func GetVaults(ctx context.Context) ([]models.Vault, error) {
+	vaults := []models.Vault{}
+	if err := database.GetContextORM(ctx).Order("ID ASC").Find(&vaults).Error; err != nil {
+		return nil, err
+	}
+	return vaults, nil
+}
+
+// GetVaultByNote - get vault which contains note (note itself or one of parents)
+func GetVaultByNote(ctx context.Context, note models.NoteDB) (*models.Vault, error) {
+	var vaults []models.Vault
+	err := database.GetContextORM(ctx).
+		Table("vaults AS v").
+		Select("v.*").
+		Joins("JOIN notes AS n ON n.ID = v.NOTE_ID").
+		Where(`n."LEFT" <= ? AND n."RIGHT" >= ?`, note.Left, note.Right).
+		Limit(1).
+		Scan(&vaults).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(vaults) == 0 {
+		return nil, nil
+	}
+	return &vaults[0], nil
+}
+
+// GetVaultByParent - get vault for new note with parent (nil if parent is not encrypted)
+func GetVaultByParent(ctx context.Context, parentID int64) (*models.Vault, error) {
+	if parentID == 0 {
+		return nil, nil
+	}
+	parent, err := GetNote(ctx, int(parentID))
+	if err != nil {
+		return nil, err
+	}
+	return GetVaultByNote(ctx, parent)
+}
+
+// UnlockVault - check passphrase and get key of vault
+func UnlockVault(vault *models.Vault, passphrase string) ([]byte, error) {
+	if vault.KDF != kdfName {
+		return nil, fmt.Errorf("unsupported key derivation %s", vault.KDF)
+	}
+
+	salt, err := base64.StdEncoding.DecodeString(vault.Salt)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := deriveKey(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	check, err := openWithKey(key, vault.Check)
+	if err != nil || subtle.ConstantTimeCompare([]byte(check), []byte(vaultCheck)) != 1 {
+		return nil, ErrWrongPassphrase
+	}
+
+	return key, nil
+}
+
+// subtreeNotes - get ID and contents of note and its children
+func subtreeNotes(tx *gorm.DB, note models.NoteDB) ([]models.NoteDB, error) {
+	var notes []models.NoteDB
+	err := tx.Model(&models.NoteDB{}).
+		Select("ID", "CONTENTS").
+		Where(`"LEFT" >= ? AND "RIGHT" <= ?`, note.Left, note.Right).
+		Find(&notes).Error
+	return notes, err
+}
+
+// EncryptSubtree - create vault for note and encrypt contents of note with children
+func EncryptSubtree(ctx context.Context, note models.NoteDB, passphrase string) (*models.Vault, []byte, error) {
+	if len(passphrase) < 8 {
+		return nil, nil, errors.New("passphrase must be at least 8 characters")
+	}
+
+	// Nested vaults are not allowed (neither inside, nor around existing vault)
+	if existing, err := GetVaultByNote(ctx, note); err != nil || existing != nil {
+		return nil, nil, errors.New("note is already encrypted")
+	}
+	var inside int64
+	database.GetContextORM(ctx).
+		Table("vaults AS v").
+		Joins("JOIN notes AS n ON n.ID = v.NOTE_ID").
+		Where(`n."LEFT" > ? AND n."RIGHT" < ?`, note.Left, note.Right).
+		Count(&inside)
+	if inside > 0 {
+		return nil, nil, errors.New("subtree already contains encrypted notes")
+	}
+
+	// Derive key
+	salt := make([]byte, kdfSalt)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, nil, err
+	}
+	key, err := deriveKey(passphrase, salt)
+	if err != nil {
+		return nil, nil, err
+	}
+	check, err := sealWithKey(key, vaultCheck)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	vault := models.Vault{
+		NoteID:      note.ID,
+		KDF:         kdfName,
+		Salt:        base64.StdEncoding.EncodeToString(salt),
+		Check:       check,
+		DateCreated: time.Now().Unix(),
+	}
+
+	err = database.GetContextORM(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&vault).Error; err != nil {
+			return err
+		}
+
+		notes, err := subtreeNotes(tx, note)
+		if err != nil {
+			return err
+		}
+		for _, n := range notes {
+			contents, err := encryptContents(vault.ID, key, n.Contents)
+			if err != nil {
+				return err
+			}
+			if err := tx.Model(&models.NoteDB{}).Where("ID = ?", n.ID).Update("CONTENTS", contents).Error; err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	forgetRendered(ctx)
+	IndexSubtreeTasks(ctx, note)
+
+	return &vault, key, nil
+}
+
+// DecryptSubtree - decrypt contents of vault notes and remove vault
+func DecryptSubtree(ctx context.Context, vault *models.Vault, key []byte) error {
+	note, err := GetNote(ctx, int(vault.NoteID))
+	if err != nil {
+		return err
+	}
+
+	err = database.GetContextORM(ctx).Transaction(func(tx *gorm.DB) error {
+		notes, err := subtreeNotes(tx, note)
+		if err != nil {
+			return err
+		}
+		for _, n := range notes {
+			if !IsEncrypted(n.Contents) {
+				continue
+			}
+			vaultID, data, err := parseEncrypted(n.Contents)
+			if err != nil {
+				return err
+			}
+			if vaultID != vault.ID {
+				continue
+			}
+			contents, err := openWithKey(key, data)
+			if err != nil {
+				return fmt.Errorf("failed to decrypt note %d: %w", n.ID, err)
+			}
+			if err := tx.Model(&models.NoteDB{}).Where("ID = ?", n.ID).Update("CONTENTS", contents).Error; err != nil {
+				return err
+			}
+		}
+
+		return tx.Delete(&models.Vault{}, vault.ID).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	// Decrypted notes have tasks again
+	return IndexSubtreeTasks(ctx, note)
+}
+
+// SealContents - prepare contents for storage: encrypt if vault is set (ErrLocked if vault is not unlocked)
+func SealContents(keyring *Keyring, vault *models.Vault, contents string) (string, error) {
+	if vault == nil {
+		return contents, nil
+	}
+
+	key := keyring.Get(vault.ID)
+	if key == nil {
+		return "", ErrLocked
+	}
+
+	return encryptContents(vault.ID, key, contents)
+}
+
+// DeleteVaults - remove vaults of deleted notes
+func DeleteVaults(tx *gorm.DB, left int64, right int64) error {
+	return tx.Where("NOTE_ID IN (?)",
+		tx.Model(&models.NoteDB{}).Select("ID").Where(`"LEFT" >= ? AND "RIGHT" <= ?`, left, right),
+	).Delete(&models.Vault{}).Error
+}
@@ -4,11 +4,13 @@ import (
 	"encoding/json"
 	"net/http"
 
+	"github.com/sondrus/tetrad/logging"
 	"github.com/sondrus/tetrad/models"
 )
 
 // RespondWithError - helper function to send error response
 func RespondWithError(w http.ResponseWriter, status int, message string, err error) {
+	logging.RecordError(w, message, err)
 	w.WriteHeader(status)
 
 	// Create error response
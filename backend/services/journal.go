@@ -0,0 +1,287 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sondrus/tetrad/models"
+)
+
+// JournalDateLayout - layout of dates in journal API
+const JournalDateLayout = "2006-01-02"
+
+// ErrJournalNotFound - there is no journal note for date
+var ErrJournalNotFound = errors.New("journal entry not found")
+
+// journalMutex - journal notes are created once, even for parallel requests
+var journalMutex sync.Mutex
+
+// JournalSettings - journal options (settings `journal.*`, user settings override shared ones)
+type JournalSettings struct {
+	Root        string // path of journal root note, eg `Journal` or `Work/Log`
+	YearFormat  string // title of year notes
+	MonthFormat string // title of month notes
+	DayFormat   string // title of day notes, it must contain full date
+	Template    int64  // template note ID for day notes (0 = empty note)
+}
+
+// JournalEntry - date with journal note
+type JournalEntry struct {
+	Date string `json:"date"`
+	ID   int64  `json:"id"`
+}
+
+// GetJournalSettings - load journal settings of user with defaults
+func GetJournalSettings(ctx context.Context, user *models.User) (JournalSettings, error) {
+	journal := JournalSettings{
+		Root:        "Journal",
+		YearFormat:  "2006",
+		MonthFormat: "01 January",
+		DayFormat:   "2006-01-02 Monday",
+	}
+
+	settings, err := LoadSettings(ctx, user)
+	if err != nil {
+		return journal, err
+	}
+	options, _ := settings["journal"].(map[string]any)
+
+	for name, target := range map[string]*string{
+		"root":        &journal.Root,
+		"yearFormat":  &journal.YearFormat,
+		"monthFormat": &journal.MonthFormat,
+		"dayFormat":   &journal.DayFormat,
+	} {
+		if value, ok := options[name].(string); ok && strings.TrimSpace(value) != "" {
+			*target = strings.TrimSpace(value)
+		}
+	}
+
+	switch value := options["template"].(type) {
+	case int:
+		journal.Template = int64(value)
+	case float64:
+		journal.Template = int64(value)
+	case string:
+		journal.Template, _ = strconv.ParseInt(value, 10, 64)
+	}
+
+	// Date must be restored from title of day note
+	sample := time.Date(2001, 2, 3, 0, 0, 0, 0, time.Local)
+	if parsed, err := time.ParseInLocation(journal.DayFormat, sample.Format(journal.DayFormat), time.Local); err != nil || !parsed.Equal(sample) {
+		return journal, fmt.Errorf("journal.dayFormat %q must contain year, month and day", journal.DayFormat)
+	}
+
+	// Siblings in nested set are sorted by title, so titles must be sorted as dates (eg, start with `2006`, `01`, `2006-01-02`)
+	for name, sorted := range map[string]bool{
+		"yearFormat":  sortedByDate(journal.YearFormat, 300, func(t time.Time) time.Time { return t.AddDate(1, 0, 0) }),
+		"monthFormat": sortedByDate(journal.MonthFormat, 12, func(t time.Time) time.Time { return t.AddDate(0, 1, 0) }),
+		"dayFormat":   sortedByDate(journal.DayFormat, 31, func(t time.Time) time.Time { return t.AddDate(0, 0, 1) }),
+	} {
+		if !sorted {
+			return journal, fmt.Errorf("journal.%s must be sorted as text in date order, start it with zero-padded number", name)
+		}
+	}
+
+	return journal, nil
+}
+
+// sortedByDate - check titles of consecutive dates (from 1900) are different and sorted as text
+func sortedByDate(format string, count int, next func(time.Time) time.Time) bool {
+	date := time.Date(1900, 1, 1, 0, 0, 0, 0, time.Local)
+	for range count - 1 {
+		following := next(date)
+		if date.Format(format) >= following.Format(format) {
+			return false
+		}
+		date = following
+	}
+	return true
+}
+
+// journalPath - titles of year, month and day notes for date
+func (j JournalSettings) journalPath(date time.Time) []string {
+	return []string{date.Format(j.YearFormat), date.Format(j.MonthFormat), date.Format(j.DayFormat)}
+}
+
+// findChild - get first child note (by ID) with title (0 = root notes)
+func findChild(ctx context.Context, parentID int64, title string) (*models.NoteDB, error) {
+	notes, err := GetNotes(ctx, NoteQueryOptions{
+		Where:        "PARENT_ID = ? AND TITLE = ?",
+		Args:         []any{parentID, title},
+		Order:        "ID ASC",
+		Limit:        1,
+		OmitContents: true,
+	})
+	if err != nil || len(notes) == 0 {
+		return nil, err
+	}
+	return &notes[0], nil
+}
+
+// findJournalRoot - get journal root note (nil if it does not exist)
+func findJournalRoot(ctx context.Context, journal JournalSettings) (*models.NoteDB, error) {
+	var note *models.NoteDB
+	var parentID int64
+	for _, title := range strings.Split(strings.Trim(journal.Root, "/"), "/") {
+		child, err := findChild(ctx, parentID, title)
+		if err != nil || child == nil {
+			return nil, err
+		}
+		note = child
+		parentID = child.ID
+	}
+	return note, nil
+}
+
+// GetJournalNote - get ID of journal note for date (ErrJournalNotFound if it does not exist)
+func GetJournalNote(ctx context.Context, journal JournalSettings, date time.Time) (int64, error) {
+	root, err := findJournalRoot(ctx, journal)
+	if err != nil {
+		return 0, err
+	}
+	if root == nil {
+		return 0, ErrJournalNotFound
+	}
+
+	parentID := root.ID
+	for _, title := range journal.journalPath(date) {
+		child, err := findChild(ctx, parentID, title)
+		if err != nil {
+			return 0, err
+		}
+		if child == nil {
+			return 0, ErrJournalNotFound
+		}
+		parentID = child.ID
+	}
+
+	return parentID, nil
+}
+
+// EnsureJournalNote - get ID of journal note for date, create it (with root, year and month notes) if it is missing
+// Returns true if the day note is created
+func EnsureJournalNote(ctx context.Context, user *models.User, keyring *Keyring, journal JournalSettings, date time.Time) (int64, bool, error) {
+	journalMutex.Lock()
+	defer journalMutex.Unlock()
+
+	// Root, year and month notes are empty folders
+	titles := strings.Split(strings.Trim(journal.Root, "/"), "/")
+	path := journal.journalPath(date)
+	titles = append(titles, path[:2]...)
+
+	var parentID int64
+	for _, title := range titles {
+		child, err := findChild(ctx, parentID, title)
+		if err != nil {
+			return 0, false, err
+		}
+		if child != nil {
+			parentID = child.ID
+			continue
+		}
+
+		parentID, err = CreateNote(ctx, user, keyring, map[string]any{
+			"PARENT_ID": parentID,
+			"TYPE":      "MD",
+			"TITLE":     title,
+		})
+		if err != nil {
+			return 0, false, err
+		}
+	}
+
+	// Day note
+	day, err := findChild(ctx, parentID, path[2])
+	if err != nil {
+		return 0, false, err
+	}
+	if day != nil {
+		return day.ID, false, nil
+	}
+
+	if journal.Template == 0 {
+		id, err := CreateNote(ctx, user, keyring, map[string]any{
+			"PARENT_ID": parentID,
+			"TYPE":      "MD",
+			"TITLE":     path[2],
+		})
+		return id, err == nil, err
+	}
+
+	template, err := GetNote(ctx, int(journal.Template))
+	if err != nil || !template.Template {
+		return 0, false, fmt.Errorf("journal template %d is not found", journal.Template)
+	}
+	ids, err := CreateFromTemplate(ctx, user, keyring, template, parentID, TemplateValues{
+		Time:  date,
+		Title: path[2],
+	})
+	if err != nil {
+		return 0, false, err
+	}
+
+	return ids[0], true, nil
+}
+
+// GetJournalEntries - get all dates with journal notes (visible for user), sorted by date
+func GetJournalEntries(ctx context.Context, user *models.User, journal JournalSettings) ([]JournalEntry, error) {
+	entries := []JournalEntry{}
+
+	root, err := findJournalRoot(ctx, journal)
+	if err != nil || root == nil {
+		return entries, err
+	}
+
+	// Day notes are on third level under root (root => year => month => day)
+	notes, err := GetNotes(ctx, NoteQueryOptions{
+		Where:        `"LEFT" > ? AND "RIGHT" < ? AND DEPTH = ?`,
+		Args:         []any{root.Left, root.Right, root.Depth + 3},
+		Order:        "LEFT ASC",
+		OmitContents: true,
+		User:         user,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, note := range notes {
+		date, err := time.ParseInLocation(journal.DayFormat, note.Title, time.Local)
+		if err != nil {
+			continue
+		}
+		entries = append(entries, JournalEntry{Date: date.Format(JournalDateLayout), ID: note.ID})
+	}
+
+	return entries, nil
+}
+
+// GetAdjacentJournalEntry - get nearest entry before (step < 0) or after (step > 0) date
+func GetAdjacentJournalEntry(ctx context.Context, user *models.User, journal JournalSettings, date time.Time, step int) (JournalEntry, error) {
+	entries, err := GetJournalEntries(ctx, user, journal)
+	if err != nil {
+		return JournalEntry{}, err
+	}
+
+	day := date.Format(JournalDateLayout)
+	if step < 0 {
+		for i := len(entries) - 1; i >= 0; i-- {
+			if entries[i].Date < day {
+				return entries[i], nil
+			}
+		}
+	} else {
+		for _, entry := range entries {
+			if entry.Date > day {
+				return entry, nil
+			}
+		}
+	}
+
+	return JournalEntry{}, ErrJournalNotFound
+}
@@ -0,0 +1,191 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/sondrus/tetrad/database"
+	"github.com/sondrus/tetrad/models"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	goldmarkhtml "github.com/yuin/goldmark/renderer/html"
+)
+
+// renderCacheLimit - max count of notes in rendered HTML cache
+const renderCacheLimit = 500
+
+// renderKey - note in notebook (note IDs are unique just inside notebook)
+type renderKey struct {
+	Notebook string
+	ID       int64
+}
+
+// renderedNote - cached HTML for note
+type renderedNote struct {
+	Hash [sha256.Size]byte // hash of rendered fields, DATE_MODIFIED is not enough (seconds)
+	HTML string
+}
+
+var (
+	// Markdown => HTML, same extensions as in frontend (MarkdownIt)
+	markdownRenderer = goldmark.New(
+		goldmark.WithExtensions(
+			extension.GFM,
+			extension.Footnote,
+			extension.DefinitionList,
+		),
+		goldmark.WithParserOptions(
+			parser.WithAutoHeadingID(),
+		),
+		goldmark.WithRendererOptions(
+			// Raw HTML is allowed in notes, output is sanitized anyway
+			goldmarkhtml.WithUnsafe(),
+		),
+	)
+
+	// HTML sanitizer for rendered notes
+	htmlPolicy = newHTMLPolicy()
+
+	// Cache: notebook and note ID => rendered HTML
+	renderCache      = make(map[renderKey]renderedNote)
+	renderCacheMutex sync.Mutex
+)
+
+// newHTMLPolicy - sanitizer policy: user generated content + markdown extras
+func newHTMLPolicy() *bluemonday.Policy {
+	policy := bluemonday.UGCPolicy()
+
+	// Syntax classes for fenced code (language-go, ...)
+	policy.AllowAttrs("class").Matching(regexp.MustCompile(`^language-[\w+#.-]+$`)).OnElements("code")
+
+	// Task lists
+	policy.AllowElements("input")
+	policy.AllowAttrs("type").Matching(regexp.MustCompile(`^checkbox$`)).OnElements("input")
+	policy.AllowAttrs("checked", "disabled").OnElements("input")
+
+	// Footnotes
+	policy.AllowAttrs("class").Matching(regexp.MustCompile(`^footnotes?(-ref|-backref)?$`)).OnElements("a", "div", "section", "sup")
+	policy.AllowAttrs("role").Matching(regexp.MustCompile(`^doc-[a-z]+$`)).OnElements("a", "div", "section")
+
+	// Heading anchors and footnote IDs
+	policy.AllowAttrs("id").Matching(regexp.MustCompile(`^[\w:.-]+$`)).Globally()
+
+	// Tables
+	policy.AllowAttrs("align").Matching(regexp.MustCompile(`^(left|right|center)$`)).OnElements("th", "td")
+	policy.AllowStyles("text-align").MatchingEnum("left", "right", "center").OnElements("th", "td")
+
+	return policy
+}
+
+// RenderMarkdown - convert markdown to sanitized HTML
+func RenderMarkdown(markdown string) (string, error) {
+	var buf bytes.Buffer
+	if err := markdownRenderer.Convert([]byte(markdown), &buf); err != nil {
+		return "", err
+	}
+
+	return htmlPolicy.Sanitize(buf.String()), nil
+}
+
+// RenderNote - convert note contents to sanitized HTML (depends on note type)
+func RenderNote(ctx context.Context, note models.NoteDB) (string, error) {
+	if note.Locked {
+		return "", ErrLocked
+	}
+
+	// Check cache, it is valid until rendered fields change
+	key := renderKey{Notebook: database.GetContextNotebook(ctx).Name, ID: note.ID}
+	hash := renderHash(note)
+	renderCacheMutex.Lock()
+	cached, ok := renderCache[key]
+	renderCacheMutex.Unlock()
+	if ok && cached.Hash == hash {
+		return cached.HTML, nil
+	}
+
+	var result string
+	var err error
+
+	switch note.Type {
+	case "IFRAME":
+		return "", fmt.Errorf("note type %s could not be rendered", note.Type)
+	case "HTML":
+		result = htmlPolicy.Sanitize(note.Contents)
+	case "CODE":
+		syntax := strings.ToLower(note.Syntax)
+		if syntax == "" {
+			syntax = "plaintext"
+		}
+		result = htmlPolicy.Sanitize(fmt.Sprintf(`<pre><code class="language-%s">%s</code></pre>`,
+			html.EscapeString(syntax), html.EscapeString(note.Contents)))
+	case "TEXT":
+		result = fmt.Sprintf(`<pre>%s</pre>`, html.EscapeString(note.Contents))
+	case "URL":
+		result = htmlPolicy.Sanitize(fmt.Sprintf(`<p><a href="%s">%s</a></p>`,
+			html.EscapeString(note.URL), html.EscapeString(note.Title)))
+
+		// Archived copy of page (web clipper)
+		if strings.TrimSpace(note.Contents) != "" {
+			archived, err := RenderMarkdown(note.Contents)
+			if err != nil {
+				return "", err
+			}
+			result += archived
+		}
+	default:
+		result, err = RenderMarkdown(note.Contents)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	// Save to cache (decrypted notes are never kept in memory)
+	if note.Encrypted {
+		return result, nil
+	}
+	renderCacheMutex.Lock()
+	if len(renderCache) >= renderCacheLimit {
+		clear(renderCache)
+	}
+	renderCache[key] = renderedNote{
+		Hash: hash,
+		HTML: result,
+	}
+	renderCacheMutex.Unlock()
+
+	return result, nil
+}
+
+// renderHash - hash of note fields which are used for rendering
+func renderHash(note models.NoteDB) [sha256.Size]byte {
+	h := sha256.New()
+	for _, value := range []string{note.Type, note.Syntax, note.Title, note.URL, note.Contents} {
+		h.Write([]byte(value))
+		h.Write([]byte{0})
+	}
+
+	var hash [sha256.Size]byte
+	h.Sum(hash[:0])
+	return hash
+}
+
+// forgetRendered - clear rendered HTML cache of notebook
+func forgetRendered(ctx context.Context) {
+	notebook := database.GetContextNotebook(ctx).Name
+
+	renderCacheMutex.Lock()
+	for key := range renderCache {
+		if key.Notebook == notebook {
+			delete(renderCache, key)
+		}
+	}
+	renderCacheMutex.Unlock()
+}
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"reflect"
@@ -8,17 +9,47 @@ import (
 	"time"
 
 	"github.com/sondrus/tetrad/database"
+	"github.com/sondrus/tetrad/events"
+	"github.com/sondrus/tetrad/metrics"
 	"github.com/sondrus/tetrad/models"
 	"gorm.io/gorm"
 )
 
+var (
+	// ErrAccessDenied - user has no role for operation
+	ErrAccessDenied = errors.New("access denied")
+
+	// ErrParentNotFound - parent note of new note does not exist
+	ErrParentNotFound = errors.New("parent note is not found")
+
+	// ErrEmptyQuery - search query has no words
+	ErrEmptyQuery = errors.New("search query is empty")
+)
+
 // NoteQueryOptions - arguments struct for func GetNotes
 type NoteQueryOptions struct {
-	Where        string
-	Args         []any
-	Order        string
-	Limit        int
-	OmitContents bool
+	Where         string
+	Args          []any
+	Order         string
+	Limit         int
+	OmitContents  bool
+	User          *models.User // filter by user permissions (nil = all notes)
+	Keyring       *Keyring     // keys for decrypt encrypted notes (nil = locked)
+	SkipEncrypted bool         // exclude encrypted notes (eg, for search)
+}
+
+// protectedNoteKeys - columns which are set by server only (nested set is used for permissions)
+var protectedNoteKeys = []string{"LEFT", "RIGHT", "DEPTH", "DATE_CREATED", "CONTENTS_LENGTH", "ENCRYPTED"}
+
+// ProtectedNoteKeys - protected columns which are present in fields
+func ProtectedNoteKeys(fields map[string]any) []string {
+	var found []string
+	for _, key := range protectedNoteKeys {
+		if _, ok := fields[key]; ok {
+			found = append(found, key)
+		}
+	}
+	return found
 }
 
 // NormalizeNoteKeys - normalize keys for update (convert from JSON to GORM)
@@ -66,24 +97,25 @@ func NormalizeNoteKeys(fields map[string]any) map[string]any {
 }
 
 // GetNotes - general method for get note list
-func GetNotes(opts NoteQueryOptions) ([]models.NoteDB, error) {
-	db := database.GetORM()
+func GetNotes(ctx context.Context, opts NoteQueryOptions) ([]models.NoteDB, error) {
+	db := database.GetContextORM(ctx)
 	var notes []models.NoteDB
 
 	query := db.Model(&models.NoteDB{})
 
-	// Exclude CONTENTS and virtual CONTENTS_LENGTH from SELECT
+	// Exclude CONTENTS and virtual CONTENTS_LENGTH, ENCRYPTED from SELECT
 	exclude := []string{}
 
 	if opts.OmitContents {
 		exclude = append(exclude, "CONTENTS")
 	}
-	exclude = append(exclude, "CONTENTS_LENGTH")
+	exclude = append(exclude, "CONTENTS_LENGTH", "ENCRYPTED")
 
 	fields := database.GetFields(&models.NoteDB{}, exclude)
 
-	// Add own CONTENTS_LENGTH to SELECT
+	// Add own CONTENTS_LENGTH and ENCRYPTED to SELECT
 	fields = append(fields, "LENGTH(CONTENTS) AS CONTENTS_LENGTH")
+	fields = append(fields, fmt.Sprintf("(SUBSTR(CONTENTS, 1, %d) = '%s') AS ENCRYPTED", len(EncryptedPrefix), EncryptedPrefix))
 
 	query = query.Select(fields)
 
@@ -92,6 +124,11 @@ func GetNotes(opts NoteQueryOptions) ([]models.NoteDB, error) {
 		query = query.Where(opts.Where, opts.Args...)
 	}
 
+	// Encrypted notes
+	if opts.SkipEncrypted {
+		query = query.Where("SUBSTR(CONTENTS, 1, ?) <> ?", len(EncryptedPrefix), EncryptedPrefix)
+	}
+
 	// ORDER
 	if opts.Order != "" {
 		query = query.Order(opts.Order)
@@ -107,15 +144,29 @@ func GetNotes(opts NoteQueryOptions) ([]models.NoteDB, error) {
 		return nil, err
 	}
 
+	// Encrypted contents => plain text (or empty if locked)
+	decryptNotes(opts.Keyring, notes)
+
+	// Keep just notes which are visible for user
+	if opts.User != nil {
+		return FilterVisibleNotes(ctx, opts.User, notes)
+	}
+
 	return notes, nil
 }
 
-// GetNote - get single note by ID
-func GetNote(id int) (models.NoteDB, error) {
-	notes, err := GetNotes(NoteQueryOptions{
-		Where: "ID = ?",
-		Args:  []any{id},
-		Limit: 1,
+// GetNote - get single note by ID (encrypted contents are not decrypted)
+func GetNote(ctx context.Context, id int) (models.NoteDB, error) {
+	return GetUnlockedNote(ctx, nil, id)
+}
+
+// GetUnlockedNote - get single note by ID, encrypted contents are decrypted by keyring
+func GetUnlockedNote(ctx context.Context, keyring *Keyring, id int) (models.NoteDB, error) {
+	notes, err := GetNotes(ctx, NoteQueryOptions{
+		Where:   "ID = ?",
+		Args:    []any{id},
+		Limit:   1,
+		Keyring: keyring,
 	})
 	if err != nil {
 		return models.NoteDB{}, err
@@ -128,20 +179,22 @@ func GetNote(id int) (models.NoteDB, error) {
 	return notes[0], nil
 }
 
-// GetNotesList - get whole list of notes
-func GetNotesList() ([]models.NoteDB, error) {
-	return GetNotes(NoteQueryOptions{
+// GetNotesList - get whole list of notes (visible for user)
+func GetNotesList(ctx context.Context, user *models.User) ([]models.NoteDB, error) {
+	return GetNotes(ctx, NoteQueryOptions{
 		Order:        "LEFT ASC",
 		OmitContents: true,
+		User:         user,
 	})
 
 }
 
-// GetNotesTree - get whole tree with notes
-func GetNotesTree() ([]models.Note, error) {
-	noteDBList, err := GetNotes(NoteQueryOptions{
+// GetNotesTree - get whole tree with notes (visible for user)
+func GetNotesTree(ctx context.Context, user *models.User) ([]models.Note, error) {
+	noteDBList, err := GetNotes(ctx, NoteQueryOptions{
 		Order:        "LEFT ASC",
 		OmitContents: true,
+		User:         user,
 	})
 	if err != nil {
 		return nil, err
@@ -179,14 +232,12 @@ func buildNoteTree(list []models.Note) []models.Note {
 	// Build the tree
 	for i := range list {
 		note := &list[i]
-		if note.ParentID == 0 {
-			// If it's a root note, add it to roots
-			roots = append(roots, note)
-		} else {
+		if parent, ok := noteMap[note.ParentID]; ok && note.ParentID != 0 {
 			// If the note has a parent, add it to the parent's Children
-			if parent, ok := noteMap[note.ParentID]; ok {
-				parent.Children = append(parent.Children, note)
-			}
+			parent.Children = append(parent.Children, note)
+		} else {
+			// If it's a root note (or parent is not visible), add it to roots
+			roots = append(roots, note)
 		}
 	}
 
@@ -199,9 +250,9 @@ func buildNoteTree(list []models.Note) []models.Note {
 	return result
 }
 
-// UpdateNoteContents - update contens for single note
-func UpdateNoteContents(id int64, newContents string) error {
-	db := database.GetORM()
+// UpdateNoteContents - update contens for single note (encrypted if note is in vault)
+func UpdateNoteContents(ctx context.Context, keyring *Keyring, id int64, newContents string) error {
+	db := database.GetContextORM(ctx)
 
 	// Search note by ID
 	var note models.NoteDB
@@ -209,21 +260,41 @@ func UpdateNoteContents(id int64, newContents string) error {
 		return errors.New("note not found")
 	}
 
+	// Encrypt contents for notes in vault
+	vault, err := GetVaultByNote(ctx, note)
+	if err != nil {
+		return err
+	}
+	contents, err := SealContents(keyring, vault, newContents)
+	if err != nil {
+		return err
+	}
+
 	// Prepare note struct for save
-	note.Contents = newContents
+	note.Contents = contents
 	note.DateModified = time.Now().Unix()
 
 	// Save note
 	if err := db.Omit("ContentsLength").Save(&note).Error; err != nil {
 		return err
 	}
+	IndexNoteTasks(ctx, note.ID)
+
+	PublishEvent(ctx, events.Event{
+		Type:         events.Updated,
+		NoteIDs:      []int64{note.ID},
+		ParentID:     note.ParentID,
+		DateModified: note.DateModified,
+	})
 
 	return nil
 }
 
 // RebuildNotesTree - rebuild the nested set values: LEFT, RIGHT, DEPTH
-func RebuildNotesTree() error {
-	db := database.GetORM()
+func RebuildNotesTree(ctx context.Context) error {
+	defer metrics.ObserveTreeRebuild(time.Now())
+
+	db := database.GetContextORM(ctx)
 
 	// Start transaction
 	return db.Transaction(func(tx *gorm.DB) error {
@@ -236,38 +307,7 @@ func RebuildNotesTree() error {
 			return fmt.Errorf("failed to load notes: %w", err)
 		}
 
-		// Prepare a map for parent-child relationships
-		children := make(map[int64][]*models.NoteDB)
-		noteByID := make(map[int64]*models.NoteDB)
-
-		// Build the map of children for each parent
-		for i := range notes {
-			n := &notes[i]
-			noteByID[n.ID] = n
-			children[n.ParentID] = append(children[n.ParentID], n)
-		}
-
-		// Recursive walk function to calculate LEFT, RIGHT, DEPTH
-		var counter int64 = 1
-		var walk func(n *models.NoteDB, depth int64)
-		walk = func(n *models.NoteDB, depth int64) {
-			n.Left = counter
-			n.Depth = depth
-			counter++
-
-			// Recursively walk through children
-			for _, child := range children[n.ID] {
-				walk(child, depth+1)
-			}
-
-			n.Right = counter
-			counter++
-		}
-
-		// Walk from the root notes (parent ID == 0)
-		for _, root := range children[0] {
-			walk(root, 0)
-		}
+		noteByID := buildNestedSet(notes)
 
 		// Update each note individually
 		for _, n := range noteByID {
@@ -286,9 +326,83 @@ func RebuildNotesTree() error {
 	})
 }
 
+// buildNestedSet - calculate LEFT, RIGHT, DEPTH for notes sorted by parent and title
+// Notes which are not reachable from root (missing parent or cycle) keep zero values
+func buildNestedSet(notes []models.NoteDB) map[int64]*models.NoteDB {
+	// Prepare a map for parent-child relationships
+	children := make(map[int64][]*models.NoteDB)
+	noteByID := make(map[int64]*models.NoteDB)
+
+	// Build the map of children for each parent
+	for i := range notes {
+		n := &notes[i]
+		n.Left, n.Right, n.Depth = 0, 0, 0
+		noteByID[n.ID] = n
+		children[n.ParentID] = append(children[n.ParentID], n)
+	}
+
+	// Recursive walk function to calculate LEFT, RIGHT, DEPTH
+	var counter int64 = 1
+	var walk func(n *models.NoteDB, depth int64)
+	walk = func(n *models.NoteDB, depth int64) {
+		n.Left = counter
+		n.Depth = depth
+		counter++
+
+		// Recursively walk through children
+		for _, child := range children[n.ID] {
+			walk(child, depth+1)
+		}
+
+		n.Right = counter
+		counter++
+	}
+
+	// Walk from the root notes (parent ID == 0)
+	for _, root := range children[0] {
+		walk(root, 0)
+	}
+
+	return noteByID
+}
+
+// CheckNotesTree - find notes with missing parent, cycles and outdated nested set values
+func CheckNotesTree(ctx context.Context) ([]string, error) {
+	var stored []models.NoteDB
+	if err := database.GetContextORM(ctx).Model(&models.NoteDB{}).
+		Select("ID", "PARENT_ID", "TITLE", "LEFT", "RIGHT", "DEPTH").
+		Order("PARENT_ID, TITLE").
+		Find(&stored).Error; err != nil {
+		return nil, fmt.Errorf("failed to load notes: %w", err)
+	}
+
+	expected := make([]models.NoteDB, len(stored))
+	copy(expected, stored)
+	noteByID := buildNestedSet(expected)
+
+	var problems []string
+	outdated := 0
+	for _, note := range stored {
+		want := noteByID[note.ID]
+		switch {
+		case note.ParentID != 0 && noteByID[note.ParentID] == nil:
+			problems = append(problems, fmt.Sprintf("note %d (%s): parent %d does not exist", note.ID, note.Title, note.ParentID))
+		case want.Left == 0:
+			problems = append(problems, fmt.Sprintf("note %d (%s): not reachable from root (cycle of parents)", note.ID, note.Title))
+		case want.Left != note.Left || want.Right != note.Right || want.Depth != note.Depth:
+			outdated++
+		}
+	}
+	if outdated > 0 {
+		problems = append(problems, fmt.Sprintf("%d notes have outdated LEFT, RIGHT or DEPTH (run rebuild-tree)", outdated))
+	}
+
+	return problems, nil
+}
+
 // SetExpandCollapse - set expand/collapse for notes
-func SetExpandCollapse(collapse []int, expand []int) {
-	db := database.GetORM()
+func SetExpandCollapse(ctx context.Context, collapse []int, expand []int) {
+	db := database.GetContextORM(ctx)
 
 	if len(expand) == 1 && expand[0] == 0 {
 		// If expand is [0], set expanded = 1 for all records
@@ -305,4 +419,189 @@ func SetExpandCollapse(collapse []int, expand []int) {
 		// Set expanded = 0 for the specified notes
 		db.Model(&models.NoteDB{}).Where("ID IN ?", collapse).Update("EXPANDED", 0)
 	}
+
+	PublishEvent(ctx, events.Event{
+		Type:     events.Expanded,
+		Expand:   expand,
+		Collapse: collapse,
+	})
+}
+
+// PublishEvent - send change notification to clients of notebook from context
+func PublishEvent(ctx context.Context, event events.Event) {
+	event.Notebook = database.GetContextNotebook(ctx).Name
+	events.Publish(event)
+}
+
+// SearchOptions - arguments struct for func SearchNotes
+type SearchOptions struct {
+	Query string
+	Title bool // search in titles only
+	Whole bool // search whole phrase, not separate words
+}
+
+// SearchNotes - find notes (visible for user) which contain all words of query
+// Encrypted notes are never searched
+func SearchNotes(ctx context.Context, user *models.User, opts SearchOptions) ([]models.NoteDB, error) {
+	// If `whole`, use full phrase 'as is', else use words from phrase
+	var words []string
+	if opts.Whole {
+		words = []string{opts.Query}
+	} else {
+		words = strings.Fields(opts.Query)
+	}
+	if len(words) == 0 {
+		return nil, ErrEmptyQuery
+	}
+
+	// Prepare SQL WHERE
+	var whereConditions []string
+	var args []any
+	for _, word := range words {
+		if opts.Title {
+			whereConditions = append(whereConditions, "custom_like(TITLE, ?)")
+			args = append(args, word)
+		} else {
+			whereConditions = append(whereConditions,
+				"("+
+					"custom_like(TITLE, ?) OR "+
+					"custom_like(CONTENTS, ?) OR "+
+					"(URL IS NOT NULL AND custom_like(URL, ?))"+
+					")",
+			)
+			args = append(args, word, word, word)
+		}
+	}
+
+	// Get notes with filter
+	return GetNotes(ctx, NoteQueryOptions{
+		Where:         strings.Join(whereConditions, " AND "),
+		Args:          args,
+		OmitContents:  true,
+		User:          user,
+		SkipEncrypted: true,
+	})
+}
+
+// CreateNote - insert note (fields are GORM columns) and rebuild tree, returns ID of new note
+// Contents of note inside encrypted subtree are encrypted by keyring
+func CreateNote(ctx context.Context, user *models.User, keyring *Keyring, fields map[string]any) (int64, error) {
+	// Check user can add notes to parent
+	parentID := toInt64(fields["PARENT_ID"])
+	if !HasParentRole(ctx, user, parentID, RoleEditor) {
+		return 0, ErrAccessDenied
+	}
+
+	// Notes inside encrypted subtree are encrypted too
+	vault, err := GetVaultByParent(ctx, parentID)
+	if err != nil {
+		return 0, ErrParentNotFound
+	}
+
+	// Insert new note to DB
+	now := time.Now().Unix()
+	var id int64
+	err = database.GetContextORM(ctx).Transaction(func(tx *gorm.DB) error {
+		id, err = insertNote(tx, user, keyring, vault, fields, now)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	IndexNoteTasks(ctx, id)
+
+	// Nested set rebuild
+	RebuildNotesTree(ctx)
+
+	PublishEvent(ctx, events.Event{
+		Type:         events.Created,
+		NoteIDs:      []int64{id},
+		ParentID:     parentID,
+		DateModified: now,
+	})
+
+	return id, nil
+}
+
+// insertNote - insert note in transaction, tree is not rebuilt (access to parent must be checked before)
+// Contents are encrypted by vault of parent, author of root note is its owner (otherwise nobody but admins could see it)
+func insertNote(tx *gorm.DB, user *models.User, keyring *Keyring, vault *models.Vault, fields map[string]any, now int64) (int64, error) {
+	// If exists 'ID', delete it
+	delete(fields, "ID")
+
+	// Columns which are set by server (nested set is rebuilt after insert)
+	for _, key := range protectedNoteKeys {
+		delete(fields, key)
+	}
+
+	// Set dates
+	fields["DATE_CREATED"] = now
+	fields["DATE_MODIFIED"] = now
+
+	// Set empty contents if not set
+	if _, exists := fields["CONTENTS"]; !exists {
+		fields["CONTENTS"] = ""
+	}
+
+	// Empty note could be created in locked subtree, it is encrypted on first save
+	if contents, ok := fields["CONTENTS"].(string); ok && contents != "" && vault != nil {
+		sealed, err := SealContents(keyring, vault, contents)
+		if err != nil {
+			return 0, err
+		}
+		fields["CONTENTS"] = sealed
+	}
+
+	if err := tx.Model(&models.NoteDB{}).Create(fields).Error; err != nil {
+		return 0, fmt.Errorf("error creating note: %w", err)
+	}
+	id := toInt64(fields["ID"])
+
+	if toInt64(fields["PARENT_ID"]) == 0 && !IsAdmin(user) {
+		if err := SaveNotePermission(tx, user.ID, id, RoleOwner); err != nil {
+			return 0, fmt.Errorf("error granting note to its owner: %w", err)
+		}
+	}
+
+	return id, nil
+}
+
+// FindNoteByPath - get note by path of titles from root, eg `Projects/Tetrad/Ideas`
+// If several siblings have same title, the first one (by ID) is used
+func FindNoteByPath(ctx context.Context, path string) (models.NoteDB, error) {
+	var note models.NoteDB
+	var parentID int64
+
+	for _, title := range strings.Split(strings.Trim(path, "/"), "/") {
+		notes, err := GetNotes(ctx, NoteQueryOptions{
+			Where:        "PARENT_ID = ? AND TITLE = ?",
+			Args:         []any{parentID, title},
+			Order:        "ID ASC",
+			Limit:        1,
+			OmitContents: true,
+		})
+		if err != nil {
+			return models.NoteDB{}, err
+		}
+		if len(notes) == 0 {
+			return models.NoteDB{}, fmt.Errorf("note %q not found", title)
+		}
+		note = notes[0]
+		parentID = note.ID
+	}
+
+	return GetNote(ctx, int(note.ID))
+}
+
+// toInt64 - number from JSON or GORM value (0 if it is not a number)
+func toInt64(value any) int64 {
+	switch v := value.(type) {
+	case float64:
+		return int64(v)
+	case int64:
+		return v
+	case int:
+		return int64(v)
+	}
+	return 0
 }
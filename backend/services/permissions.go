@@ -0,0 +1,287 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+
+	"github.com/sondrus/tetrad/database"
+	"github.com/sondrus/tetrad/models"
+	"gorm.io/gorm"
+)
+
+// Roles for note subtree, every role includes the previous ones
+const (
+	RoleViewer = "viewer" // read notes
+	RoleEditor = "editor" // create and update notes
+	RoleOwner  = "owner"  // delete, move notes and manage permissions
+)
+
+// roleRanks - role => level
+var roleRanks = map[string]int{
+	RoleViewer: 1,
+	RoleEditor: 2,
+	RoleOwner:  3,
+}
+
+// grant - permission with nested set values of the note
+type grant struct {
+	Left  int64  `gorm:"column:LEFT"`
+	Right int64  `gorm:"column:RIGHT"`
+	Role  string `gorm:"column:ROLE"`
+}
+
+// NotePermission - permission with user name (for response)
+type NotePermission struct {
+	models.Permission
+	UserName  string `gorm:"column:USER_NAME" json:"userName"`
+	NoteTitle string `gorm:"column:NOTE_TITLE" json:"noteTitle"`
+	Inherited bool   `gorm:"column:INHERITED" json:"inherited"`
+}
+
+// IsValidRole - check role name
+func IsValidRole(role string) bool {
+	_, ok := roleRanks[role]
+	return ok
+}
+
+// IsAdmin - check user has full access (admin or authentication is disabled)
+func IsAdmin(user *models.User) bool {
+	return user == nil || user.Admin
+}
+
+// loadGrants - get all permissions of user with nested set values
+func loadGrants(ctx context.Context, userID int64) ([]grant, error) {
+	var grants []grant
+	err := database.GetContextORM(ctx).
+		Table("permissions AS p").
+		Select(`n."LEFT" AS "LEFT", n."RIGHT" AS "RIGHT", p.ROLE AS ROLE`).
+		Joins("JOIN notes AS n ON n.ID = p.NOTE_ID").
+		Where("p.USER_ID = ?", userID).
+		Scan(&grants).Error
+	if err != nil {
+		return nil, err
+	}
+	return grants, nil
+}
+
+// roleByGrants - best role for note inside granted subtrees
+func roleByGrants(grants []grant, note models.NoteDB) string {
+	role := ""
+	for _, g := range grants {
+		if g.Left <= note.Left && g.Right >= note.Right && roleRanks[g.Role] > roleRanks[role] {
+			role = g.Role
+		}
+	}
+	return role
+}
+
+// GetNoteRole - get user role for note (inherited from parents)
+func GetNoteRole(ctx context.Context, user *models.User, note models.NoteDB) string {
+	if IsAdmin(user) {
+		return RoleOwner
+	}
+
+	grants, err := loadGrants(ctx, user.ID)
+	if err != nil {
+		return ""
+	}
+
+	return roleByGrants(grants, note)
+}
+
+// HasNoteRole - check user has role (or higher) for note
+func HasNoteRole(ctx context.Context, user *models.User, note models.NoteDB, role string) bool {
+	return roleRanks[GetNoteRole(ctx, user, note)] >= roleRanks[role]
+}
+
+// HasParentRole - check user could add notes to parent (0 = root, allowed for everyone)
+func HasParentRole(ctx context.Context, user *models.User, parentID int64, role string) bool {
+	if parentID == 0 || IsAdmin(user) {
+		return true
+	}
+
+	parent, err := GetNote(ctx, int(parentID))
+	if err != nil {
+		return false
+	}
+
+	return HasNoteRole(ctx, user, parent, role)
+}
+
+// FilterVisibleNotes - keep just notes which user can view
+func FilterVisibleNotes(ctx context.Context, user *models.User, notes []models.NoteDB) ([]models.NoteDB, error) {
+	if IsAdmin(user) {
+		return notes, nil
+	}
+
+	grants, err := loadGrants(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	visible := make([]models.NoteDB, 0, len(notes))
+	for _, note := range notes {
+		if roleByGrants(grants, note) != "" {
+			visible = append(visible, note)
+		}
+	}
+
+	return visible, nil
+}
+
+// FilterVisibleNoteIDs - keep just note IDs which user can view
+func FilterVisibleNoteIDs(ctx context.Context, user *models.User, ids []int) ([]int, error) {
+	if IsAdmin(user) || len(ids) == 0 {
+		return ids, nil
+	}
+
+	notes, err := GetNotes(ctx, NoteQueryOptions{
+		Where:        "ID IN ?",
+		Args:         []any{ids},
+		OmitContents: true,
+		User:         user,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	visible := make([]int, 0, len(notes))
+	for _, note := range notes {
+		visible = append(visible, int(note.ID))
+	}
+
+	return visible, nil
+}
+
+// GetSubtreeViewers - notes of subtree visible for each user with grants (user ID => note IDs), admins are not included
+// It is used for notification about deleted notes, so it must be called before deletion
+func GetSubtreeViewers(ctx context.Context, root models.NoteDB) (map[int64][]int64, error) {
+	db := database.GetContextORM(ctx)
+
+	// Grants on the root (or its parents) and inside the subtree
+	var grants []struct {
+		UserID int64 `gorm:"column:USER_ID"`
+		Left   int64 `gorm:"column:LEFT"`
+		Right  int64 `gorm:"column:RIGHT"`
+	}
+	err := db.Table("permissions AS p").
+		Select(`p.USER_ID AS USER_ID, n."LEFT" AS "LEFT", n."RIGHT" AS "RIGHT"`).
+		Joins("JOIN notes AS n ON n.ID = p.NOTE_ID").
+		Where(`(n."LEFT" <= ? AND n."RIGHT" >= ?) OR (n."LEFT" > ? AND n."RIGHT" < ?)`, root.Left, root.Right, root.Left, root.Right).
+		Scan(&grants).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var notes []models.NoteDB
+	if err := db.Model(&models.NoteDB{}).Select("ID", "LEFT", "RIGHT").
+		Where(`"LEFT" >= ? AND "RIGHT" <= ?`, root.Left, root.Right).Find(&notes).Error; err != nil {
+		return nil, err
+	}
+
+	viewers := make(map[int64][]int64)
+	for _, note := range notes {
+		for _, g := range grants {
+			if g.Left <= note.Left && g.Right >= note.Right && !slices.Contains(viewers[g.UserID], note.ID) {
+				viewers[g.UserID] = append(viewers[g.UserID], note.ID)
+			}
+		}
+	}
+
+	return viewers, nil
+}
+
+// GetNotePermissions - get permissions for note and its parents
+func GetNotePermissions(ctx context.Context, note models.NoteDB) ([]NotePermission, error) {
+	var permissions []NotePermission
+	err := database.GetContextORM(ctx).
+		Table("permissions AS p").
+		Select(`p.*, n.TITLE AS NOTE_TITLE, (p.NOTE_ID <> ?) AS INHERITED`, note.ID).
+		Joins("JOIN notes AS n ON n.ID = p.NOTE_ID").
+		Where(`n."LEFT" <= ? AND n."RIGHT" >= ?`, note.Left, note.Right).
+		Order(`n."LEFT" ASC`).
+		Scan(&permissions).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// Users are kept in main database (notebook could be other file)
+	users, err := GetUsers()
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[int64]string, len(users))
+	for _, user := range users {
+		names[user.ID] = user.Name
+	}
+
+	// Parents first (as loaded), then by user name
+	result := make([]NotePermission, 0, len(permissions))
+	depth := make(map[int64]int)
+	for _, permission := range permissions {
+		if _, ok := depth[permission.NoteID]; !ok {
+			depth[permission.NoteID] = len(depth)
+		}
+		if name, ok := names[permission.UserID]; ok {
+			permission.UserName = name
+			result = append(result, permission)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		if depth[result[i].NoteID] != depth[result[j].NoteID] {
+			return depth[result[i].NoteID] < depth[result[j].NoteID]
+		}
+		return result[i].UserName < result[j].UserName
+	})
+
+	return result, nil
+}
+
+// SetNotePermission - set role for user on note subtree (empty role = remove)
+func SetNotePermission(ctx context.Context, userID int64, noteID int64, role string) error {
+	return SaveNotePermission(database.GetContextORM(ctx), userID, noteID, role)
+}
+
+// SaveNotePermission - set role for user on note subtree (in transaction)
+func SaveNotePermission(db *gorm.DB, userID int64, noteID int64, role string) error {
+	if role == "" {
+		return db.Where("USER_ID = ? AND NOTE_ID = ?", userID, noteID).Delete(&models.Permission{}).Error
+	}
+	if !IsValidRole(role) {
+		return fmt.Errorf("unknown role %s", role)
+	}
+
+	var existing models.Permission
+	result := db.Where("USER_ID = ? AND NOTE_ID = ?", userID, noteID).Limit(1).Find(&existing)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		return db.Model(&existing).Update("ROLE", role).Error
+	}
+
+	return db.Create(&models.Permission{UserID: userID, NoteID: noteID, Role: role}).Error
+}
+
+// CheckNoteRole - check current user has role for note, respond 403 if not
+func CheckNoteRole(w http.ResponseWriter, r *http.Request, note models.NoteDB, role string) bool {
+	if HasNoteRole(r.Context(), GetContextUser(r.Context()), note, role) {
+		return true
+	}
+
+	RespondWithError(w, http.StatusForbidden, "Access denied", nil)
+	return false
+}
+
+// CheckAdmin - check current user is admin, respond 403 if not
+func CheckAdmin(w http.ResponseWriter, r *http.Request) bool {
+	if IsAdmin(GetContextUser(r.Context())) {
+		return true
+	}
+
+	RespondWithError(w, http.StatusForbidden, "Access denied", nil)
+	return false
+}
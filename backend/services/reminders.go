@@ -0,0 +1,298 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/sondrus/tetrad/database"
+	"github.com/sondrus/tetrad/events"
+	"github.com/sondrus/tetrad/models"
+	"gorm.io/gorm"
+)
+
+// Reminder states
+const (
+	ReminderPending   = "pending"   // waiting for its time
+	ReminderFired     = "fired"     // shown to user until it is snoozed or dismissed
+	ReminderDismissed = "dismissed" // finished (just reminders without recurrence)
+)
+
+// reminderInterval - how often the scheduler looks for due reminders
+const reminderInterval = 10 * time.Second
+
+// ErrReminderNotFound - reminder does not exist or belongs to other user
+var ErrReminderNotFound = errors.New("reminder not found")
+
+// reminderRecurrences - recurrence => step (empty recurrence = single reminder)
+var reminderRecurrences = map[string][3]int{
+	"":        {0, 0, 0},
+	"daily":   {0, 0, 1},
+	"weekly":  {0, 0, 7},
+	"monthly": {0, 1, 0},
+	"yearly":  {1, 0, 0},
+}
+
+// ReminderFilter - filters for reminder list (zero values are ignored)
+type ReminderFilter struct {
+	Status string // active (pending and fired), pending, fired, dismissed or all (default: active)
+	NoteID int64  // reminders of single note
+	User   *models.User
+}
+
+// ReminderInfo - reminder with title of its note (for response)
+type ReminderInfo struct {
+	models.Reminder
+	NoteTitle string `gorm:"column:NOTE_TITLE" json:"noteTitle"`
+	Left      int64  `gorm:"column:LEFT" json:"-"`
+	Right     int64  `gorm:"column:RIGHT" json:"-"`
+}
+
+// reminderUserID - owner of reminders (0 = authentication is disabled)
+func reminderUserID(user *models.User) int64 {
+	if user == nil {
+		return 0
+	}
+	return user.ID
+}
+
+// IsValidRecurrence - check recurrence is supported
+func IsValidRecurrence(recurrence string) bool {
+	_, ok := reminderRecurrences[recurrence]
+	return ok
+}
+
+// nextOccurrence - first time of recurring reminder after `after` (0 if reminder is not recurring)
+// Occurrences are counted from start, so monthly reminders on 31st do not drift
+func nextOccurrence(reminder models.Reminder, after int64) int64 {
+	step := reminderRecurrences[reminder.Recurrence]
+	if reminder.Recurrence == "" {
+		return 0
+	}
+
+	start := time.Unix(reminder.DateStart, 0)
+	for i := 1; ; i++ {
+		next := start.AddDate(step[0]*i, step[1]*i, step[2]*i).Unix()
+		if next > after {
+			return next
+		}
+	}
+}
+
+// GetReminders - get reminders of user (their notes must be visible), sorted by next time
+func GetReminders(ctx context.Context, filter ReminderFilter) ([]ReminderInfo, error) {
+	query := database.GetContextORM(ctx).
+		Table("reminders AS r").
+		Select(`r.*, n.TITLE AS NOTE_TITLE, n."LEFT" AS "LEFT", n."RIGHT" AS "RIGHT"`).
+		Joins("JOIN notes AS n ON n.ID = r.NOTE_ID").
+		Where("r.USER_ID = ?", reminderUserID(filter.User))
+
+	switch filter.Status {
+	case "", "active":
+		query = query.Where("r.STATE <> ?", ReminderDismissed)
+	case ReminderPending, ReminderFired, ReminderDismissed:
+		query = query.Where("r.STATE = ?", filter.Status)
+	case "all":
+	default:
+		return nil, fmt.Errorf("unknown reminder status %s", filter.Status)
+	}
+	if filter.NoteID > 0 {
+		query = query.Where("r.NOTE_ID = ?", filter.NoteID)
+	}
+
+	var reminders []ReminderInfo
+	if err := query.Order("r.DATE_NEXT ASC, r.ID ASC").Scan(&reminders).Error; err != nil {
+		return nil, err
+	}
+
+	// Access to note could be revoked after reminder is created
+	if IsAdmin(filter.User) {
+		return reminders, nil
+	}
+	grants, err := loadGrants(ctx, filter.User.ID)
+	if err != nil {
+		return nil, err
+	}
+	visible := make([]ReminderInfo, 0, len(reminders))
+	for _, reminder := range reminders {
+		if roleByGrants(grants, models.NoteDB{Left: reminder.Left, Right: reminder.Right}) != "" {
+			visible = append(visible, reminder)
+		}
+	}
+
+	return visible, nil
+}
+
+// GetReminder - get reminder of user by ID
+func GetReminder(ctx context.Context, user *models.User, id int64) (models.Reminder, error) {
+	var reminders []models.Reminder
+	err := database.GetContextORM(ctx).
+		Where("ID = ? AND USER_ID = ?", id, reminderUserID(user)).
+		Limit(1).
+		Find(&reminders).Error
+	if err != nil {
+		return models.Reminder{}, err
+	}
+	if len(reminders) == 0 {
+		return models.Reminder{}, ErrReminderNotFound
+	}
+	return reminders[0], nil
+}
+
+// CreateReminder - add reminder of user to note (DateStart is the first time)
+func CreateReminder(ctx context.Context, user *models.User, reminder models.Reminder) (models.Reminder, error) {
+	if !IsValidRecurrence(reminder.Recurrence) {
+		return models.Reminder{}, fmt.Errorf("unknown recurrence %s", reminder.Recurrence)
+	}
+
+	reminder.ID = 0
+	reminder.UserID = reminderUserID(user)
+	reminder.State = ReminderPending
+	reminder.DateNext = reminder.DateStart
+	reminder.DateFired = 0
+
+	if err := database.GetContextORM(ctx).Create(&reminder).Error; err != nil {
+		return models.Reminder{}, err
+	}
+
+	publishReminders(ctx, reminder)
+	return reminder, nil
+}
+
+// UpdateReminder - change time, recurrence or message of reminder, it waits for new time again
+func UpdateReminder(ctx context.Context, reminder models.Reminder) (models.Reminder, error) {
+	if !IsValidRecurrence(reminder.Recurrence) {
+		return models.Reminder{}, fmt.Errorf("unknown recurrence %s", reminder.Recurrence)
+	}
+
+	reminder.State = ReminderPending
+	reminder.DateNext = reminder.DateStart
+
+	// Past start of recurring reminder: wait for the next occurrence
+	if now := time.Now().Unix(); reminder.DateNext <= now && reminder.Recurrence != "" {
+		reminder.DateNext = nextOccurrence(reminder, now)
+	}
+
+	return saveReminder(ctx, reminder)
+}
+
+// SnoozeReminder - fire reminder again at time
+func SnoozeReminder(ctx context.Context, reminder models.Reminder, until time.Time) (models.Reminder, error) {
+	reminder.State = ReminderPending
+	reminder.DateNext = until.Unix()
+	return saveReminder(ctx, reminder)
+}
+
+// DismissReminder - finish reminder, recurring reminder waits for the next occurrence
+func DismissReminder(ctx context.Context, reminder models.Reminder) (models.Reminder, error) {
+	if reminder.Recurrence == "" {
+		reminder.State = ReminderDismissed
+		return saveReminder(ctx, reminder)
+	}
+
+	reminder.State = ReminderPending
+	if now := time.Now().Unix(); reminder.DateNext <= now {
+		reminder.DateNext = nextOccurrence(reminder, now)
+	}
+	return saveReminder(ctx, reminder)
+}
+
+// DeleteReminder - delete reminder
+func DeleteReminder(ctx context.Context, reminder models.Reminder) error {
+	if err := database.GetContextORM(ctx).Delete(&models.Reminder{}, reminder.ID).Error; err != nil {
+		return err
+	}
+
+	publishReminders(ctx, reminder)
+	return nil
+}
+
+// DeleteReminders - delete reminders of notes inside nested set range (in transaction)
+func DeleteReminders(tx *gorm.DB, left int64, right int64) error {
+	ids := tx.Model(&models.NoteDB{}).Select("ID").Where(`"LEFT" >= ? AND "RIGHT" <= ?`, left, right)
+	return tx.Where("NOTE_ID IN (?)", ids).Delete(&models.Reminder{}).Error
+}
+
+// saveReminder - save reminder and notify other clients of user
+func saveReminder(ctx context.Context, reminder models.Reminder) (models.Reminder, error) {
+	if err := database.GetContextORM(ctx).Save(&reminder).Error; err != nil {
+		return models.Reminder{}, err
+	}
+
+	publishReminders(ctx, reminder)
+	return reminder, nil
+}
+
+// publishReminders - notify clients of user that reminders are changed
+func publishReminders(ctx context.Context, reminder models.Reminder) {
+	PublishEvent(ctx, events.Event{
+		Type:       events.Reminders,
+		NoteIDs:    []int64{reminder.NoteID},
+		ReminderID: reminder.ID,
+		UserID:     reminder.UserID,
+	})
+}
+
+// FireReminders - fire due reminders of notebook from context, returns count of fired reminders
+// Recurring reminders are moved to the next occurrence, so they are fired again even if they are not dismissed
+func FireReminders(ctx context.Context, now time.Time) (int, error) {
+	db := database.GetContextORM(ctx)
+
+	var due []models.Reminder
+	err := db.
+		Where("DATE_NEXT <= ? AND (STATE = ? OR (STATE = ? AND RECURRENCE <> ''))", now.Unix(), ReminderPending, ReminderFired).
+		Order("DATE_NEXT ASC").
+		Find(&due).Error
+	if err != nil {
+		return 0, err
+	}
+
+	for _, reminder := range due {
+		reminder.State = ReminderFired
+		reminder.DateFired = now.Unix()
+
+		// Occurrences missed while server was stopped are fired once
+		if reminder.Recurrence != "" {
+			reminder.DateNext = nextOccurrence(reminder, now.Unix())
+		}
+
+		if err := db.Save(&reminder).Error; err != nil {
+			return 0, err
+		}
+
+		PublishEvent(ctx, events.Event{
+			Type:       events.Reminder,
+			NoteIDs:    []int64{reminder.NoteID},
+			ReminderID: reminder.ID,
+			UserID:     reminder.UserID,
+		})
+	}
+
+	return len(due), nil
+}
+
+// ScheduleReminders - fire due reminders of all opened notebooks now and then periodically
+func ScheduleReminders() {
+	fire := func() {
+		now := time.Now()
+		for _, notebook := range database.OpenedNotebooks() {
+			count, err := FireReminders(database.WithNotebook(context.Background(), notebook), now)
+			if err != nil {
+				slog.Error("Failed to fire reminders", "notebook", notebook.Name, "error", err)
+				continue
+			}
+			if count > 0 {
+				slog.Debug("Reminders fired", "notebook", notebook.Name, "count", count)
+			}
+		}
+	}
+
+	go func() {
+		fire()
+		for range time.Tick(reminderInterval) {
+			fire()
+		}
+	}()
+}
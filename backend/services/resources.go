@@ -0,0 +1,23 @@
+package services
+
+import (
+	"context"
+	"errors"
+
+	"github.com/sondrus/tetrad/database"
+	"github.com/sondrus/tetrad/models"
+)
+
+// GetResource - get single resource (attached file) by ID
+func GetResource(ctx context.Context, id int) (models.Resource, error) {
+	var resource models.Resource
+	if err := database.GetContextORM(ctx).Where("ID = ?", id).Limit(1).Find(&resource).Error; err != nil {
+		return models.Resource{}, err
+	}
+
+	if resource.ID == 0 {
+		return models.Resource{}, errors.New("resource not found")
+	}
+
+	return resource, nil
+}
@@ -1,22 +1,46 @@
 package services
 
 import (
+	"context"
 	"fmt"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 
 	"github.com/sondrus/tetrad/database"
+	"github.com/sondrus/tetrad/events"
 	"github.com/sondrus/tetrad/models"
+	"gorm.io/gorm"
 )
 
+// ServerOptionPrefix - options with this prefix are used by server only (never sent to frontend)
+const ServerOptionPrefix = "server."
+
 // LoadSettings - load frontend settings from database
-func LoadSettings() (map[string]any, error) {
+// Shared options are defaults, user options (if user is set) override them
+func LoadSettings(ctx context.Context, user *models.User) (map[string]any, error) {
+	db := database.GetContextORM(ctx)
+
 	var options []models.Option
-	if err := database.GetORM().Find(&options).Error; err != nil {
+	if err := db.Where("NAME NOT LIKE ?", ServerOptionPrefix+"%").Find(&options).Error; err != nil {
 		return nil, err
 	}
 
+	if user != nil {
+		var userOptions []models.UserOption
+		if err := db.Where("USER_ID = ?", user.ID).Find(&userOptions).Error; err != nil {
+			return nil, err
+		}
+		for _, userOption := range userOptions {
+			options = append(options, models.Option{
+				Name:  userOption.Name,
+				Value: userOption.Value,
+				Type:  userOption.Type,
+			})
+		}
+	}
+
 	// Transform flat data into hierarchical structure
 	settings := make(map[string]any)
 	for _, option := range options {
@@ -67,34 +91,103 @@ func setNestedValue(data map[string]any, keys []string, value any) {
 	setNestedValue(data[keys[0]].(map[string]any), keys[1:], value)
 }
 
-// SaveSettings - save settings array to database
-func SaveSettings(settings map[string]any) error {
+// SaveSettings - save settings array to database (shared or user options), returns names of changed options
+func SaveSettings(ctx context.Context, user *models.User, settings map[string]any) ([]string, error) {
+	db := database.GetContextORM(ctx)
+
 	var flattenedOptions []models.Option
 
 	// Convert settigns tree to list
 	flattenSettings(settings, "", &flattenedOptions)
 
+	// Current values (for list of changes)
+	current, err := optionValues(db, user)
+	if err != nil {
+		return nil, err
+	}
+
+	var changed []string
 	for _, option := range flattenedOptions {
+		// Server options could not be changed by frontend
+		if strings.HasPrefix(option.Name, ServerOptionPrefix) {
+			continue
+		}
+
+		if value, ok := current[option.Name]; ok && value == option.Value {
+			continue
+		}
+		changed = append(changed, option.Name)
+
+		// Each user has own settings
+		if user != nil {
+			userOption := models.UserOption{
+				UserID: user.ID,
+				Name:   option.Name,
+				Value:  option.Value,
+				Type:   option.Type,
+			}
+			if err := db.Save(&userOption).Error; err != nil {
+				return nil, fmt.Errorf("Error save user option %s: %v", option.Name, err)
+			}
+			continue
+		}
 
 		var existingOption models.Option
-		if err := database.GetORM().Where("NAME = ?", option.Name).First(&existingOption).Error; err != nil {
+		if err := db.Where("NAME = ?", option.Name).First(&existingOption).Error; err != nil {
 			// Create
-			if err := database.GetORM().Create(&option).Error; err != nil {
-				return fmt.Errorf("Error create option %s: %v", option.Name, err)
+			if err := db.Create(&option).Error; err != nil {
+				return nil, fmt.Errorf("Error create option %s: %v", option.Name, err)
 			}
 
 		} else {
 			// Update
 			existingOption.Value = option.Value
 			existingOption.Type = option.Type
-			if err := database.GetORM().Save(&existingOption).Error; err != nil {
-				return fmt.Errorf("Error update option %s: %v", option.Name, err)
+			if err := db.Save(&existingOption).Error; err != nil {
+				return nil, fmt.Errorf("Error update option %s: %v", option.Name, err)
 			}
 
 		}
 	}
 
-	return nil
+	if len(changed) == 0 {
+		return nil, nil
+	}
+
+	event := events.Event{Type: events.Settings}
+	if user != nil {
+		event.UserID = user.ID
+	}
+	PublishEvent(ctx, event)
+
+	sort.Strings(changed)
+	return changed, nil
+}
+
+// optionValues - get stored option values: name => value (user options for user, shared otherwise)
+func optionValues(db *gorm.DB, user *models.User) (map[string]string, error) {
+	values := make(map[string]string)
+
+	if user != nil {
+		var userOptions []models.UserOption
+		if err := db.Where("USER_ID = ?", user.ID).Find(&userOptions).Error; err != nil {
+			return nil, err
+		}
+		for _, option := range userOptions {
+			values[option.Name] = option.Value
+		}
+		return values, nil
+	}
+
+	var options []models.Option
+	if err := db.Find(&options).Error; err != nil {
+		return nil, err
+	}
+	for _, option := range options {
+		values[option.Name] = option.Value
+	}
+
+	return values, nil
 }
 
 // flattenSettings - transform options map to a flat list of options
@@ -131,3 +224,36 @@ func flattenSettings(data map[string]any, parent string, options *[]models.Optio
 		}
 	}
 }
+
+// GetServerOption - get server option value by name (eg, server.auth.password), server options are kept in main database
+func GetServerOption(name string) (string, bool) {
+	var options []models.Option
+	if err := database.GetORM().Where("NAME = ?", name).Limit(1).Find(&options).Error; err != nil {
+		return "", false
+	}
+	if len(options) == 0 {
+		return "", false
+	}
+
+	return options[0].Value, true
+}
+
+// SetServerOption - create or update server option
+func SetServerOption(name string, value string) error {
+	option := models.Option{
+		Name:  name,
+		Value: value,
+		Type:  "string",
+	}
+
+	if err := database.GetORM().Save(&option).Error; err != nil {
+		return fmt.Errorf("Error save option %s: %v", name, err)
+	}
+
+	return nil
+}
+
+// DeleteServerOption - delete server option
+func DeleteServerOption(name string) error {
+	return database.GetORM().Where("NAME = ?", name).Delete(&models.Option{}).Error
+}
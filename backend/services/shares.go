@@ -0,0 +1,210 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sondrus/tetrad/database"
+	"github.com/sondrus/tetrad/models"
+	"gorm.io/gorm"
+)
+
+// shareSessionTTL - lifetime of access to share link after password is entered
+const shareSessionTTL = 24 * time.Hour
+
+// ErrShareNotFound - share link does not exist, it is revoked or expired
+var ErrShareNotFound = errors.New("share link not found")
+
+// GetShares - get share links of note (0 = all notes), admins get links of all users
+func GetShares(ctx context.Context, user *models.User, noteID int64) ([]models.Share, error) {
+	query := database.GetContextORM(ctx).Model(&models.Share{})
+	if !IsAdmin(user) {
+		query = query.Where("USER_ID = ?", user.ID)
+	}
+	if noteID > 0 {
+		query = query.Where("NOTE_ID = ?", noteID)
+	}
+
+	shares := []models.Share{}
+	if err := query.Order("ID ASC").Find(&shares).Error; err != nil {
+		return nil, err
+	}
+	for i := range shares {
+		shares[i].HasPassword = shares[i].Password != ""
+	}
+
+	return shares, nil
+}
+
+// GetShare - get single share link by ID
+func GetShare(ctx context.Context, id int64) (models.Share, error) {
+	var shares []models.Share
+	if err := database.GetContextORM(ctx).Where("ID = ?", id).Limit(1).Find(&shares).Error; err != nil {
+		return models.Share{}, err
+	}
+	if len(shares) == 0 {
+		return models.Share{}, ErrShareNotFound
+	}
+	shares[0].HasPassword = shares[0].Password != ""
+	return shares[0], nil
+}
+
+// GetShareByHash - get share link by hash of its token (ErrShareNotFound if it is expired)
+func GetShareByHash(ctx context.Context, hash string) (models.Share, error) {
+	var shares []models.Share
+	if err := database.GetContextORM(ctx).Where("HASH = ?", hash).Limit(1).Find(&shares).Error; err != nil {
+		return models.Share{}, err
+	}
+	if len(shares) == 0 {
+		return models.Share{}, ErrShareNotFound
+	}
+	if shares[0].DateExpires > 0 && time.Now().Unix() >= shares[0].DateExpires {
+		return models.Share{}, ErrShareNotFound
+	}
+	return shares[0], nil
+}
+
+// CreateShare - save share link (token and password must be already hashed)
+func CreateShare(ctx context.Context, share models.Share) (models.Share, error) {
+	share.ID = 0
+	share.AccessCount = 0
+	share.DateCreated = time.Now().Unix()
+	share.DateLastAccess = 0
+
+	if err := database.GetContextORM(ctx).Create(&share).Error; err != nil {
+		return models.Share{}, err
+	}
+
+	share.HasPassword = share.Password != ""
+	return share, nil
+}
+
+// RevokeShare - delete share link, it stops working immediately
+func RevokeShare(ctx context.Context, id int64) error {
+	result := database.GetContextORM(ctx).Delete(&models.Share{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrShareNotFound
+	}
+	return nil
+}
+
+// DeleteShares - delete share links of notes inside nested set range (in transaction)
+func DeleteShares(tx *gorm.DB, left int64, right int64) error {
+	ids := tx.Model(&models.NoteDB{}).Select("ID").Where(`"LEFT" >= ? AND "RIGHT" <= ?`, left, right)
+	return tx.Where("NOTE_ID IN (?)", ids).Delete(&models.Share{}).Error
+}
+
+// TouchShare - count visit of share link
+func TouchShare(ctx context.Context, share models.Share) error {
+	return database.GetContextORM(ctx).Model(&models.Share{}).Where("ID = ?", share.ID).Updates(map[string]any{
+		"ACCESS_COUNT":     gorm.Expr("ACCESS_COUNT + 1"),
+		"DATE_LAST_ACCESS": time.Now().Unix(),
+	}).Error
+}
+
+// GetSharedRoot - get shared note, creator of link must still be able to view it
+func GetSharedRoot(ctx context.Context, share models.Share) (models.NoteDB, error) {
+	note, err := GetNote(ctx, int(share.NoteID))
+	if err != nil {
+		return models.NoteDB{}, ErrShareNotFound
+	}
+
+	// User could lose access (or be deleted) after link was created
+	var user *models.User
+	if share.UserID > 0 {
+		user, err = GetUser(share.UserID)
+		if err != nil {
+			return models.NoteDB{}, ErrShareNotFound
+		}
+	}
+	if !HasNoteRole(ctx, user, note, RoleViewer) {
+		return models.NoteDB{}, ErrShareNotFound
+	}
+
+	return note, nil
+}
+
+// GetSharedNote - get note visible by share link: shared note or its child (just for subtree links)
+func GetSharedNote(ctx context.Context, share models.Share, root models.NoteDB, id int64) (models.NoteDB, error) {
+	if id == root.ID {
+		return root, nil
+	}
+	if !share.Subtree {
+		return models.NoteDB{}, ErrShareNotFound
+	}
+
+	note, err := GetNote(ctx, int(id))
+	if err != nil || note.Left <= root.Left || note.Right >= root.Right {
+		return models.NoteDB{}, ErrShareNotFound
+	}
+	return note, nil
+}
+
+// GetSharedNotes - get notes visible by share link (without contents), sorted by tree
+func GetSharedNotes(ctx context.Context, share models.Share, root models.NoteDB) ([]models.NoteDB, error) {
+	if !share.Subtree {
+		return []models.NoteDB{root}, nil
+	}
+
+	return GetNotes(ctx, NoteQueryOptions{
+		Where:        `"LEFT" >= ? AND "RIGHT" <= ?`,
+		Args:         []any{root.Left, root.Right},
+		Order:        `"LEFT" ASC`,
+		OmitContents: true,
+	})
+}
+
+// CreateShareSession - signed value of cookie which opens share link with password
+// The link hash is signed too, so the value does not work for other links
+func CreateShareSession(share models.Share) (string, time.Time, error) {
+	secret, err := getCapabilitySecret()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	expires := time.Now().Add(shareSessionTTL)
+	if share.DateExpires > 0 && share.DateExpires < expires.Unix() {
+		expires = time.Unix(share.DateExpires, 0)
+	}
+
+	payload := fmt.Sprintf("share:%d:%s:%d", share.ID, share.Hash, expires.Unix())
+	return fmt.Sprintf("%d.%s", expires.Unix(), signCapability(secret, payload)), expires, nil
+}
+
+// CheckShareSession - check cookie value made by CreateShareSession
+func CheckShareSession(share models.Share, value string) bool {
+	secret, err := getCapabilitySecret()
+	if err != nil {
+		return false
+	}
+
+	expiresValue, signature, ok := strings.Cut(value, ".")
+	if !ok {
+		return false
+	}
+	expires, err := strconv.ParseInt(expiresValue, 10, 64)
+	if err != nil || time.Now().Unix() >= expires {
+		return false
+	}
+
+	payload := fmt.Sprintf("share:%d:%s:%d", share.ID, share.Hash, expires)
+	return hmac.Equal([]byte(signature), []byte(signCapability(secret, payload)))
+}
+
+// SharePath - URL path of share link (links of notebooks have notebook prefix)
+func SharePath(ctx context.Context, token string) string {
+	path := "/share/" + token
+	if notebook := database.GetContextNotebook(ctx); !notebook.IsDefault() {
+		path = "/nb/" + url.PathEscape(notebook.Name) + path
+	}
+	return path
+}
@@ -0,0 +1,372 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/sondrus/tetrad/database"
+	"github.com/sondrus/tetrad/events"
+	"github.com/sondrus/tetrad/models"
+	"gorm.io/gorm"
+)
+
+// Task priorities (0 = not set)
+const (
+	PriorityHigh   = 1
+	PriorityMedium = 2
+	PriorityLow    = 3
+)
+
+// Tasks index is rebuilt on startup just if its version is missing (new table) or outdated
+// Increase version when parsing of tasks is changed
+const (
+	tasksIndex        = "tasks"
+	tasksIndexVersion = 1
+)
+
+// ErrTaskChanged - line of task is changed since it was indexed
+var ErrTaskChanged = errors.New("task is changed")
+
+var (
+	// Checkbox item of list: `- [ ] text`, `* [x] text`, `1. [ ] text`
+	reTask = regexp.MustCompile(`^(\s*(?:[-*+]|\d+[.)])\s+\[)([ xX])(\]\s+)(.*?)\s*$`)
+
+	// Due date annotation: @due(2025-06-01)
+	reTaskDue = regexp.MustCompile(`@due\((\d{4}-\d{2}-\d{2})\)`)
+
+	// Priority annotation: !high, !medium, !low or !1, !2, !3
+	reTaskPriority = regexp.MustCompile(`(?:^|\s)!(high|medium|low|[123])\b`)
+
+	// Fenced code block (tasks inside are not indexed)
+	reCodeFence = regexp.MustCompile("^\\s*(```|~~~)")
+)
+
+// taskPriorities - annotation => priority
+var taskPriorities = map[string]int64{
+	"high": PriorityHigh, "1": PriorityHigh,
+	"medium": PriorityMedium, "2": PriorityMedium,
+	"low": PriorityLow, "3": PriorityLow,
+}
+
+// TaskFilter - filters for task list (zero values are ignored)
+type TaskFilter struct {
+	Status  string // open, done or all (default: open)
+	DueFrom string // YYYY-MM-DD, tasks without due date are skipped if range is set
+	DueTo   string
+	NoteID  int64 // tasks of note and its children
+	User    *models.User
+}
+
+// TaskInfo - task with title of its note (for response)
+type TaskInfo struct {
+	models.Task
+	NoteTitle string `gorm:"column:NOTE_TITLE" json:"noteTitle"`
+	Left      int64  `gorm:"column:LEFT" json:"-"`
+	Right     int64  `gorm:"column:RIGHT" json:"-"`
+}
+
+// ParseTasks - find checkbox items in markdown (line numbers start from 1)
+func ParseTasks(contents string) []models.Task {
+	var tasks []models.Task
+	fence := ""
+
+	for i, line := range strings.Split(contents, "\n") {
+		line = strings.TrimRight(line, "\r")
+
+		// Skip fenced code blocks
+		if match := reCodeFence.FindStringSubmatch(line); match != nil {
+			if fence == "" {
+				fence = match[1]
+			} else if fence == match[1] {
+				fence = ""
+			}
+			continue
+		}
+		if fence != "" {
+			continue
+		}
+
+		match := reTask.FindStringSubmatch(line)
+		if match == nil || strings.TrimSpace(match[4]) == "" {
+			continue
+		}
+
+		task := models.Task{
+			Line: int64(i + 1),
+			Done: match[2] != " ",
+		}
+		text := match[4]
+		if due := reTaskDue.FindStringSubmatch(text); due != nil {
+			if _, err := time.Parse(JournalDateLayout, due[1]); err == nil {
+				task.Due = due[1]
+			}
+		}
+		if priority := reTaskPriority.FindStringSubmatch(text); priority != nil {
+			task.Priority = taskPriorities[priority[1]]
+		}
+
+		// Text without annotations
+		text = reTaskDue.ReplaceAllString(text, "")
+		text = reTaskPriority.ReplaceAllString(text, "")
+		task.Text = strings.Join(strings.Fields(text), " ")
+
+		tasks = append(tasks, task)
+	}
+
+	return tasks
+}
+
+// IndexNoteTasks - replace indexed tasks of note (just plain MD notes have tasks)
+func IndexNoteTasks(ctx context.Context, noteID int64) error {
+	return database.GetContextORM(ctx).Transaction(func(tx *gorm.DB) error {
+		return indexTasks(tx, noteID)
+	})
+}
+
+// indexTasks - replace indexed tasks of note in transaction
+func indexTasks(tx *gorm.DB, noteID int64) error {
+	if err := tx.Where("NOTE_ID = ?", noteID).Delete(&models.Task{}).Error; err != nil {
+		return err
+	}
+
+	var notes []models.NoteDB
+	if err := tx.Select("ID", "TYPE", "CONTENTS").Where("ID = ?", noteID).Limit(1).Find(&notes).Error; err != nil {
+		return err
+	}
+
+	// Encrypted contents are never indexed
+	if len(notes) == 0 || notes[0].Type != "MD" || IsEncrypted(notes[0].Contents) {
+		return nil
+	}
+
+	tasks := ParseTasks(notes[0].Contents)
+	if len(tasks) == 0 {
+		return nil
+	}
+	for i := range tasks {
+		tasks[i].NoteID = noteID
+	}
+
+	return tx.Create(&tasks).Error
+}
+
+// IndexSubtreeTasks - replace indexed tasks of note and its children (eg, after encryption)
+func IndexSubtreeTasks(ctx context.Context, note models.NoteDB) error {
+	db := database.GetContextORM(ctx)
+
+	var ids []int64
+	if err := db.Model(&models.NoteDB{}).Where(`"LEFT" >= ? AND "RIGHT" <= ?`, note.Left, note.Right).Pluck("ID", &ids).Error; err != nil {
+		return err
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, id := range ids {
+			if err := indexTasks(tx, id); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ReindexTasks - rebuild tasks index of whole notebook (eg, after import or for database of older version)
+func ReindexTasks(ctx context.Context) error {
+	db := database.GetContextORM(ctx)
+
+	var ids []int64
+	if err := db.Model(&models.NoteDB{}).Where("TYPE = ?", "MD").Pluck("ID", &ids).Error; err != nil {
+		return err
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("1 = 1").Delete(&models.Task{}).Error; err != nil {
+			return err
+		}
+		for _, id := range ids {
+			if err := indexTasks(tx, id); err != nil {
+				return err
+			}
+		}
+		return tx.Save(&models.IndexVersion{Name: tasksIndex, Version: tasksIndexVersion, DateModified: time.Now().Unix()}).Error
+	})
+}
+
+// ReindexNotebookTasks - rebuild tasks index of notebook in background, just if it is missing (new table) or outdated
+func ReindexNotebookTasks(notebook *database.Notebook) {
+	go func() {
+		ctx := database.WithNotebook(context.Background(), notebook)
+
+		var versions []models.IndexVersion
+		if err := database.GetContextORM(ctx).Where("NAME = ?", tasksIndex).Limit(1).Find(&versions).Error; err != nil {
+			slog.Error("Failed to check tasks index", "notebook", notebook.Name, "error", err)
+			return
+		}
+		if len(versions) > 0 && versions[0].Version == tasksIndexVersion {
+			return
+		}
+
+		if err := ReindexTasks(ctx); err != nil {
+			slog.Error("Failed to index tasks", "notebook", notebook.Name, "error", err)
+		}
+	}()
+}
+
+// ReindexAllTasks - rebuild tasks index of opened notebooks which need it in background (on startup)
+func ReindexAllTasks() {
+	for _, notebook := range database.OpenedNotebooks() {
+		ReindexNotebookTasks(notebook)
+	}
+}
+
+// DeleteTasks - delete tasks of notes inside nested set range (in transaction)
+func DeleteTasks(tx *gorm.DB, left int64, right int64) error {
+	ids := tx.Model(&models.NoteDB{}).Select("ID").Where(`"LEFT" >= ? AND "RIGHT" <= ?`, left, right)
+	return tx.Where("NOTE_ID IN (?)", ids).Delete(&models.Task{}).Error
+}
+
+// GetTasks - get indexed tasks visible for user
+// Sorted by due date (tasks without date are last), priority and position in tree
+func GetTasks(ctx context.Context, filter TaskFilter) ([]TaskInfo, error) {
+	query := database.GetContextORM(ctx).
+		Table("tasks AS t").
+		Select(`t.*, n.TITLE AS NOTE_TITLE, n."LEFT" AS "LEFT", n."RIGHT" AS "RIGHT"`).
+		Joins("JOIN notes AS n ON n.ID = t.NOTE_ID")
+
+	switch filter.Status {
+	case "", "open":
+		query = query.Where("t.DONE = ?", false)
+	case "done":
+		query = query.Where("t.DONE = ?", true)
+	case "all":
+	default:
+		return nil, fmt.Errorf("unknown task status %s", filter.Status)
+	}
+	if filter.DueFrom != "" {
+		query = query.Where("t.DUE <> '' AND t.DUE >= ?", filter.DueFrom)
+	}
+	if filter.DueTo != "" {
+		query = query.Where("t.DUE <> '' AND t.DUE <= ?", filter.DueTo)
+	}
+	if filter.NoteID > 0 {
+		note, err := GetNote(ctx, int(filter.NoteID))
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where(`n."LEFT" >= ? AND n."RIGHT" <= ?`, note.Left, note.Right)
+	}
+
+	var tasks []TaskInfo
+	err := query.
+		Order("t.DUE = '' ASC, t.DUE ASC, t.PRIORITY = 0 ASC, t.PRIORITY ASC, n.\"LEFT\" ASC, t.LINE ASC").
+		Scan(&tasks).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// Keep just tasks of notes which are visible for user
+	if IsAdmin(filter.User) {
+		return tasks, nil
+	}
+	grants, err := loadGrants(ctx, filter.User.ID)
+	if err != nil {
+		return nil, err
+	}
+	visible := make([]TaskInfo, 0, len(tasks))
+	for _, task := range tasks {
+		if roleByGrants(grants, models.NoteDB{Left: task.Left, Right: task.Right}) != "" {
+			visible = append(visible, task)
+		}
+	}
+
+	return visible, nil
+}
+
+// GetTask - get single indexed task by ID
+func GetTask(ctx context.Context, id int64) (models.Task, error) {
+	var tasks []models.Task
+	if err := database.GetContextORM(ctx).Where("ID = ?", id).Limit(1).Find(&tasks).Error; err != nil {
+		return models.Task{}, err
+	}
+	if len(tasks) == 0 {
+		return models.Task{}, errors.New("task not found")
+	}
+	return tasks[0], nil
+}
+
+// ToggleTask - switch checkbox of task in note contents (just this line is changed), returns updated task
+func ToggleTask(ctx context.Context, keyring *Keyring, task models.Task, done bool) (models.Task, error) {
+	note, err := GetNote(ctx, int(task.NoteID))
+	if err != nil {
+		return models.Task{}, err
+	}
+
+	// Line must be the same task as indexed, otherwise index is outdated
+	lines := strings.SplitAfter(note.Contents, "\n")
+	if task.Line < 1 || int(task.Line) > len(lines) {
+		return models.Task{}, ErrTaskChanged
+	}
+	line := lines[task.Line-1]
+	match := reTask.FindStringSubmatchIndex(line)
+	if match == nil {
+		return models.Task{}, ErrTaskChanged
+	}
+	parsed := ParseTasks(strings.Repeat("\n", int(task.Line-1)) + line)
+	if len(parsed) != 1 || parsed[0].Text != task.Text {
+		return models.Task{}, ErrTaskChanged
+	}
+
+	mark := " "
+	if done {
+		mark = "x"
+	}
+	lines[task.Line-1] = line[:match[4]] + mark + line[match[5]:]
+
+	// Encrypt contents for notes in vault
+	vault, err := GetVaultByNote(ctx, note)
+	if err != nil {
+		return models.Task{}, err
+	}
+	contents, err := SealContents(keyring, vault, strings.Join(lines, ""))
+	if err != nil {
+		return models.Task{}, err
+	}
+
+	// Save contents just if they are not changed since they were read, tasks are indexed again
+	now := time.Now().Unix()
+	err = database.GetContextORM(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.NoteDB{}).Where("ID = ? AND CONTENTS = ?", note.ID, note.Contents).
+			Updates(map[string]any{"CONTENTS": contents, "DATE_MODIFIED": now})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrTaskChanged
+		}
+		return indexTasks(tx, note.ID)
+	})
+	if err != nil {
+		return models.Task{}, err
+	}
+
+	PublishEvent(ctx, events.Event{
+		Type:         events.Updated,
+		NoteIDs:      []int64{note.ID},
+		ParentID:     note.ParentID,
+		DateModified: now,
+	})
+
+	var tasks []models.Task
+	if err := database.GetContextORM(ctx).Where("NOTE_ID = ? AND LINE = ?", task.NoteID, task.Line).Limit(1).Find(&tasks).Error; err != nil {
+		return models.Task{}, err
+	}
+	if len(tasks) == 0 {
+		return models.Task{}, ErrTaskChanged
+	}
+
+	return tasks[0], nil
+}
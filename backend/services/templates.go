@@ -0,0 +1,203 @@
+package services
+
+import (
+	"context"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/sondrus/tetrad/database"
+	"github.com/sondrus/tetrad/events"
+	"github.com/sondrus/tetrad/models"
+	"gorm.io/gorm"
+)
+
+// Default layouts for {{date}} and {{time}} placeholders
+const (
+	TemplateDateLayout = "2006-01-02"
+	TemplateTimeLayout = "15:04"
+)
+
+// rePlaceholder - {{name}} or {{name:argument}} in template
+var rePlaceholder = regexp.MustCompile(`\{\{\s*([a-zA-Z.]+)(?::([^{}]*))?\s*\}\}`)
+
+// TemplateValues - values for placeholders of template
+type TemplateValues struct {
+	Time        time.Time         // {{date}}, {{time}}, {{date:layout}}, {{time:layout}}
+	Title       string            // {{title}} - title of new note
+	ParentTitle string            // {{parent.title}} - title of note where new note is created
+	Prompts     map[string]string // {{prompt:Name}} - values entered by user
+}
+
+// ExpandTemplate - replace placeholders in text, unknown placeholders are kept as is
+func ExpandTemplate(text string, values TemplateValues) string {
+	return rePlaceholder.ReplaceAllStringFunc(text, func(placeholder string) string {
+		match := rePlaceholder.FindStringSubmatch(placeholder)
+		name, argument := strings.ToLower(match[1]), strings.TrimSpace(match[2])
+
+		switch name {
+		case "date", "time":
+			layout := argument
+			if layout == "" && name == "date" {
+				layout = TemplateDateLayout
+			} else if layout == "" {
+				layout = TemplateTimeLayout
+			}
+			return values.Time.Format(layout)
+		case "title":
+			return values.Title
+		case "parent.title":
+			return values.ParentTitle
+		case "prompt":
+			return values.Prompts[argument]
+		}
+
+		return placeholder
+	})
+}
+
+// templatePrompts - names of {{prompt:Name}} placeholders in text (in order, without duplicates)
+func templatePrompts(text string, prompts []string) []string {
+	for _, match := range rePlaceholder.FindAllStringSubmatch(text, -1) {
+		name := strings.TrimSpace(match[2])
+		if strings.ToLower(match[1]) != "prompt" || name == "" {
+			continue
+		}
+		if !slices.Contains(prompts, name) {
+			prompts = append(prompts, name)
+		}
+	}
+	return prompts
+}
+
+// GetTemplates - get notes marked as templates (visible for user)
+func GetTemplates(ctx context.Context, user *models.User) ([]models.NoteDB, error) {
+	return GetNotes(ctx, NoteQueryOptions{
+		Where:        "TEMPLATE = ?",
+		Args:         []any{true},
+		Order:        "TITLE ASC",
+		OmitContents: true,
+		User:         user,
+	})
+}
+
+// GetTemplateSubtree - get template note with all its children (sorted by LEFT), contents are decrypted by keyring
+func GetTemplateSubtree(ctx context.Context, keyring *Keyring, template models.NoteDB) ([]models.NoteDB, error) {
+	notes, err := GetNotes(ctx, NoteQueryOptions{
+		Where:   `"LEFT" >= ? AND "RIGHT" <= ?`,
+		Args:    []any{template.Left, template.Right},
+		Order:   "LEFT ASC",
+		Keyring: keyring,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, note := range notes {
+		if note.Locked {
+			return nil, ErrLocked
+		}
+	}
+
+	return notes, nil
+}
+
+// GetTemplatePrompts - names of prompts used in template subtree (titles, contents and URLs)
+func GetTemplatePrompts(subtree []models.NoteDB) []string {
+	prompts := []string{}
+	for _, note := range subtree {
+		prompts = templatePrompts(note.Title, prompts)
+		prompts = templatePrompts(note.Contents, prompts)
+		prompts = templatePrompts(note.URL, prompts)
+	}
+	return prompts
+}
+
+// CreateFromTemplate - copy template subtree under parent with expanded placeholders, returns IDs of new notes
+// Empty title of values is replaced by expanded title of template
+func CreateFromTemplate(ctx context.Context, user *models.User, keyring *Keyring, template models.NoteDB, parentID int64, values TemplateValues) ([]int64, error) {
+	if !HasNoteRole(ctx, user, template, RoleViewer) {
+		return nil, ErrAccessDenied
+	}
+
+	subtree, err := GetTemplateSubtree(ctx, keyring, template)
+	if err != nil {
+		return nil, err
+	}
+
+	if values.Time.IsZero() {
+		values.Time = time.Now()
+	}
+	if parentID > 0 {
+		parent, err := GetNote(ctx, int(parentID))
+		if err != nil {
+			return nil, ErrParentNotFound
+		}
+		values.ParentTitle = parent.Title
+	}
+	if values.Title == "" {
+		titleValues := values
+		titleValues.Title = template.Title
+		values.Title = ExpandTemplate(template.Title, titleValues)
+	}
+
+	// Whole copy is created or nothing
+	if !HasParentRole(ctx, user, parentID, RoleEditor) {
+		return nil, ErrAccessDenied
+	}
+	vault, err := GetVaultByParent(ctx, parentID)
+	if err != nil {
+		return nil, ErrParentNotFound
+	}
+
+	// Template note => new note, its children are copied under new note (parents are first by LEFT)
+	now := time.Now().Unix()
+	newIDs := make(map[int64]int64, len(subtree))
+	ids := make([]int64, 0, len(subtree))
+	err = database.GetContextORM(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, note := range subtree {
+			fields := map[string]any{
+				"TYPE":     note.Type,
+				"ICON":     note.Icon,
+				"TITLE":    ExpandTemplate(note.Title, values),
+				"CONTENTS": ExpandTemplate(note.Contents, values),
+				"URL":      ExpandTemplate(note.URL, values),
+				"SYNTAX":   note.Syntax,
+			}
+			if note.ID == template.ID {
+				fields["PARENT_ID"] = parentID
+				fields["TITLE"] = values.Title
+			} else {
+				fields["PARENT_ID"] = newIDs[note.ParentID]
+			}
+
+			id, err := insertNote(tx, user, keyring, vault, fields, now)
+			if err != nil {
+				return err
+			}
+			newIDs[note.ID] = id
+			ids = append(ids, id)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, id := range ids {
+		IndexNoteTasks(ctx, id)
+	}
+
+	// Nested set rebuild, once for whole copy
+	RebuildNotesTree(ctx)
+
+	PublishEvent(ctx, events.Event{
+		Type:         events.Created,
+		NoteIDs:      ids,
+		ParentID:     parentID,
+		DateModified: now,
+	})
+
+	return ids, nil
+}
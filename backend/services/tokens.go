@@ -0,0 +1,101 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/sondrus/tetrad/database"
+	"github.com/sondrus/tetrad/models"
+)
+
+// Token scopes
+const (
+	ScopeRead      = "read"       // GET requests and search
+	ScopeReadWrite = "read-write" // everything the user can do
+)
+
+// tokenTouchInterval - min interval between DATE_LAST_USED updates (avoid write on every request)
+const tokenTouchInterval = 60
+
+// GetContextToken - get API token from request context (nil if request is authorized by session)
+func GetContextToken(ctx context.Context) *models.Token {
+	token, _ := ctx.Value(database.TokenKey).(*models.Token)
+	return token
+}
+
+// IsValidScope - check token scope name
+func IsValidScope(scope string) bool {
+	return scope == ScopeRead || scope == ScopeReadWrite
+}
+
+// GetTokens - get all API tokens of user
+func GetTokens(userID int64) ([]models.Token, error) {
+	tokens := []models.Token{}
+	if err := database.GetORM().Where("USER_ID = ?", userID).Order("ID ASC").Find(&tokens).Error; err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// GetTokenByHash - get single API token by hash
+func GetTokenByHash(hash string) (*models.Token, error) {
+	var tokens []models.Token
+	if err := database.GetORM().Where("HASH = ?", hash).Limit(1).Find(&tokens).Error; err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, errors.New("token not found")
+	}
+	return &tokens[0], nil
+}
+
+// CreateToken - save API token (token must be already hashed), expires = 0 means never
+func CreateToken(userID int64, name string, hash string, prefix string, scope string, expires int64) (*models.Token, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, errors.New("token name is empty")
+	}
+	if !IsValidScope(scope) {
+		return nil, errors.New("unknown token scope " + scope)
+	}
+
+	token := models.Token{
+		UserID:      userID,
+		Name:        name,
+		Hash:        hash,
+		Prefix:      prefix,
+		Scope:       scope,
+		DateCreated: time.Now().Unix(),
+		DateExpires: expires,
+	}
+	if err := database.GetORM().Create(&token).Error; err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// DeleteToken - revoke API token of user
+func DeleteToken(userID int64, id int64) error {
+	result := database.GetORM().Where("ID = ? AND USER_ID = ?", id, userID).Delete(&models.Token{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("token not found")
+	}
+	return nil
+}
+
+// TouchToken - update last used date of token
+func TouchToken(token *models.Token) {
+	now := time.Now().Unix()
+	if now-token.DateLastUsed < tokenTouchInterval {
+		return
+	}
+
+	token.DateLastUsed = now
+	database.GetORM().Model(&models.Token{}).Where("ID = ?", token.ID).Update("DATE_LAST_USED", now)
+}
@@ -0,0 +1,128 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sondrus/tetrad/database"
+	"github.com/sondrus/tetrad/models"
+	"gorm.io/gorm"
+)
+
+// GetContextUser - get current user from request context (nil if authentication is disabled)
+func GetContextUser(ctx context.Context) *models.User {
+	user, _ := ctx.Value(database.UserKey).(*models.User)
+	return user
+}
+
+// CountUsers - get count of user accounts (0 = authentication is disabled)
+func CountUsers() int64 {
+	var count int64
+	database.GetORM().Model(&models.User{}).Count(&count)
+	return count
+}
+
+// GetUsers - get all user accounts
+func GetUsers() ([]models.User, error) {
+	var users []models.User
+	if err := database.GetORM().Order("NAME ASC").Find(&users).Error; err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// GetUser - get single user by ID
+func GetUser(id int64) (*models.User, error) {
+	var users []models.User
+	if err := database.GetORM().Where("ID = ?", id).Limit(1).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	if len(users) == 0 {
+		return nil, errors.New("user not found")
+	}
+	return &users[0], nil
+}
+
+// GetUserByName - get single user by name
+func GetUserByName(name string) (*models.User, error) {
+	var users []models.User
+	if err := database.GetORM().Where("NAME = ?", name).Limit(1).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	if len(users) == 0 {
+		return nil, errors.New("user not found")
+	}
+	return &users[0], nil
+}
+
+// CreateUser - create user account (password must be already hashed)
+func CreateUser(name string, passwordHash string, admin bool) (*models.User, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, errors.New("user name is empty")
+	}
+	if _, err := GetUserByName(name); err == nil {
+		return nil, fmt.Errorf("user %s already exists", name)
+	}
+
+	now := time.Now().Unix()
+	user := models.User{
+		Name:         name,
+		Password:     passwordHash,
+		Admin:        admin,
+		DateCreated:  now,
+		DateModified: now,
+	}
+	if err := database.GetORM().Create(&user).Error; err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// UpdateUser - update user fields (NAME, PASSWORD, ADMIN)
+func UpdateUser(id int64, fields map[string]any) error {
+	if name, ok := fields["NAME"].(string); ok {
+		if existing, err := GetUserByName(name); err == nil && existing.ID != id {
+			return fmt.Errorf("user %s already exists", name)
+		}
+	}
+
+	fields["DATE_MODIFIED"] = time.Now().Unix()
+
+	return database.GetORM().Model(&models.User{}).Where("ID = ?", id).Updates(fields).Error
+}
+
+// DeleteUser - delete user with permissions, settings and API tokens (in all opened notebooks)
+func DeleteUser(id int64) error {
+	for _, notebook := range database.OpenedNotebooks() {
+		if notebook.IsDefault() {
+			continue
+		}
+		err := notebook.ORM().Transaction(func(tx *gorm.DB) error {
+			if err := tx.Where("USER_ID = ?", id).Delete(&models.Permission{}).Error; err != nil {
+				return err
+			}
+			return tx.Where("USER_ID = ?", id).Delete(&models.UserOption{}).Error
+		})
+		if err != nil {
+			return fmt.Errorf("notebook %s: %w", notebook.Name, err)
+		}
+	}
+
+	return database.GetORM().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("USER_ID = ?", id).Delete(&models.Permission{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("USER_ID = ?", id).Delete(&models.UserOption{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("USER_ID = ?", id).Delete(&models.Token{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("ID = ?", id).Delete(&models.User{}).Error
+	})
+}
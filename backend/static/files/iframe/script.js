@@ -1,5 +1,32 @@
+// IFrame is sandboxed (and may be on other origin), so parent origin is unknown here.
+// Messages to parent contain nothing private, parent checks the source window.
+
 // Initialize iframe
-window.parent.postMessage({action: 'tetrad_init'}, location.origin);
+window.parent.postMessage({action: 'tetrad_init'}, '*');
+
+// Apply app styles (sent by parent in reply to `tetrad_init`)
+window.addEventListener('message', (event)=>{
+	if(event.source !== window.parent || event.data?.action !== 'tetrad_styles'){
+		return
+	}
+
+	const payload = event.data.payload ?? {}
+
+	for(const href of payload.links ?? []){
+		const link = document.createElement('link')
+		link.setAttribute('rel', 'stylesheet')
+		link.setAttribute('href', href)
+		document.head.appendChild(link)
+	}
+
+	for(const css of payload.styles ?? []){
+		const style = document.createElement('style')
+		style.textContent = css
+		document.head.appendChild(style)
+	}
+
+	document.body.focus()
+})
 
 // Passthrough hotkeys
 document.addEventListener('keydown', (event)=>{
@@ -16,5 +43,59 @@ document.addEventListener('keydown', (event)=>{
 				metaKey: event.metaKey,
 			}
 		}
-	}, location.origin);
+	}, '*');
 })
+
+// Bridge API for mini-apps: requests go through parent window, access is checked by server
+//   await tetrad.getData()         - JSON data of this note
+//   await tetrad.setData(data)     - save JSON data of this note (editor role is required)
+//   await tetrad.listNotes()       - notes granted to this note (without contents)
+//   await tetrad.getNote(id)       - granted note with contents
+window.tetrad = (()=>{
+	const timeout = 30000
+	const pending = new Map()
+	let lastID = 0
+
+	window.addEventListener('message', (event)=>{
+		if(event.source !== window.parent || event.data?.action !== 'tetrad_bridge_result'){
+			return
+		}
+
+		const {requestID, result, error} = event.data.payload ?? {}
+		const request = pending.get(requestID)
+		if(!request){
+			return
+		}
+		pending.delete(requestID)
+		clearTimeout(request.timer)
+
+		if(error){
+			request.reject(new Error(error))
+		} else {
+			request.resolve(result)
+		}
+	})
+
+	const call = (method, params = {})=>{
+		return new Promise((resolve, reject)=>{
+			const requestID = ++lastID
+			const timer = setTimeout(()=>{
+				pending.delete(requestID)
+				reject(new Error(`Bridge request timeout: ${method}`))
+			}, timeout)
+
+			pending.set(requestID, {resolve, reject, timer})
+			window.parent.postMessage({
+				action: 'tetrad_bridge',
+				payload: {requestID, method, params},
+			}, '*')
+		})
+	}
+
+	return Object.freeze({
+		getData: ()=> call('getData'),
+		setData: (data)=> call('setData', {data}),
+		listNotes: ()=> call('listNotes'),
+		getNote: (id)=> call('getNote', {id}),
+	})
+})()
@@ -0,0 +1,112 @@
+import { defineStore } from 'pinia';
+import { ref } from 'vue';
+
+import { useNotesStore } from '@/stores/notesStore';
+import { useSettingsStore } from '@/stores/settingsStore';
+
+// Change notification from server (see backend/events)
+interface ServerEvent {
+	id: number;
+	type: 'created' | 'updated' | 'deleted' | 'moved' | 'expanded' | 'settings' | 'reset';
+	ids?: number[];
+	parentId?: number;
+	dateModified?: number;
+}
+
+export const useEventsStore = defineStore('events', () => {
+	const notesStore = useNotesStore()
+	const settingsStore = useSettingsStore()
+
+	// Check stream is connected
+	const connected = ref<boolean>(false)
+
+	let source: EventSource | null = null
+
+	// Several changes in a row => single reload
+	let debounceTimerReload: ReturnType<typeof setTimeout>
+
+	// Connect to event stream (EventSource reconnects itself with `Last-Event-ID`)
+	const connect = () => {
+		if (source || typeof EventSource === 'undefined') {
+			return
+		}
+
+		source = new EventSource('/api/events')
+		source.onopen = () => {
+			connected.value = true
+		}
+		source.onerror = () => {
+			connected.value = false
+		}
+		source.onmessage = (message: MessageEvent) => {
+			try {
+				handleEvent(JSON.parse(message.data) as ServerEvent)
+			} catch {
+				// Ignore invalid messages
+			}
+		}
+	}
+
+	// Close event stream
+	const disconnect = () => {
+		source?.close()
+		source = null
+		connected.value = false
+	}
+
+	// Apply change made by other tab or user
+	const handleEvent = (event: ServerEvent) => {
+		switch (event.type) {
+			case 'settings':
+				settingsStore.loadSettings()
+				return
+			case 'reset':
+				settingsStore.loadSettings()
+				reloadNotes()
+				reloadCurrent(0)
+				return
+			case 'updated':
+				if (event.ids?.includes(notesStore.current?.id)) {
+					reloadCurrent(event.dateModified ?? 0)
+				}
+				break
+		}
+
+		reloadNotes()
+	}
+
+	// Reload notes tree (with debounce)
+	const reloadNotes = () => {
+		clearTimeout(debounceTimerReload)
+		debounceTimerReload = setTimeout(() => {
+			notesStore.loadNotes()
+		}, 300)
+	}
+
+	// Reload contents of current note if it is newer (editor is never touched)
+	const reloadCurrent = async (dateModified: number) => {
+		const current = notesStore.current
+		if (!current?.id || settingsStore.settings.editor.editMode) {
+			return
+		}
+		if (dateModified && dateModified <= current.dateModified) {
+			return
+		}
+
+		const contents = await notesStore.loadNoteContents(current.id)
+		if (contents === undefined || notesStore.current?.id !== current.id) {
+			return
+		}
+
+		notesStore.updateNoteContents(contents)
+		if (dateModified) {
+			notesStore.current.dateModified = dateModified
+		}
+	}
+
+	return {
+		connected,
+		connect,
+		disconnect,
+	};
+});
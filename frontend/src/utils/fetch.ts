@@ -34,6 +34,11 @@ async function fetcher(
 
 		const data = await response.json().catch(() => null);
 
+		// Session is expired (or password is set) => go to login page
+		if (response.status === 401) {
+			window.location.href = '/login?next=' + encodeURIComponent(window.location.pathname + window.location.search);
+		}
+
 		if (!response.ok) {
 			return {
 				ok: false,